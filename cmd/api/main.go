@@ -2,22 +2,33 @@ package main
 
 import (
 	"context"
+	"net"
 	nethttp "net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/mikiasyonas/url-shortener/internal/adapters/cache/local"
 	"github.com/mikiasyonas/url-shortener/internal/adapters/cache/redis"
+	"github.com/mikiasyonas/url-shortener/internal/adapters/codefilter"
 	"github.com/mikiasyonas/url-shortener/internal/adapters/http"
+	"github.com/mikiasyonas/url-shortener/internal/adapters/idempotency"
+	"github.com/mikiasyonas/url-shortener/internal/adapters/keypool"
+	"github.com/mikiasyonas/url-shortener/internal/adapters/linkcheck"
+	"github.com/mikiasyonas/url-shortener/internal/adapters/ratelimit"
 	"github.com/mikiasyonas/url-shortener/internal/adapters/repository/gorm"
+	"github.com/mikiasyonas/url-shortener/internal/adapters/screening"
+	"github.com/mikiasyonas/url-shortener/internal/adapters/sequence"
 	"github.com/mikiasyonas/url-shortener/internal/app/service"
 	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+	"github.com/mikiasyonas/url-shortener/pkg/canonical"
 	"github.com/mikiasyonas/url-shortener/pkg/config"
 	"github.com/mikiasyonas/url-shortener/pkg/database"
 	"github.com/mikiasyonas/url-shortener/pkg/monitoring"
 	"github.com/mikiasyonas/url-shortener/pkg/shortcode"
 
 	"github.com/joho/godotenv"
+	goredis "github.com/redis/go-redis/v9"
 )
 
 func main() {
@@ -35,10 +46,12 @@ func main() {
 	cfg := config.Load()
 
 	if err := cfg.Validate(); err != nil {
-		logger.Error("Invalid configuration:", err)
+		logger.Error("Invalid configuration: %v", err)
+		os.Exit(1)
 	}
 
 	var redisCache ports.Cache
+	var redisClient *goredis.Client
 	if cfg.Redis.URL != "" {
 		cache, err := redis.NewRedisCache(
 			cfg.Redis.URL,
@@ -50,6 +63,7 @@ func main() {
 			logger.Info("Redis cache disabled: %v", err)
 		} else {
 			redisCache = cache
+			redisClient = cache.Client()
 			defer cache.Close()
 			logger.Info("Redis cache connected")
 		}
@@ -57,7 +71,11 @@ func main() {
 
 	db, err := database.Connect(&cfg.Database)
 	if err != nil {
-		logger.Error("Failed to connect to database:", err)
+		logger.Error("Failed to connect to database: %v", err)
+	}
+
+	if err := db.Use(monitoring.NewGormPlugin(metrics)); err != nil {
+		logger.Error("Failed to register database metrics: %v", err)
 	}
 
 	if err := database.OptimizeConnectionPool(db,
@@ -69,7 +87,7 @@ func main() {
 	}
 
 	if err := database.AutoMigrate(db); err != nil {
-		logger.Error("Failed to run migrations:", err)
+		logger.Error("Failed to run migrations: %v", err)
 	}
 
 	healthChecker.RegisterCheck("database", monitoring.DatabaseHealthCheck(db), true)
@@ -78,18 +96,240 @@ func main() {
 	}
 
 	urlRepo := gorm.NewURLRepository(db)
-	codeGenerator := shortcode.NewGenerator(6)
+	alphabet, err := shortcode.ParseAlphabet(cfg.App.ShortCodeAlphabet)
+	if err != nil {
+		logger.Error("Invalid short code alphabet: %v", err)
+		os.Exit(1)
+	}
+	codeOptions := []shortcode.Option{
+		shortcode.WithAlphabet(alphabet),
+		shortcode.WithBlocklist(cfg.App.ShortCodeBlocklist),
+	}
+	if cfg.App.ShortCodeCheckCharacter {
+		codeOptions = append(codeOptions, shortcode.WithCheckCharacter())
+	}
+
+	var codeGenerator ports.ShortCodeGenerator = shortcode.NewGenerator(cfg.App.ShortCodeLength, codeOptions...)
+	if cfg.App.ShortCodeStrategy == config.ShortCodeStrategyCounter {
+		var ids shortcode.IDAllocator = sequence.NewPostgresAllocator(db)
+		switch {
+		case cfg.App.ShortCodeCounter == config.ShortCodeCounterRedis && redisClient != nil:
+			ids = sequence.NewRedisAllocator(redisClient)
+		case cfg.App.ShortCodeCounter == config.ShortCodeCounterRedis:
+			logger.Info("Redis unavailable, allocating short code IDs from Postgres")
+		}
+		codeGenerator = shortcode.NewCounterGenerator(ids, []byte(cfg.App.ShortCodeSecret), cfg.App.ShortCodeLength, codeOptions...)
+		logger.Info("Counter-based short codes enabled (%s)", cfg.App.ShortCodeCounter)
+	}
+	aliasValidator := shortcode.NewAliasValidator(
+		cfg.App.AliasMinLength,
+		cfg.App.AliasMaxLength,
+		cfg.App.ReservedAliases,
+	)
 
-	baseURLService := service.NewURLService(urlRepo, codeGenerator)
+	var codeFilter *codefilter.Filter
+	switch {
+	case cfg.BloomFilter.Backend == config.BloomFilterBackendRedis && redisClient != nil:
+		codeFilter = codefilter.NewRedisFilter(redisClient, uint64(cfg.BloomFilter.ExpectedCodes), cfg.BloomFilter.FalsePositiveRate)
+	case cfg.BloomFilter.Backend == config.BloomFilterBackendMemory:
+		codeFilter = codefilter.NewMemoryFilter(uint64(cfg.BloomFilter.ExpectedCodes), cfg.BloomFilter.FalsePositiveRate, cfg.BloomFilter.File)
+	case cfg.BloomFilter.Backend == config.BloomFilterBackendRedis:
+		// An instance-local filter would not see codes issued elsewhere.
+		logger.Info("Redis unavailable, short code filter disabled")
+	}
+
+	var issuedCodes ports.ShortCodeFilter
+	if codeFilter != nil {
+		issuedCodes = codeFilter
+		// Until loading completes the filter lets every code through.
+		go func() {
+			if err := codeFilter.Load(context.Background(), urlRepo); err != nil {
+				logger.Error("Failed to load short code filter: %v", err)
+			}
+		}()
+		logger.Info("Short code filter enabled (%s)", cfg.BloomFilter.Backend)
+	}
+
+	keyPoolOptions := keypool.Options{
+		LowWater:       cfg.KeyPool.LowWater,
+		Target:         cfg.KeyPool.Target,
+		BatchSize:      cfg.KeyPool.BatchSize,
+		RefillInterval: cfg.KeyPool.RefillInterval,
+	}
+	var keyPool *keypool.Pool
+	switch {
+	case cfg.KeyPool.Backend == config.KeyPoolBackendRedis && redisClient != nil:
+		keyPool = keypool.NewRedisPool(redisClient, codeGenerator, urlRepo, metrics, keyPoolOptions)
+	case cfg.KeyPool.Backend == config.KeyPoolBackendPostgres:
+		keyPool = keypool.NewPostgresPool(db, codeGenerator, urlRepo, metrics, keyPoolOptions)
+	case cfg.KeyPool.Backend == config.KeyPoolBackendRedis:
+		logger.Info("Redis unavailable, short code pool disabled")
+	}
+
+	var shortCodePool ports.ShortCodePool
+	if keyPool != nil {
+		shortCodePool = keyPool
+		keyPool.Start()
+		healthChecker.RegisterCheck("key_pool", keyPool.HealthCheck, false)
+		logger.Info("Short code pool enabled (%s)", cfg.KeyPool.Backend)
+	}
+
+	domainService := service.NewDomainService(gorm.NewDomainRepository(db), cfg.App.DomainRefreshInterval)
+	if err := domainService.Refresh(context.Background()); err != nil {
+		logger.Error("Failed to load domains: %v", err)
+	}
+	domainService.Start()
+
+	var resolver screening.Resolver
+	if cfg.Screening.ResolveHosts {
+		resolver = net.DefaultResolver
+	}
+	screeners := screening.NewPipeline(
+		screening.NewSelfLinkScreener(cfg.App.BaseURL, domainService),
+		screening.NewPrivateHostScreener(resolver),
+	)
+	if cfg.Screening.BlocklistFile != "" || cfg.Screening.AllowlistFile != "" {
+		hostList, err := screening.LoadHostList(cfg.Screening.BlocklistFile, cfg.Screening.AllowlistFile)
+		if err != nil {
+			logger.Error("Failed to load screening host lists: %v", err)
+		} else {
+			screeners = append(screeners, hostList)
+		}
+	}
+	if cfg.Screening.HashPrefixFile != "" {
+		hashList, err := screening.LoadHashPrefixList(cfg.Screening.HashPrefixFile)
+		if err != nil {
+			logger.Error("Failed to load screening hash prefix list: %v", err)
+		} else {
+			screeners = append(screeners, hashList)
+		}
+	}
+
+	canonicalOptions := []canonical.Option{
+		canonical.WithStrippedParams(cfg.App.CanonicalStripParams),
+	}
+	if cfg.App.CanonicalSortQuery {
+		canonicalOptions = append(canonicalOptions, canonical.WithSortedQuery())
+	}
+	canonicalizer := canonical.NewCanonicalizer(canonicalOptions...)
+
+	baseURLService := service.NewURLService(urlRepo, codeGenerator, aliasValidator,
+		service.WithShortCodeFilter(issuedCodes),
+		service.WithShortCodePool(shortCodePool),
+		service.WithScreener(screeners),
+		service.WithCanonicalizer(canonicalizer),
+		service.WithMaxURLLength(cfg.App.MaxURLLength),
+	)
 
 	var urlService ports.URLService = baseURLService
+	var clickFlusher *service.ClickFlusher
 	if redisCache != nil {
-		urlService = service.NewCachedURLService(baseURLService, redisCache, urlRepo)
+		linkCache := redisCache
+		var cacheMetrics ports.CacheMetrics = metrics
+		if cfg.LocalCache.MaxEntries > 0 {
+			// The two-level cache reports hits and misses per tier itself.
+			linkCache = local.NewTwoLevelCache(
+				local.NewLocalCache(cfg.LocalCache.MaxEntries, cfg.LocalCache.MaxBytes, cfg.LocalCache.TTL),
+				redisCache,
+				metrics,
+			)
+			cacheMetrics = nil
+			logger.Info("Local cache enabled")
+		}
+
+		urlService = service.NewCachedURLService(baseURLService, linkCache, cacheMetrics, cfg.Redis.NegativeTTL)
 		logger.Info("Cached URL service enabled")
+
+		clickFlusher = service.NewClickFlusher(redisCache, urlRepo, cfg.Redis.ClickFlushInterval)
+		clickFlusher.Start()
+	}
+
+	if issuedCodes != nil {
+		urlService = service.NewGuardedURLService(urlService, issuedCodes)
+	}
+
+	if err := cfg.ValidateAnalytics(); err != nil {
+		logger.Error("Invalid analytics configuration: %v", err)
+		os.Exit(1)
+	}
+	clickRecorder := service.NewAsyncClickRecorder(
+		gorm.NewClickRepository(db),
+		cfg.Analytics.BufferSize,
+		cfg.Analytics.BatchSize,
+		cfg.Analytics.FlushInterval,
+		cfg.Analytics.IPHashSalt,
+	)
+	clickRecorder.Start()
+
+	statsRepo := gorm.NewStatsRepository(db)
+	statsService := service.NewStatsService(urlRepo, statsRepo)
+
+	statsAggregator := service.NewStatsAggregator(
+		statsRepo,
+		cfg.Analytics.RollupInterval,
+		cfg.Analytics.RollupSettleDelay,
+	)
+	statsAggregator.Start()
+
+	var linkChecker *linkcheck.Checker
+	if cfg.LinkCheck.Enabled {
+		linkChecker = linkcheck.NewChecker(urlRepo, linkcheck.Options{
+			Interval:     cfg.LinkCheck.Interval,
+			BatchSize:    cfg.LinkCheck.BatchSize,
+			RecheckAfter: cfg.LinkCheck.RecheckAfter,
+			Concurrency:  cfg.LinkCheck.Concurrency,
+			HostDelay:    cfg.LinkCheck.HostDelay,
+			Timeout:      cfg.LinkCheck.Timeout,
+		})
+		linkChecker.Start()
+		logger.Info("Dead link checking enabled")
+	}
+
+	var idempotencyStore ports.IdempotencyStore
+	var idempotencyPurger *idempotency.PostgresStore
+	switch {
+	case cfg.Idempotency.Backend == config.IdempotencyBackendRedis && redisClient != nil:
+		idempotencyStore = idempotency.NewRedisStore(redisClient, cfg.Idempotency.TTL)
+	case cfg.Idempotency.Backend != "":
+		if cfg.Idempotency.Backend == config.IdempotencyBackendRedis {
+			logger.Info("Redis unavailable, storing idempotency keys in Postgres")
+		}
+		idempotencyPurger = idempotency.NewPostgresStore(db, cfg.Idempotency.TTL)
+		idempotencyPurger.Start()
+		idempotencyStore = idempotencyPurger
+	}
+	if idempotencyStore != nil {
+		logger.Info("Idempotency keys enabled (%s)", cfg.Idempotency.Backend)
+	}
+
+	apiKeyService := service.NewAPIKeyService(gorm.NewAPIKeyRepository(db))
+
+	router := http.NewRouter(urlService, statsService, apiKeyService, cfg.App.BaseURL, healthChecker, metrics,
+		http.WithDomainService(domainService),
+		http.WithClickRecorder(clickRecorder),
+		http.WithIdempotencyStore(idempotencyStore),
+		http.WithRedirectCacheMaxAge(cfg.App.RedirectCacheMaxAge),
+	)
+	var limiter ports.RateLimiter
+	switch {
+	case cfg.App.RateLimitBackend == config.RateLimitBackendRedis && redisClient != nil:
+		limiter = ratelimit.NewRedisLimiter(redisClient, float64(cfg.App.RateLimitPerSecond), cfg.App.RateLimitBurst)
+		logger.Info("Distributed rate limiting enabled")
+	default:
+		if cfg.App.RateLimitBackend == config.RateLimitBackendRedis {
+			logger.Info("Redis unavailable, falling back to in-memory rate limiting")
+		}
+		limiter = ratelimit.NewMemoryLimiter(float64(cfg.App.RateLimitPerSecond), cfg.App.RateLimitBurst)
 	}
 
-	router := http.NewRouter(urlService, cfg.App.BaseURL, healthChecker, metrics)
-	rateLimiter := http.NewRateLimiter(1000, 100)
+	clientIP, err := http.NewClientIPResolver(cfg.Server.TrustedProxies, cfg.Server.ClientIPHeader)
+	if err != nil {
+		logger.Error("Invalid client IP settings, trusting no proxies: %v", err)
+		clientIP, _ = http.NewClientIPResolver(nil, "")
+	}
+	router.Use(clientIP.Middleware)
+
+	rateLimiter := http.NewRateLimiter(limiter, cfg.App.RateLimitBurst)
 	router.Use(rateLimiter.Limit)
 
 	monitoring := http.NewMonitoringMiddleware(metrics)
@@ -109,7 +349,7 @@ func main() {
 		logger.Info("Environment: %s", cfg.Server.Env)
 
 		if err := server.ListenAndServe(); err != nil && err != nethttp.ErrServerClosed {
-			logger.Error("Failed to start server:", err)
+			logger.Error("Failed to start server: %v", err)
 		}
 	}()
 
@@ -123,7 +363,38 @@ func main() {
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		logger.Error("Server forced to shutdown:", err)
+		logger.Error("Server forced to shutdown: %v", err)
+	}
+
+	statsAggregator.Stop()
+	domainService.Stop()
+	if linkChecker != nil {
+		linkChecker.Stop()
+	}
+	if idempotencyPurger != nil {
+		idempotencyPurger.Stop()
+	}
+
+	if err := clickRecorder.Stop(ctx); err != nil {
+		logger.Error("Failed to write pending click events: %v", err)
+	}
+
+	if keyPool != nil {
+		if err := keyPool.Stop(ctx); err != nil {
+			logger.Error("Failed to return unused short codes to the pool: %v", err)
+		}
+	}
+
+	if clickFlusher != nil {
+		if err := clickFlusher.Stop(ctx); err != nil {
+			logger.Error("Failed to flush click counts: %v", err)
+		}
+	}
+
+	if codeFilter != nil {
+		if err := codeFilter.Close(); err != nil {
+			logger.Error("Failed to persist short code filter: %v", err)
+		}
 	}
 
 	logger.Info("Server stopped gracefully")
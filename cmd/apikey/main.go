@@ -0,0 +1,128 @@
+// Command apikey mints, lists and revokes API keys.
+//
+//	apikey create -owner <owner id> [-name <label>]
+//	apikey list -owner <owner id>
+//	apikey revoke -id <key id>
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/adapters/repository/gorm"
+	"github.com/mikiasyonas/url-shortener/internal/app/service"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+	"github.com/mikiasyonas/url-shortener/pkg/config"
+	"github.com/mikiasyonas/url-shortener/pkg/database"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `usage:
+  apikey create -owner <owner id> [-name <label>]
+  apikey list -owner <owner id>
+  apikey revoke -id <key id>`
+
+func main() {
+	if len(os.Args) < 2 {
+		fail(usage)
+	}
+
+	env := os.Getenv("ENVIRONMENT")
+	if env == "" || env == "development" {
+		_ = godotenv.Load()
+	}
+
+	cfg := config.Load()
+	db, err := database.Connect(&cfg.Database)
+	if err != nil {
+		fail("Failed to connect to database: %v", err)
+	}
+
+	keys := service.NewAPIKeyService(gorm.NewAPIKeyRepository(db))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "create":
+		create(ctx, keys, args)
+	case "list":
+		list(ctx, keys, args)
+	case "revoke":
+		revoke(ctx, keys, args)
+	default:
+		fail(usage)
+	}
+}
+
+func create(ctx context.Context, keys ports.APIKeyService, args []string) {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	owner := fs.String("owner", "", "owner the key acts on behalf of")
+	name := fs.String("name", "", "label to help identify the key")
+	fs.Parse(args)
+
+	if *owner == "" {
+		fail("-owner is required")
+	}
+
+	key, secret, err := keys.Create(ctx, *owner, *name)
+	if err != nil {
+		fail("Failed to create API key: %v", err)
+	}
+
+	fmt.Printf("id:     %s\n", key.ID)
+	fmt.Printf("owner:  %s\n", key.OwnerID)
+	fmt.Printf("key:    %s\n", secret)
+	fmt.Println("Store the key now; it cannot be shown again.")
+}
+
+func list(ctx context.Context, keys ports.APIKeyService, args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	owner := fs.String("owner", "", "owner whose keys to list")
+	fs.Parse(args)
+
+	if *owner == "" {
+		fail("-owner is required")
+	}
+
+	result, err := keys.List(ctx, *owner)
+	if err != nil {
+		fail("Failed to list API keys: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPREFIX\tNAME\tCREATED\tREVOKED")
+	for _, key := range result {
+		revoked := "-"
+		if key.RevokedAt != nil {
+			revoked = key.RevokedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", key.ID, key.Prefix, key.Name, key.CreatedAt.Format(time.RFC3339), revoked)
+	}
+	w.Flush()
+}
+
+func revoke(ctx context.Context, keys ports.APIKeyService, args []string) {
+	fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+	id := fs.String("id", "", "ID of the key to revoke")
+	fs.Parse(args)
+
+	if *id == "" {
+		fail("-id is required")
+	}
+
+	if err := keys.Revoke(ctx, *id); err != nil {
+		fail("Failed to revoke API key: %v", err)
+	}
+	fmt.Printf("Revoked %s\n", *id)
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -12,6 +12,15 @@ import (
 func main() {
 	stmts, err := gormschema.New("postgres").Load(
 		&domain.URL{},
+		&domain.APIKey{},
+		&domain.Click{},
+		&domain.ClickRollup{},
+		&domain.ClickDimensionRollup{},
+		&domain.ClickDailyVisitor{},
+		&domain.RollupCheckpoint{},
+		&domain.PooledShortCode{},
+		&domain.Domain{},
+		&domain.IdempotencyKey{},
 	)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to load gorm schema: %v\n", err)
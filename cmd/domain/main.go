@@ -0,0 +1,103 @@
+// Command domain registers and lists branded short domains.
+//
+//	domain add -host <host> [-owner <owner id>]
+//	domain list [-owner <owner id>]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/adapters/repository/gorm"
+	"github.com/mikiasyonas/url-shortener/internal/app/service"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+	"github.com/mikiasyonas/url-shortener/pkg/config"
+	"github.com/mikiasyonas/url-shortener/pkg/database"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `usage:
+  domain add -host <host> [-owner <owner id>]
+  domain list [-owner <owner id>]`
+
+func main() {
+	if len(os.Args) < 2 {
+		fail(usage)
+	}
+
+	env := os.Getenv("ENVIRONMENT")
+	if env == "" || env == "development" {
+		_ = godotenv.Load()
+	}
+
+	cfg := config.Load()
+	db, err := database.Connect(&cfg.Database)
+	if err != nil {
+		fail("Failed to connect to database: %v", err)
+	}
+
+	domains := service.NewDomainService(gorm.NewDomainRepository(db), cfg.App.DomainRefreshInterval)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "add":
+		add(ctx, domains, args)
+	case "list":
+		list(ctx, domains, args)
+	default:
+		fail(usage)
+	}
+}
+
+func add(ctx context.Context, domains ports.DomainService, args []string) {
+	fs := flag.NewFlagSet("add", flag.ExitOnError)
+	host := fs.String("host", "", "host name the domain is served on")
+	owner := fs.String("owner", "", "owner allowed to use the domain; empty shares it with everyone")
+	fs.Parse(args)
+
+	if *host == "" {
+		fail("-host is required")
+	}
+
+	d, err := domains.Register(ctx, *host, *owner)
+	if err != nil {
+		fail("Failed to register domain: %v", err)
+	}
+
+	fmt.Printf("Registered %s\n", d.Host)
+	fmt.Println("Point its DNS at this service so redirects reach it.")
+}
+
+func list(ctx context.Context, domains ports.DomainService, args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	owner := fs.String("owner", "", "list only domains usable by this owner")
+	fs.Parse(args)
+
+	result, err := domains.List(ctx, *owner)
+	if err != nil {
+		fail("Failed to list domains: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tOWNER\tCREATED")
+	for _, d := range result {
+		owner := d.OwnerID
+		if owner == "" {
+			owner = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", d.Host, owner, d.CreatedAt.Format(time.RFC3339))
+	}
+	w.Flush()
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
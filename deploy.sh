@@ -52,16 +52,21 @@ curl -s http://localhost/health || echo "Health check failed"
 
 echo ""
 echo "Testing URL shortening:"
-SHORT_URL=$(curl -s -X POST http://localhost/api/shorten \
-  -H "Content-Type: application/json" \
-  -d '{"url":"https://example.com"}' | grep -o '"short_url":"[^"]*"' | cut -d'"' -f4)
-
-if [ -n "$SHORT_URL" ]; then
-    echo "Short URL created: $SHORT_URL"
-    echo "Testing redirect:"
-    curl -I -s $SHORT_URL | head -n 1
+if [ -z "$API_KEY" ]; then
+    echo "API_KEY not set, skipping (mint one with: go run ./cmd/apikey create -owner <owner>)"
 else
-    echo "URL shortening failed"
+    SHORT_URL=$(curl -s -X POST http://localhost/api/shorten \
+      -H "Authorization: Bearer $API_KEY" \
+      -H "Content-Type: application/json" \
+      -d '{"url":"https://example.com"}' | grep -o '"short_url":"[^"]*"' | cut -d'"' -f4)
+
+    if [ -n "$SHORT_URL" ]; then
+        echo "Short URL created: $SHORT_URL"
+        echo "Testing redirect:"
+        curl -I -s $SHORT_URL | head -n 1
+    else
+        echo "URL shortening failed"
+    fi
 fi
 
 echo ""
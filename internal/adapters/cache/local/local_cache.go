@@ -0,0 +1,160 @@
+package local
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+// entryOverhead approximates the memory an entry costs beyond its strings:
+// the list element, map bucket and the fixed-size fields of domain.URL.
+const entryOverhead = 256
+
+// LocalCache is a bounded in-process LRU cache of links. It is limited both
+// by entry count and by an estimate of the bytes held, and never keeps an
+// entry longer than its TTL, which should be short: other instances cannot
+// invalidate it, so the TTL bounds how stale a redirect can be after a link
+// is updated or deleted elsewhere. It holds no click counters, which must be
+// shared by all instances; TwoLevelCache keeps them in L2.
+type LocalCache struct {
+	mu         sync.Mutex
+	items      map[domain.LinkRef]*list.Element
+	order      *list.List
+	bytes      int
+	maxEntries int
+	maxBytes   int
+	ttl        time.Duration
+}
+
+type entry struct {
+	key       domain.LinkRef
+	url       domain.URL
+	notFound  bool
+	size      int
+	expiresAt time.Time
+}
+
+func NewLocalCache(maxEntries, maxBytes int, ttl time.Duration) *LocalCache {
+	return &LocalCache{
+		items:      make(map[domain.LinkRef]*list.Element),
+		order:      list.New(),
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ttl:        ttl,
+	}
+}
+
+func (c *LocalCache) GetURL(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[domain.LinkRef{Domain: host, ShortCode: shortCode}]
+	if !ok {
+		return nil, ports.ErrCacheMiss
+	}
+
+	e := elem.Value.(*entry)
+	if !time.Now().Before(e.expiresAt) {
+		c.remove(elem)
+		return nil, ports.ErrCacheMiss
+	}
+
+	c.order.MoveToFront(elem)
+	if e.notFound {
+		return nil, domain.ErrURLNotFound
+	}
+	url := e.url
+	return &url, nil
+}
+
+// SetURL caches a copy of url for the shorter of ttl (in seconds), the
+// cache's own TTL and the link's remaining lifetime.
+func (c *LocalCache) SetURL(ctx context.Context, url *domain.URL, ttl int) error {
+	now := time.Now()
+
+	cacheTTL := c.ttl
+	if ttl > 0 && time.Duration(ttl)*time.Second < cacheTTL {
+		cacheTTL = time.Duration(ttl) * time.Second
+	}
+	if url.ExpiresAt != nil {
+		if remaining := url.ExpiresAt.Sub(now); remaining < cacheTTL {
+			cacheTTL = remaining
+		}
+	}
+
+	c.store(&entry{
+		key:       domain.LinkRef{Domain: url.Domain, ShortCode: url.ShortCode},
+		url:       *url,
+		size:      entryOverhead + len(url.ID) + len(url.Domain) + len(url.ShortCode) + len(url.OriginalURL) + len(url.OwnerID),
+		expiresAt: now.Add(cacheTTL),
+	}, cacheTTL)
+	return nil
+}
+
+// SetNotFound remembers a missing link for the shorter of ttl (in
+// seconds) and the cache's own TTL.
+func (c *LocalCache) SetNotFound(ctx context.Context, host, shortCode string, ttl int) error {
+	cacheTTL := c.ttl
+	if ttl > 0 && time.Duration(ttl)*time.Second < cacheTTL {
+		cacheTTL = time.Duration(ttl) * time.Second
+	}
+
+	c.store(&entry{
+		key:       domain.LinkRef{Domain: host, ShortCode: shortCode},
+		notFound:  true,
+		size:      entryOverhead + len(host) + len(shortCode),
+		expiresAt: time.Now().Add(cacheTTL),
+	}, cacheTTL)
+	return nil
+}
+
+func (c *LocalCache) DeleteURL(ctx context.Context, host, shortCode string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[domain.LinkRef{Domain: host, ShortCode: shortCode}]; ok {
+		c.remove(elem)
+	}
+	return nil
+}
+
+// Len reports the number of cached links, including expired ones that have
+// not been evicted yet.
+func (c *LocalCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.items)
+}
+
+// store replaces any entry for e.key with e, evicting the least
+// recently used entries until the cache is back within its limits.
+func (c *LocalCache) store(e *entry, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[e.key]; ok {
+		c.remove(elem)
+	}
+
+	if ttl <= 0 || e.size > c.maxBytes || c.maxEntries <= 0 {
+		return
+	}
+
+	c.items[e.key] = c.order.PushFront(e)
+	c.bytes += e.size
+
+	for len(c.items) > c.maxEntries || c.bytes > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *LocalCache) remove(elem *list.Element) {
+	e := c.order.Remove(elem).(*entry)
+	delete(c.items, e.key)
+	c.bytes -= e.size
+}
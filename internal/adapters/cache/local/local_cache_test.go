@@ -0,0 +1,188 @@
+package local_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/adapters/cache/local"
+	"github.com/mikiasyonas/url-shortener/internal/adapters/cache/redis"
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newURL(shortCode string) *domain.URL {
+	return &domain.URL{
+		ID:          "id-" + shortCode,
+		OriginalURL: "https://example.com/" + shortCode,
+		ShortCode:   shortCode,
+		CreatedAt:   time.Now(),
+	}
+}
+
+func TestLocalCache_GetSetURL(t *testing.T) {
+	cache := local.NewLocalCache(10, 1<<20, time.Minute)
+	ctx := context.Background()
+
+	require.NoError(t, cache.SetURL(ctx, newURL("abc123"), 0))
+
+	url, err := cache.GetURL(ctx, "", "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/abc123", url.OriginalURL)
+
+	// Callers get a copy and cannot corrupt the cached entry.
+	url.OriginalURL = "https://evil.example"
+	url, err = cache.GetURL(ctx, "", "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/abc123", url.OriginalURL)
+
+	_, err = cache.GetURL(ctx, "", "missing")
+	assert.ErrorIs(t, err, ports.ErrCacheMiss)
+}
+
+func TestLocalCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := local.NewLocalCache(2, 1<<20, time.Minute)
+	ctx := context.Background()
+
+	cache.SetURL(ctx, newURL("a"), 0)
+	cache.SetURL(ctx, newURL("b"), 0)
+	_, err := cache.GetURL(ctx, "", "a")
+	require.NoError(t, err)
+	cache.SetURL(ctx, newURL("c"), 0)
+
+	assert.Equal(t, 2, cache.Len())
+	_, err = cache.GetURL(ctx, "", "b")
+	assert.ErrorIs(t, err, ports.ErrCacheMiss)
+	_, err = cache.GetURL(ctx, "", "a")
+	assert.NoError(t, err)
+	_, err = cache.GetURL(ctx, "", "c")
+	assert.NoError(t, err)
+}
+
+func TestLocalCache_EvictsToStayWithinBytes(t *testing.T) {
+	cache := local.NewLocalCache(100, 1000, time.Minute)
+	ctx := context.Background()
+
+	big := newURL("big")
+	big.OriginalURL = "https://example.com/" + strings.Repeat("x", 600)
+	cache.SetURL(ctx, newURL("a"), 0)
+	cache.SetURL(ctx, big, 0)
+
+	_, err := cache.GetURL(ctx, "", "a")
+	assert.ErrorIs(t, err, ports.ErrCacheMiss)
+	_, err = cache.GetURL(ctx, "", "big")
+	assert.NoError(t, err)
+
+	// An entry larger than the whole cache is never stored.
+	huge := newURL("huge")
+	huge.OriginalURL = "https://example.com/" + strings.Repeat("x", 2000)
+	cache.SetURL(ctx, huge, 0)
+	_, err = cache.GetURL(ctx, "", "huge")
+	assert.ErrorIs(t, err, ports.ErrCacheMiss)
+}
+
+func TestLocalCache_TTL(t *testing.T) {
+	cache := local.NewLocalCache(10, 1<<20, 20*time.Millisecond)
+	ctx := context.Background()
+
+	expiring := newURL("soon")
+	expiresAt := time.Now().Add(-time.Second)
+	expiring.ExpiresAt = &expiresAt
+	cache.SetURL(ctx, expiring, 0)
+	_, err := cache.GetURL(ctx, "", "soon")
+	assert.ErrorIs(t, err, ports.ErrCacheMiss, "expired links are not cached")
+
+	cache.SetURL(ctx, newURL("abc123"), 3600)
+	_, err = cache.GetURL(ctx, "", "abc123")
+	require.NoError(t, err)
+
+	time.Sleep(30 * time.Millisecond)
+	_, err = cache.GetURL(ctx, "", "abc123")
+	assert.ErrorIs(t, err, ports.ErrCacheMiss)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestLocalCache_SetNotFound(t *testing.T) {
+	cache := local.NewLocalCache(10, 1<<20, time.Minute)
+	ctx := context.Background()
+
+	require.NoError(t, cache.SetNotFound(ctx, "", "abc123", 30))
+	_, err := cache.GetURL(ctx, "", "abc123")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	require.NoError(t, cache.SetURL(ctx, newURL("abc123"), 0))
+	_, err = cache.GetURL(ctx, "", "abc123")
+	assert.NoError(t, err)
+
+	require.NoError(t, cache.SetNotFound(ctx, "", "abc123", 30))
+	require.NoError(t, cache.DeleteURL(ctx, "", "abc123"))
+	_, err = cache.GetURL(ctx, "", "abc123")
+	assert.ErrorIs(t, err, ports.ErrCacheMiss)
+}
+
+type countingMetrics struct {
+	hits, misses, negative map[string]int
+}
+
+func newCountingMetrics() *countingMetrics {
+	return &countingMetrics{hits: map[string]int{}, misses: map[string]int{}, negative: map[string]int{}}
+}
+
+func (m *countingMetrics) RecordCacheHit(cache string)         { m.hits[cache]++ }
+func (m *countingMetrics) RecordCacheMiss(cache string)        { m.misses[cache]++ }
+func (m *countingMetrics) RecordCacheNegativeHit(cache string) { m.negative[cache]++ }
+
+func TestTwoLevelCache(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	shared, err := redis.NewRedisCache(mr.Addr(), "", 0, time.Hour)
+	require.NoError(t, err)
+	defer shared.Close()
+
+	ctx := context.Background()
+	metrics := newCountingMetrics()
+	l1 := local.NewLocalCache(10, 1<<20, time.Minute)
+	cache := local.NewTwoLevelCache(l1, shared, metrics)
+
+	// A link cached by another instance is promoted into L1 on first read.
+	require.NoError(t, shared.SetURL(ctx, newURL("abc123"), 0))
+	for i := 0; i < 3; i++ {
+		url, err := cache.GetURL(ctx, "", "abc123")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/abc123", url.OriginalURL)
+	}
+	assert.Equal(t, map[string]int{"local": 2, "redis": 1}, metrics.hits)
+	assert.Equal(t, map[string]int{"local": 1}, metrics.misses)
+
+	_, err = cache.GetURL(ctx, "", "missing")
+	assert.ErrorIs(t, err, ports.ErrCacheMiss)
+	assert.Equal(t, 1, metrics.misses["redis"])
+
+	// Negative entries from Redis are promoted into L1 too.
+	require.NoError(t, shared.SetNotFound(ctx, "", "unknown", 30))
+	for i := 0; i < 2; i++ {
+		_, err = cache.GetURL(ctx, "", "unknown")
+		assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	}
+	assert.Equal(t, map[string]int{"local": 1, "redis": 1}, metrics.negative)
+
+	// Deletes reach both levels.
+	require.NoError(t, cache.DeleteURL(ctx, "", "abc123"))
+	_, err = l1.GetURL(ctx, "", "abc123")
+	assert.ErrorIs(t, err, ports.ErrCacheMiss)
+	_, err = shared.GetURL(ctx, "", "abc123")
+	assert.ErrorIs(t, err, ports.ErrCacheMiss)
+
+	// Click counters are shared, so they bypass L1.
+	require.NoError(t, cache.IncrementClickCount(ctx, "", "abc123"))
+	count, err := shared.GetClickCount(ctx, "", "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
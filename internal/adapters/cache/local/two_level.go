@@ -0,0 +1,97 @@
+package local
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+const (
+	localTier  = "local"
+	sharedTier = "redis"
+)
+
+// TwoLevelCache serves links from an in-process LocalCache (L1) and falls
+// back to a shared cache such as Redis (L2), promoting L2 hits into L1.
+// Writes and invalidations go to both levels. Click counters must be shared
+// across instances, so they live in L2 only.
+//
+// It records hits and misses for both tiers, labelled "local" and "redis".
+type TwoLevelCache struct {
+	l1      *LocalCache
+	l2      ports.Cache
+	metrics ports.CacheMetrics
+}
+
+func NewTwoLevelCache(l1 *LocalCache, l2 ports.Cache, metrics ports.CacheMetrics) *TwoLevelCache {
+	if metrics == nil {
+		metrics = noopCacheMetrics{}
+	}
+	return &TwoLevelCache{
+		l1:      l1,
+		l2:      l2,
+		metrics: metrics,
+	}
+}
+
+func (c *TwoLevelCache) GetURL(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	url, err := c.l1.GetURL(ctx, host, shortCode)
+	switch {
+	case err == nil:
+		c.metrics.RecordCacheHit(localTier)
+		return url, nil
+	case errors.Is(err, domain.ErrURLNotFound):
+		c.metrics.RecordCacheNegativeHit(localTier)
+		return nil, err
+	}
+	c.metrics.RecordCacheMiss(localTier)
+
+	url, err = c.l2.GetURL(ctx, host, shortCode)
+	switch {
+	case err == nil:
+		c.metrics.RecordCacheHit(sharedTier)
+		c.l1.SetURL(ctx, url, 0)
+		return url, nil
+	case errors.Is(err, domain.ErrURLNotFound):
+		c.metrics.RecordCacheNegativeHit(sharedTier)
+		c.l1.SetNotFound(ctx, host, shortCode, 0)
+		return nil, err
+	}
+	c.metrics.RecordCacheMiss(sharedTier)
+	return nil, err
+}
+
+func (c *TwoLevelCache) SetURL(ctx context.Context, url *domain.URL, ttl int) error {
+	c.l1.SetURL(ctx, url, ttl)
+	return c.l2.SetURL(ctx, url, ttl)
+}
+
+func (c *TwoLevelCache) SetNotFound(ctx context.Context, host, shortCode string, ttl int) error {
+	c.l1.SetNotFound(ctx, host, shortCode, ttl)
+	return c.l2.SetNotFound(ctx, host, shortCode, ttl)
+}
+
+func (c *TwoLevelCache) DeleteURL(ctx context.Context, host, shortCode string) error {
+	c.l1.DeleteURL(ctx, host, shortCode)
+	return c.l2.DeleteURL(ctx, host, shortCode)
+}
+
+func (c *TwoLevelCache) IncrementClickCount(ctx context.Context, host, shortCode string) error {
+	return c.l2.IncrementClickCount(ctx, host, shortCode)
+}
+
+func (c *TwoLevelCache) GetClickCount(ctx context.Context, host, shortCode string) (int64, error) {
+	return c.l2.GetClickCount(ctx, host, shortCode)
+}
+
+func (c *TwoLevelCache) DrainClickCounts(ctx context.Context) (map[domain.LinkRef]int64, error) {
+	return c.l2.DrainClickCounts(ctx)
+}
+
+type noopCacheMetrics struct{}
+
+func (noopCacheMetrics) RecordCacheHit(string)         {}
+func (noopCacheMetrics) RecordCacheMiss(string)        {}
+func (noopCacheMetrics) RecordCacheNegativeHit(string) {}
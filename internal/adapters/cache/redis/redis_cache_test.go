@@ -7,6 +7,7 @@ import (
 
 	"github.com/mikiasyonas/url-shortener/internal/adapters/cache/redis"
 	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/stretchr/testify/assert"
@@ -34,7 +35,7 @@ func TestRedisCache_GetSetURL(t *testing.T) {
 	err = cache.SetURL(ctx, url, 3600)
 	assert.NoError(t, err)
 
-	retrieved, err := cache.GetURL(ctx, "abc123")
+	retrieved, err := cache.GetURL(ctx, "", "abc123")
 	assert.NoError(t, err)
 	assert.Equal(t, url.OriginalURL, retrieved.OriginalURL)
 	assert.Equal(t, url.ShortCode, retrieved.ShortCode)
@@ -51,8 +52,8 @@ func TestRedisCache_GetURL_NotFound(t *testing.T) {
 
 	ctx := context.Background()
 
-	_, err = cache.GetURL(ctx, "nonexistent")
-	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	_, err = cache.GetURL(ctx, "", "nonexistent")
+	assert.ErrorIs(t, err, ports.ErrCacheMiss)
 }
 
 func TestRedisCache_IncrementClickCount(t *testing.T) {
@@ -66,17 +67,17 @@ func TestRedisCache_IncrementClickCount(t *testing.T) {
 
 	ctx := context.Background()
 
-	err = cache.IncrementClickCount(ctx, "abc123")
+	err = cache.IncrementClickCount(ctx, "", "abc123")
 	assert.NoError(t, err)
 
-	count, err := cache.GetClickCount(ctx, "abc123")
+	count, err := cache.GetClickCount(ctx, "", "abc123")
 	assert.NoError(t, err)
 	assert.Equal(t, int64(1), count)
 
-	err = cache.IncrementClickCount(ctx, "abc123")
+	err = cache.IncrementClickCount(ctx, "", "abc123")
 	assert.NoError(t, err)
 
-	count, err = cache.GetClickCount(ctx, "abc123")
+	count, err = cache.GetClickCount(ctx, "", "abc123")
 	assert.NoError(t, err)
 	assert.Equal(t, int64(2), count)
 }
@@ -102,9 +103,174 @@ func TestRedisCache_DeleteURL(t *testing.T) {
 	err = cache.SetURL(ctx, url, 3600)
 	assert.NoError(t, err)
 
-	err = cache.DeleteURL(ctx, "abc123")
+	err = cache.DeleteURL(ctx, "", "abc123")
+	assert.NoError(t, err)
+
+	_, err = cache.GetURL(ctx, "", "abc123")
+	assert.ErrorIs(t, err, ports.ErrCacheMiss)
+}
+
+func TestRedisCache_SetURL_CapsTTLAtExpiry(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	cache, err := redis.NewRedisCache(mr.Addr(), "", 0, time.Hour)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	expiresAt := time.Now().Add(10 * time.Minute)
+	url := &domain.URL{
+		OriginalURL: "https://example.com",
+		ShortCode:   "abc123",
+		ExpiresAt:   &expiresAt,
+	}
+
+	err = cache.SetURL(ctx, url, 3600)
+	assert.NoError(t, err)
+
+	ttl := mr.TTL("url:abc123")
+	assert.LessOrEqual(t, ttl, 10*time.Minute)
+	assert.Greater(t, ttl, 9*time.Minute)
+}
+
+func TestRedisCache_SetURL_SkipsExpiredURL(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	cache, err := redis.NewRedisCache(mr.Addr(), "", 0, time.Hour)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	expiresAt := time.Now().Add(-time.Minute)
+	url := &domain.URL{
+		OriginalURL: "https://example.com",
+		ShortCode:   "abc123",
+		ExpiresAt:   &expiresAt,
+	}
+
+	err = cache.SetURL(ctx, url, 3600)
+	assert.NoError(t, err)
+
+	_, err = cache.GetURL(ctx, "", "abc123")
+	assert.ErrorIs(t, err, ports.ErrCacheMiss)
+}
+
+func TestRedisCache_SetNotFound(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	cache, err := redis.NewRedisCache(mr.Addr(), "", 0, time.Hour)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+
+	err = cache.SetNotFound(ctx, "", "abc123", 30)
 	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, mr.TTL("url:abc123"))
 
-	_, err = cache.GetURL(ctx, "abc123")
+	_, err = cache.GetURL(ctx, "", "abc123")
 	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	// Caching the link replaces the negative entry.
+	err = cache.SetURL(ctx, &domain.URL{OriginalURL: "https://example.com", ShortCode: "abc123"}, 3600)
+	assert.NoError(t, err)
+
+	url, err := cache.GetURL(ctx, "", "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", url.OriginalURL)
+
+	err = cache.SetNotFound(ctx, "", "gone", 0)
+	assert.NoError(t, err)
+	assert.False(t, mr.Exists("url:gone"), "negative entries are never stored without a TTL")
+}
+
+func TestRedisCache_DrainClickCounts(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	cache, err := redis.NewRedisCache(mr.Addr(), "", 0, time.Hour)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, cache.IncrementClickCount(ctx, "", "abc123"))
+	}
+	require.NoError(t, cache.IncrementClickCount(ctx, "", "xyz789"))
+	require.NoError(t, cache.IncrementClickCount(ctx, "go.example.com", "abc123"))
+
+	counts, err := cache.DrainClickCounts(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[domain.LinkRef]int64{
+		{ShortCode: "abc123"}:                           3,
+		{ShortCode: "xyz789"}:                           1,
+		{Domain: "go.example.com", ShortCode: "abc123"}: 1,
+	}, counts)
+
+	counts, err = cache.DrainClickCounts(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, counts)
+}
+
+func TestRedisCache_DrainClickCounts_KeepsCountsOnError(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	cache, err := redis.NewRedisCache(mr.Addr(), "", 0, time.Hour)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+
+	require.NoError(t, cache.IncrementClickCount(ctx, "", "abc123"))
+	require.NoError(t, cache.IncrementClickCount(ctx, "", "xyz789"))
+	// GETDEL fails on a key of the wrong type, failing the pipeline.
+	_, err = mr.Lpush("clicks:broken", "1")
+	require.NoError(t, err)
+
+	counts, err := cache.DrainClickCounts(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, map[domain.LinkRef]int64{
+		{ShortCode: "abc123"}: 1,
+		{ShortCode: "xyz789"}: 1,
+	}, counts, "counters deleted by the pipeline are returned")
+	assert.False(t, mr.Exists("clicks:abc123"))
+}
+
+func TestRedisCache_KeysIncludeDomain(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	cache, err := redis.NewRedisCache(mr.Addr(), "", 0, time.Hour)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+
+	require.NoError(t, cache.SetURL(ctx, &domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com"}, 0))
+	require.NoError(t, cache.SetURL(ctx, &domain.URL{Domain: "go.example.com", ShortCode: "abc123", OriginalURL: "https://example.org"}, 0))
+	assert.True(t, mr.Exists("url:abc123"))
+	assert.True(t, mr.Exists("url:go.example.com/abc123"))
+
+	url, err := cache.GetURL(ctx, "go.example.com", "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.org", url.OriginalURL)
+
+	require.NoError(t, cache.DeleteURL(ctx, "go.example.com", "abc123"))
+	_, err = cache.GetURL(ctx, "go.example.com", "abc123")
+	assert.ErrorIs(t, err, ports.ErrCacheMiss)
+
+	url, err = cache.GetURL(ctx, "", "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url.OriginalURL)
 }
@@ -4,13 +4,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
 
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	clickCountPrefix = "clicks:"
+	drainBatchSize   = 500
+
+	// notFoundMarker is stored in place of a link's JSON to remember that the
+	// short code does not exist. JSON objects never take this form.
+	notFoundMarker = "!"
+)
+
 type RedisCache struct {
 	client *redis.Client
 	ttl    time.Duration
@@ -37,16 +48,25 @@ func NewRedisCache(addr, password string, db int, ttl time.Duration) (*RedisCach
 	}, nil
 }
 
-func (r *RedisCache) GetURL(ctx context.Context, shortCode string) (*domain.URL, error) {
-	key := r.urlKey(shortCode)
+// Client exposes the underlying connection pool so that other Redis-backed
+// adapters can share it.
+func (r *RedisCache) Client() *redis.Client {
+	return r.client
+}
+
+func (r *RedisCache) GetURL(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	key := r.urlKey(host, shortCode)
 
 	val, err := r.client.Get(ctx, key).Result()
 	if err == redis.Nil {
-		return nil, domain.ErrURLNotFound
+		return nil, ports.ErrCacheMiss
 	}
 	if err != nil {
 		return nil, err
 	}
+	if val == notFoundMarker {
+		return nil, domain.ErrURLNotFound
+	}
 
 	var url domain.URL
 	if err := json.Unmarshal([]byte(val), &url); err != nil {
@@ -57,7 +77,7 @@ func (r *RedisCache) GetURL(ctx context.Context, shortCode string) (*domain.URL,
 }
 
 func (r *RedisCache) SetURL(ctx context.Context, url *domain.URL, ttl int) error {
-	key := r.urlKey(url.ShortCode)
+	key := r.urlKey(url.Domain, url.ShortCode)
 
 	data, err := json.Marshal(url)
 	if err != nil {
@@ -69,31 +89,109 @@ func (r *RedisCache) SetURL(ctx context.Context, url *domain.URL, ttl int) error
 		cacheTTL = time.Duration(ttl) * time.Second
 	}
 
+	// Never keep a link in the cache past its own expiry.
+	if url.ExpiresAt != nil {
+		remaining := time.Until(*url.ExpiresAt)
+		if remaining <= 0 {
+			return r.client.Del(ctx, key).Err()
+		}
+		if remaining < cacheTTL {
+			cacheTTL = remaining
+		}
+	}
+
 	return r.client.Set(ctx, key, data, cacheTTL).Err()
 }
 
-func (r *RedisCache) DeleteURL(ctx context.Context, shortCode string) error {
-	key := r.urlKey(shortCode)
+func (r *RedisCache) SetNotFound(ctx context.Context, host, shortCode string, ttl int) error {
+	// A marker without expiry would hide a link created later on another
+	// path for good.
+	if ttl <= 0 {
+		return nil
+	}
+	return r.client.Set(ctx, r.urlKey(host, shortCode), notFoundMarker, time.Duration(ttl)*time.Second).Err()
+}
+
+func (r *RedisCache) DeleteURL(ctx context.Context, host, shortCode string) error {
+	key := r.urlKey(host, shortCode)
 	return r.client.Del(ctx, key).Err()
 }
 
-func (r *RedisCache) IncrementClickCount(ctx context.Context, shortCode string) error {
-	key := r.clickCountKey(shortCode)
+func (r *RedisCache) IncrementClickCount(ctx context.Context, host, shortCode string) error {
+	key := r.clickCountKey(host, shortCode)
 	_, err := r.client.Incr(ctx, key).Result()
 	return err
 }
 
-func (r *RedisCache) GetClickCount(ctx context.Context, shortCode string) (int64, error) {
-	key := r.clickCountKey(shortCode)
+func (r *RedisCache) GetClickCount(ctx context.Context, host, shortCode string) (int64, error) {
+	key := r.clickCountKey(host, shortCode)
 	return r.client.Get(ctx, key).Int64()
 }
 
-func (r *RedisCache) urlKey(shortCode string) string {
-	return fmt.Sprintf("url:%s", shortCode)
+// DrainClickCounts collects all click counters with GETDEL so that increments
+// arriving after a key is read start a fresh counter instead of being lost.
+// On error the counts drained so far are returned alongside it.
+func (r *RedisCache) DrainClickCounts(ctx context.Context) (map[domain.LinkRef]int64, error) {
+	var keys []string
+	iter := r.client.Scan(ctx, 0, clickCountPrefix+"*", drainBatchSize).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[domain.LinkRef]int64, len(keys))
+	for start := 0; start < len(keys); start += drainBatchSize {
+		end := min(start+drainBatchSize, len(keys))
+
+		pipe := r.client.Pipeline()
+		cmds := make([]*redis.StringCmd, 0, end-start)
+		for _, key := range keys[start:end] {
+			cmds = append(cmds, pipe.GetDel(ctx, key))
+		}
+		_, execErr := pipe.Exec(ctx)
+
+		// A failed command does not stop the rest of the pipeline, and the
+		// keys it did read are already deleted, so collect them either way.
+		for i, cmd := range cmds {
+			count, err := cmd.Int64()
+			if err != nil {
+				continue
+			}
+			counts[parseLinkKey(strings.TrimPrefix(keys[start+i], clickCountPrefix))] += count
+		}
+		if execErr != nil && execErr != redis.Nil {
+			return counts, execErr
+		}
+	}
+
+	return counts, nil
+}
+
+func (r *RedisCache) urlKey(host, shortCode string) string {
+	return "url:" + linkKey(host, shortCode)
+}
+
+func (r *RedisCache) clickCountKey(host, shortCode string) string {
+	return clickCountPrefix + linkKey(host, shortCode)
 }
 
-func (r *RedisCache) clickCountKey(shortCode string) string {
-	return fmt.Sprintf("clicks:%s", shortCode)
+// linkKey identifies a link within a key. Links on the default domain keep
+// the bare short code, so keys written before branded domains still match;
+// other links are prefixed with their host, which never contains a slash.
+func linkKey(host, shortCode string) string {
+	if host == domain.DefaultDomain {
+		return shortCode
+	}
+	return host + "/" + shortCode
+}
+
+func parseLinkKey(key string) domain.LinkRef {
+	if host, shortCode, ok := strings.Cut(key, "/"); ok {
+		return domain.LinkRef{Domain: host, ShortCode: shortCode}
+	}
+	return domain.LinkRef{Domain: domain.DefaultDomain, ShortCode: key}
 }
 
 func (r *RedisCache) Close() error {
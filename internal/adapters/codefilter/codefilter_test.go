@@ -0,0 +1,145 @@
+package codefilter_test
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/adapters/codefilter"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource serves codes in order and remembers the window it was asked for.
+type fakeSource struct {
+	mu    sync.Mutex
+	codes []string
+	since []time.Time
+}
+
+func (s *fakeSource) ShortCodes(ctx context.Context, createdSince time.Time, after string, limit int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.since = append(s.since, createdSince)
+	sorted := append([]string(nil), s.codes...)
+	sort.Strings(sorted)
+
+	var page []string
+	for _, code := range sorted {
+		if code > after && len(page) < limit {
+			page = append(page, code)
+		}
+	}
+	return page, nil
+}
+
+func assertContains(t *testing.T, filter *codefilter.Filter, code string, expected bool) {
+	t.Helper()
+	maybe, err := filter.MayContain(context.Background(), code)
+	require.NoError(t, err)
+	assert.Equal(t, expected, maybe, code)
+}
+
+func TestMemoryFilter(t *testing.T) {
+	ctx := context.Background()
+	filter := codefilter.NewMemoryFilter(1000, 0.001, "")
+
+	// Nothing is ruled out before the filter has been loaded.
+	assertContains(t, filter, "nope42", true)
+
+	source := &fakeSource{codes: []string{"abc123", "promo"}}
+	require.NoError(t, filter.Load(ctx, source))
+	assert.Equal(t, []time.Time{{}}, source.since, "a fresh filter loads every code")
+
+	assertContains(t, filter, "abc123", true)
+	assertContains(t, filter, "promo", true)
+	assertContains(t, filter, "nope42", false)
+
+	require.NoError(t, filter.Add(ctx, "nope42"))
+	assertContains(t, filter, "nope42", true)
+}
+
+func TestMemoryFilter_Snapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "codes.bloom")
+
+	filter := codefilter.NewMemoryFilter(1000, 0.001, path)
+	require.NoError(t, filter.Load(ctx, &fakeSource{codes: []string{"abc123"}}))
+	require.NoError(t, filter.Add(ctx, "promo"))
+	require.NoError(t, filter.Close())
+
+	restored := codefilter.NewMemoryFilter(1000, 0.001, path)
+	source := &fakeSource{codes: []string{"newer1"}}
+	require.NoError(t, restored.Load(ctx, source))
+
+	require.Len(t, source.since, 1)
+	assert.WithinDuration(t, time.Now().Add(-time.Minute), source.since[0], 5*time.Second, "only codes since the snapshot are reloaded")
+	assertContains(t, restored, "abc123", true)
+	assertContains(t, restored, "promo", true)
+	assertContains(t, restored, "newer1", true)
+
+	// A snapshot of a differently sized filter is not trusted.
+	resized := codefilter.NewMemoryFilter(5000, 0.001, path)
+	source = &fakeSource{}
+	require.NoError(t, resized.Load(ctx, source))
+	assert.Equal(t, []time.Time{{}}, source.since)
+}
+
+func TestRedisFilter(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	first := codefilter.NewRedisFilter(client, 1000, 0.001)
+	source := &fakeSource{codes: []string{"abc123"}}
+	require.NoError(t, first.Load(ctx, source))
+	assert.Equal(t, []time.Time{{}}, source.since)
+
+	// A second instance reuses the loaded bitmap and sees codes added by
+	// the first.
+	second := codefilter.NewRedisFilter(client, 1000, 0.001)
+	source = &fakeSource{}
+	require.NoError(t, second.Load(ctx, source))
+	require.Len(t, source.since, 1)
+	assert.False(t, source.since[0].IsZero(), "a loaded bitmap is not rebuilt")
+
+	require.NoError(t, first.Add(ctx, "promo"))
+	assertContains(t, second, "abc123", true)
+	assertContains(t, second, "promo", true)
+	assertContains(t, second, "nope42", false)
+}
+
+func TestRedisFilter_FailedAddIsNeverRuledOut(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer client.Close()
+	ctx := context.Background()
+
+	filter := codefilter.NewRedisFilter(client, 1000, 0.001)
+	require.NoError(t, filter.Load(ctx, &fakeSource{}))
+
+	mr.SetError("READONLY")
+	assert.Error(t, filter.Add(ctx, "abc123"))
+	assertContains(t, filter, "abc123", true)
+
+	// The failed code is written on the next successful Add.
+	mr.SetError("")
+	require.NoError(t, filter.Add(ctx, "promo"))
+	other := codefilter.NewRedisFilter(client, 1000, 0.001)
+	require.NoError(t, other.Load(ctx, &fakeSource{}))
+	assertContains(t, other, "abc123", true)
+}
@@ -0,0 +1,142 @@
+package codefilter
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	loadBatchSize = 5000
+
+	// catchUpMargin widens the catch-up window after a persisted filter is
+	// restored, covering links whose creation straddled the snapshot.
+	catchUpMargin = time.Minute
+)
+
+// ShortCodeSource lists issued short codes; it is satisfied by
+// ports.URLRepository.
+type ShortCodeSource interface {
+	ShortCodes(ctx context.Context, createdSince time.Time, after string, limit int) ([]string, error)
+}
+
+// bitStore holds the filter's bits.
+type bitStore interface {
+	add(ctx context.Context, shortCode string) error
+	test(ctx context.Context, shortCode string) (bool, error)
+
+	// resumeFrom reports whether the store already holds every code issued
+	// before the returned time, so that only later codes need loading.
+	resumeFrom(ctx context.Context) (time.Time, bool, error)
+	// markLoaded records that every code issued before at has been added.
+	markLoaded(ctx context.Context, at time.Time) error
+	close() error
+}
+
+// Filter implements ports.ShortCodeFilter on top of a bitStore. It answers
+// "maybe" for every code until Load has completed, and remembers codes whose
+// Add failed so that it never reports them absent.
+type Filter struct {
+	store bitStore
+	ready atomic.Bool
+
+	mu      sync.Mutex
+	pending map[string]struct{}
+}
+
+func newFilter(store bitStore) *Filter {
+	return &Filter{
+		store:   store,
+		pending: make(map[string]struct{}),
+	}
+}
+
+// Load fills the filter from source, resuming from persisted state when the
+// store has any, and starts trusting negative answers once it is done.
+func (f *Filter) Load(ctx context.Context, source ShortCodeSource) error {
+	since, resumed, err := f.store.resumeFrom(ctx)
+	if err != nil {
+		return err
+	}
+	if resumed {
+		since = since.Add(-catchUpMargin)
+	} else {
+		since = time.Time{}
+	}
+
+	start := time.Now()
+	loaded := 0
+	after := ""
+	for {
+		codes, err := source.ShortCodes(ctx, since, after, loadBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, code := range codes {
+			if err := f.store.add(ctx, code); err != nil {
+				return err
+			}
+		}
+		loaded += len(codes)
+		if len(codes) < loadBatchSize {
+			break
+		}
+		after = codes[len(codes)-1]
+	}
+
+	if !resumed {
+		if err := f.store.markLoaded(ctx, start); err != nil {
+			return err
+		}
+	}
+
+	f.ready.Store(true)
+	log.Printf("Short code filter loaded %d codes in %v (resumed: %t)", loaded, time.Since(start), resumed)
+	return nil
+}
+
+func (f *Filter) Add(ctx context.Context, shortCode string) error {
+	if err := f.store.add(ctx, shortCode); err != nil {
+		f.mu.Lock()
+		f.pending[shortCode] = struct{}{}
+		f.mu.Unlock()
+		return err
+	}
+
+	f.retryPending(ctx)
+	return nil
+}
+
+func (f *Filter) MayContain(ctx context.Context, shortCode string) (bool, error) {
+	if !f.ready.Load() {
+		return true, nil
+	}
+
+	f.mu.Lock()
+	_, pending := f.pending[shortCode]
+	f.mu.Unlock()
+	if pending {
+		return true, nil
+	}
+
+	return f.store.test(ctx, shortCode)
+}
+
+// Close persists the filter where the store supports it.
+func (f *Filter) Close() error {
+	return f.store.close()
+}
+
+func (f *Filter) retryPending(ctx context.Context) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for code := range f.pending {
+		if err := f.store.add(ctx, code); err != nil {
+			return
+		}
+		delete(f.pending, code)
+	}
+}
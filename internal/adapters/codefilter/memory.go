@@ -0,0 +1,125 @@
+package codefilter
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/pkg/bloom"
+)
+
+// memoryStore keeps the bits in process memory and, when given a path,
+// snapshots them to a file on Close. It only sees codes issued by this
+// process, so it is suitable for single-instance deployments only.
+type memoryStore struct {
+	filter *bloom.Filter
+	path   string
+
+	snapshotAt time.Time
+	restored   bool
+}
+
+// NewMemoryFilter creates a filter sized for expectedCodes at the given
+// false positive rate. If path names a snapshot written with the same
+// sizing it is restored, so Load only has to catch up on newer codes.
+func NewMemoryFilter(expectedCodes uint64, falsePositiveRate float64, path string) *Filter {
+	store := &memoryStore{
+		filter: bloom.New(expectedCodes, falsePositiveRate),
+		path:   path,
+	}
+
+	if path != "" {
+		if err := store.restore(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Ignoring short code filter snapshot %s: %v", path, err)
+		}
+	}
+
+	return newFilter(store)
+}
+
+func (s *memoryStore) add(ctx context.Context, shortCode string) error {
+	s.filter.Add(shortCode)
+	return nil
+}
+
+func (s *memoryStore) test(ctx context.Context, shortCode string) (bool, error) {
+	return s.filter.Test(shortCode), nil
+}
+
+func (s *memoryStore) resumeFrom(ctx context.Context) (time.Time, bool, error) {
+	return s.snapshotAt, s.restored, nil
+}
+
+func (s *memoryStore) markLoaded(ctx context.Context, at time.Time) error {
+	return nil
+}
+
+// close writes the snapshot atomically: a crash mid-write leaves the previous
+// snapshot in place.
+func (s *memoryStore) close() error {
+	if s.path == "" {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if err := binary.Write(w, binary.BigEndian, time.Now().UnixNano()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := s.filter.WriteTo(w); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func (s *memoryStore) restore() error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	var snapshotAt int64
+	if err := binary.Read(r, binary.BigEndian, &snapshotAt); err != nil {
+		return fmt.Errorf("%w: %v", bloom.ErrInvalidFormat, err)
+	}
+	filter, err := bloom.ReadFrom(r)
+	if err != nil {
+		return err
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		return bloom.ErrInvalidFormat
+	}
+
+	if filter.M() != s.filter.M() || filter.K() != s.filter.K() {
+		return fmt.Errorf("snapshot was sized for m=%d k=%d, want m=%d k=%d", filter.M(), filter.K(), s.filter.M(), s.filter.K())
+	}
+
+	s.filter = filter
+	s.snapshotAt = time.Unix(0, snapshotAt)
+	s.restored = true
+	return nil
+}
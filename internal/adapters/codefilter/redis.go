@@ -0,0 +1,91 @@
+package codefilter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/pkg/bloom"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MaxRedisBits is the largest filter a Redis string can hold.
+const MaxRedisBits = 1 << 32
+
+// redisStore keeps the bits in a Redis bitmap shared by every instance, so a
+// code issued anywhere is visible everywhere and the filter survives
+// restarts. The key embeds the filter's sizing, so changing it starts a new
+// filter instead of misreading the old one.
+type redisStore struct {
+	client    *redis.Client
+	m, k      uint64
+	bitsKey   string
+	loadedKey string
+}
+
+// NewRedisFilter creates a filter sized for expectedCodes at the given false
+// positive rate, stored in Redis.
+func NewRedisFilter(client *redis.Client, expectedCodes uint64, falsePositiveRate float64) *Filter {
+	m, k := bloom.EstimateParameters(expectedCodes, falsePositiveRate)
+	m = min(m, MaxRedisBits)
+	bitsKey := fmt.Sprintf("bloom:shortcodes:%d:%d", m, k)
+
+	return newFilter(&redisStore{
+		client:    client,
+		m:         m,
+		k:         k,
+		bitsKey:   bitsKey,
+		loadedKey: bitsKey + ":loaded",
+	})
+}
+
+// add and test pipeline their k bit operations into a single round trip.
+func (s *redisStore) add(ctx context.Context, shortCode string) error {
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, loc := range bloom.Locations(shortCode, s.m, s.k) {
+			pipe.SetBit(ctx, s.bitsKey, int64(loc), 1)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *redisStore) test(ctx context.Context, shortCode string) (bool, error) {
+	locs := bloom.Locations(shortCode, s.m, s.k)
+	cmds := make([]*redis.IntCmd, len(locs))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, loc := range locs {
+			cmds[i] = pipe.GetBit(ctx, s.bitsKey, int64(loc))
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, cmd := range cmds {
+		if cmd.Val() == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// resumeFrom trusts a bitmap some instance has fully loaded; every instance
+// adds the codes it issues, so there is nothing to catch up on.
+func (s *redisStore) resumeFrom(ctx context.Context) (time.Time, bool, error) {
+	n, err := s.client.Exists(ctx, s.loadedKey).Result()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.Now(), n > 0, nil
+}
+
+func (s *redisStore) markLoaded(ctx context.Context, at time.Time) error {
+	return s.client.Set(ctx, s.loadedKey, at.UTC().Format(time.RFC3339), 0).Err()
+}
+
+func (s *redisStore) close() error {
+	return nil
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+type contextKey int
+
+const (
+	apiKeyContextKey contextKey = iota
+	clientIPContextKey
+)
+
+// AuthMiddleware requires a valid "Authorization: Bearer <key>" header and
+// stores the authenticated key on the request context.
+type AuthMiddleware struct {
+	apiKeys ports.APIKeyService
+}
+
+func NewAuthMiddleware(apiKeys ports.APIKeyService) *AuthMiddleware {
+	return &AuthMiddleware{
+		apiKeys: apiKeys,
+	}
+}
+
+func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret, ok := bearerToken(r)
+		if !ok {
+			m.unauthorized(w, "Missing API key")
+			return
+		}
+
+		key, err := m.apiKeys.Authenticate(r.Context(), secret)
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidAPIKey) {
+				m.unauthorized(w, "Invalid API key")
+				return
+			}
+			log.Printf("Failed to authenticate API key: %v", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(JSONResponse{Success: false, Error: "Internal server error"})
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey, key)))
+	})
+}
+
+func (m *AuthMiddleware) unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(JSONResponse{Success: false, Error: message})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// ownerID returns the owner of the API key that authenticated the request,
+// or the empty string for unauthenticated requests.
+func ownerID(r *http.Request) string {
+	if key, ok := r.Context().Value(apiKeyContextKey).(*domain.APIKey); ok {
+		return key.OwnerID
+	}
+	return ""
+}
@@ -0,0 +1,199 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Headers a trusted proxy may record the client address in.
+const (
+	ClientIPHeaderXForwardedFor = "X-Forwarded-For"
+	ClientIPHeaderForwarded     = "Forwarded"
+	ClientIPHeaderXRealIP       = "X-Real-IP"
+)
+
+// ClientIPResolver determines the address of the client behind any trusted
+// reverse proxies. Only the header the proxies write is read: a proxy
+// passes the others through as the client sent them. The header is walked
+// from the right, i.e. from the hop closest to us, and only entries
+// appended by a trusted proxy are believed; everything to the left of the
+// first untrusted hop is ignored because the client could have written it.
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+	header  string
+}
+
+// NewClientIPResolver parses the trusted proxy ranges. Entries may be CIDRs
+// or single addresses. header names the header the proxies record the
+// client address in; empty means X-Forwarded-For.
+func NewClientIPResolver(trustedProxies []string, header string) (*ClientIPResolver, error) {
+	r := &ClientIPResolver{}
+	if header == "" {
+		header = ClientIPHeaderXForwardedFor
+	}
+	for _, supported := range []string{ClientIPHeaderXForwardedFor, ClientIPHeaderForwarded, ClientIPHeaderXRealIP} {
+		if strings.EqualFold(header, supported) {
+			r.header = supported
+		}
+	}
+	if r.header == "" {
+		return nil, fmt.Errorf("unsupported client IP header %q", header)
+	}
+
+	for _, entry := range trustedProxies {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			r.trusted = append(r.trusted, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+		}
+		r.trusted = append(r.trusted, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return r, nil
+}
+
+// Middleware resolves the client IP once and stores it on the request
+// context, so rate limiting, analytics and logging all see the same value.
+func (c *ClientIPResolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := c.Resolve(r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPContextKey, ip)))
+	})
+}
+
+func (c *ClientIPResolver) Resolve(r *http.Request) string {
+	remote, ok := parseHostAddr(r.RemoteAddr)
+	if !ok {
+		return remoteHost(r)
+	}
+	if !c.isTrusted(remote) {
+		return remote.String()
+	}
+
+	hops := c.forwardedFor(r)
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseHostAddr(hops[i])
+		if !ok {
+			// An obfuscated or garbled entry: the nearest address we could
+			// verify is the best we can do.
+			break
+		}
+		client = hop
+		if !c.isTrusted(hop) {
+			break
+		}
+	}
+	return client.String()
+}
+
+func (c *ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range c.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the client address resolved by ClientIPResolver, falling
+// back to the connection's remote address when the middleware is not in use.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey).(string); ok {
+		return ip
+	}
+	return remoteHost(r)
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor lists the client addresses recorded by proxies in the
+// resolver's header, oldest first.
+func (c *ClientIPResolver) forwardedFor(r *http.Request) []string {
+	var hops []string
+	switch c.header {
+	case ClientIPHeaderForwarded:
+		for _, value := range r.Header.Values(ClientIPHeaderForwarded) {
+			for _, element := range splitQuoted(value, ',') {
+				hops = append(hops, forwardedElementFor(element))
+			}
+		}
+	case ClientIPHeaderXRealIP:
+		if value := r.Header.Get(ClientIPHeaderXRealIP); value != "" {
+			hops = append(hops, value)
+		}
+	default:
+		for _, value := range r.Header.Values(ClientIPHeaderXForwardedFor) {
+			for _, hop := range strings.Split(value, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+	}
+	return hops
+}
+
+// forwardedElementFor extracts the for= parameter of one Forwarded element,
+// returning the empty string when it has none.
+func forwardedElementFor(element string) string {
+	for _, pair := range splitQuoted(element, ';') {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if found && strings.EqualFold(strings.TrimSpace(name), "for") {
+			return strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return ""
+}
+
+// splitQuoted splits s on sep, ignoring separators inside double quotes.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case sep:
+			if !inQuotes {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseHostAddr parses an address that may carry a port and, for IPv6,
+// brackets: "192.0.2.1", "192.0.2.1:80", "[2001:db8::1]:80", "2001:db8::1".
+func parseHostAddr(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return netip.Addr{}, false
+	}
+
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
@@ -0,0 +1,25 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+)
+
+// ListDomains returns the branded domains the caller may create links on.
+func (h *Handlers) ListDomains(w http.ResponseWriter, r *http.Request) {
+	domains := []*domain.Domain{}
+	if h.domains != nil {
+		var err error
+		domains, err = h.domains.List(r.Context(), ownerID(r))
+		if err != nil {
+			h.respondError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+	}
+
+	h.respondJSON(w, http.StatusOK, JSONResponse{
+		Success: true,
+		Data:    domains,
+	})
+}
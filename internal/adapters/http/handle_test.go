@@ -6,42 +6,123 @@ import (
 	"encoding/json"
 	nethttp "net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mikiasyonas/url-shortener/internal/adapters/http"
 	"github.com/mikiasyonas/url-shortener/internal/core/domain"
 
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockURLService struct {
 	mock.Mock
 }
 
-func (m *MockURLService) ShortenURL(ctx context.Context, originalURL string) (*domain.URL, error) {
-	args := m.Called(ctx, originalURL)
+func (m *MockURLService) ShortenURL(ctx context.Context, originalURL string, opts domain.ShortenOptions) (*domain.URL, error) {
+	args := m.Called(ctx, originalURL, opts)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.URL), args.Error(1)
 }
 
-func (m *MockURLService) Redirect(ctx context.Context, shortCode string) (string, error) {
-	args := m.Called(ctx, shortCode)
+func (m *MockURLService) Redirect(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	args := m.Called(ctx, host, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockURLService) LookupURL(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	args := m.Called(ctx, host, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockURLService) GetURL(ctx context.Context, ownerID, host, shortCode string) (*domain.URL, error) {
+	args := m.Called(ctx, ownerID, host, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockURLService) UpdateURL(ctx context.Context, ownerID, host, shortCode string, update domain.URLUpdate) (*domain.URL, error) {
+	args := m.Called(ctx, ownerID, host, shortCode, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockURLService) DeleteURL(ctx context.Context, ownerID, host, shortCode string) error {
+	args := m.Called(ctx, ownerID, host, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockURLService) ListURLs(ctx context.Context, filter domain.ListFilter) (*domain.URLPage, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URLPage), args.Error(1)
+}
+
+type MockClickRecorder struct {
+	mock.Mock
+}
+
+func (m *MockClickRecorder) Record(click *domain.Click, clientIP string) {
+	m.Called(click, clientIP)
+}
+
+type MockDomainService struct {
+	mock.Mock
+}
+
+func (m *MockDomainService) Resolve(host string) string {
+	return m.Called(host).String(0)
+}
+
+func (m *MockDomainService) Authorize(ctx context.Context, ownerID, host string) (string, error) {
+	args := m.Called(ctx, ownerID, host)
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockDomainService) Register(ctx context.Context, host, ownerID string) (*domain.Domain, error) {
+	args := m.Called(ctx, host, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Domain), args.Error(1)
+}
+
+func (m *MockDomainService) List(ctx context.Context, ownerID string) ([]*domain.Domain, error) {
+	args := m.Called(ctx, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Domain), args.Error(1)
+}
+
 func TestHandlers_ShortenURL_Success(t *testing.T) {
 	mockService := new(MockURLService)
-	handlers := http.NewHandlers(mockService, "http://localhost:8080")
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
 
 	expectedURL := &domain.URL{
 		OriginalURL: "https://example.com",
 		ShortCode:   "abc123",
 	}
 
-	mockService.On("ShortenURL", mock.Anything, "https://example.com").Return(expectedURL, nil)
+	mockService.On("ShortenURL", mock.Anything, "https://example.com", domain.ShortenOptions{}).Return(expectedURL, nil)
 
 	reqBody := map[string]string{"url": "https://example.com"}
 	body, _ := json.Marshal(reqBody)
@@ -62,15 +143,16 @@ func TestHandlers_ShortenURL_Success(t *testing.T) {
 	assert.True(t, response.Success)
 	assert.Equal(t, "https://example.com", dataMap["original_url"])
 	assert.Equal(t, "abc123", dataMap["short_code"])
+	assert.Equal(t, "http://localhost:8080/abc123", dataMap["short_url"])
 
 	mockService.AssertExpectations(t)
 }
 
 func TestHandlers_ShortenURL_InvalidURL(t *testing.T) {
 	mockService := new(MockURLService)
-	handlers := http.NewHandlers(mockService, "http://localhost:8080")
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
 
-	mockService.On("ShortenURL", mock.Anything, "invalid-url").Return((*domain.URL)(nil), domain.ErrInvalidURL)
+	mockService.On("ShortenURL", mock.Anything, "invalid-url", domain.ShortenOptions{}).Return((*domain.URL)(nil), domain.ErrInvalidURL)
 
 	reqBody := map[string]string{"url": "invalid-url"}
 	body, _ := json.Marshal(reqBody)
@@ -92,30 +174,252 @@ func TestHandlers_ShortenURL_InvalidURL(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestHandlers_ShortenURL_AliasTaken(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
+
+	opts := domain.ShortenOptions{Alias: "spring-sale"}
+	mockService.On("ShortenURL", mock.Anything, "https://example.com", opts).Return((*domain.URL)(nil), domain.ErrShortCodeTaken)
+
+	reqBody := map[string]string{"url": "https://example.com", "alias": "spring-sale"}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/api/shorten", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handlers.ShortenURL(rr, req)
+
+	assert.Equal(t, nethttp.StatusConflict, rr.Code)
+
+	var response http.JSONResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	assert.False(t, response.Success)
+	assert.Equal(t, "Alias is already taken", response.Error)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlers_ShortenURL_Domain(t *testing.T) {
+	mockService := new(MockURLService)
+	mockDomains := new(MockDomainService)
+	handlers := http.NewHandlers(mockService, nil, mockDomains, "https://sho.rt", 0)
+
+	mockDomains.On("Authorize", mock.Anything, "", "Go.Example.com").Return("go.example.com", nil)
+	opts := domain.ShortenOptions{Domain: "go.example.com"}
+	mockService.On("ShortenURL", mock.Anything, "https://example.com", opts).Return(&domain.URL{
+		OriginalURL: "https://example.com",
+		ShortCode:   "abc123",
+		Domain:      "go.example.com",
+	}, nil)
+
+	body, _ := json.Marshal(map[string]string{"url": "https://example.com", "domain": "Go.Example.com"})
+	req := httptest.NewRequest("POST", "/api/shorten", bytes.NewReader(body))
+
+	rr := httptest.NewRecorder()
+	handlers.ShortenURL(rr, req)
+
+	assert.Equal(t, nethttp.StatusCreated, rr.Code)
+
+	var response http.JSONResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	dataMap := response.Data.(map[string]interface{})
+	assert.Equal(t, "https://go.example.com/abc123", dataMap["short_url"])
+	assert.Equal(t, "go.example.com", dataMap["domain"])
+
+	mockService.AssertExpectations(t)
+	mockDomains.AssertExpectations(t)
+}
+
+func TestHandlers_ShortenURL_DomainNotAllowed(t *testing.T) {
+	mockService := new(MockURLService)
+	mockDomains := new(MockDomainService)
+	handlers := http.NewHandlers(mockService, nil, mockDomains, "https://sho.rt", 0)
+
+	mockDomains.On("Authorize", mock.Anything, "", "theirs.example.com").Return("", domain.ErrDomainNotAllowed)
+
+	body, _ := json.Marshal(map[string]string{"url": "https://example.com", "domain": "theirs.example.com"})
+	req := httptest.NewRequest("POST", "/api/shorten", bytes.NewReader(body))
+
+	rr := httptest.NewRecorder()
+	handlers.ShortenURL(rr, req)
+
+	assert.Equal(t, nethttp.StatusForbidden, rr.Code)
+	mockService.AssertNotCalled(t, "ShortenURL", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandlers_ShortenURL_DomainPending(t *testing.T) {
+	mockService := new(MockURLService)
+	mockDomains := new(MockDomainService)
+	handlers := http.NewHandlers(mockService, nil, mockDomains, "https://sho.rt", 0)
+
+	mockDomains.On("Authorize", mock.Anything, "", "new.example.com").Return("", domain.ErrDomainPending)
+
+	body, _ := json.Marshal(map[string]string{"url": "https://example.com", "domain": "new.example.com"})
+	req := httptest.NewRequest("POST", "/api/shorten", bytes.NewReader(body))
+
+	rr := httptest.NewRecorder()
+	handlers.ShortenURL(rr, req)
+
+	assert.Equal(t, nethttp.StatusConflict, rr.Code)
+	mockService.AssertNotCalled(t, "ShortenURL", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandlers_ShortenURL_DomainWithoutDomains(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "https://sho.rt", 0)
+
+	body, _ := json.Marshal(map[string]string{"url": "https://example.com", "domain": "go.example.com"})
+	req := httptest.NewRequest("POST", "/api/shorten", bytes.NewReader(body))
+
+	rr := httptest.NewRecorder()
+	handlers.ShortenURL(rr, req)
+
+	assert.Equal(t, nethttp.StatusForbidden, rr.Code)
+}
+
+func TestHandlers_ShortenURL_RedirectType(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "https://sho.rt", 0)
+
+	mockService.On("ShortenURL", mock.Anything, "https://example.com", mock.MatchedBy(func(opts domain.ShortenOptions) bool {
+		return opts.RedirectType == domain.RedirectMovedPermanently
+	})).Return(&domain.URL{OriginalURL: "https://example.com", ShortCode: "abc123", RedirectType: domain.RedirectMovedPermanently}, nil)
+	mockService.On("ShortenURL", mock.Anything, "https://example.com", mock.Anything).Return(nil, domain.ErrInvalidRedirectType)
+
+	body, _ := json.Marshal(map[string]interface{}{"url": "https://example.com", "redirect_type": 301})
+	rr := httptest.NewRecorder()
+	handlers.ShortenURL(rr, httptest.NewRequest("POST", "/api/shorten", bytes.NewReader(body)))
+
+	assert.Equal(t, nethttp.StatusCreated, rr.Code)
+	var response struct {
+		Data http.ShortenResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, 301, response.Data.RedirectType)
+
+	body, _ = json.Marshal(map[string]interface{}{"url": "https://example.com", "redirect_type": 303})
+	rr = httptest.NewRecorder()
+	handlers.ShortenURL(rr, httptest.NewRequest("POST", "/api/shorten", bytes.NewReader(body)))
+
+	assert.Equal(t, nethttp.StatusBadRequest, rr.Code)
+}
+
+func TestHandlers_ShortenURL_Rejected(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "https://sho.rt", 0)
+
+	mockService.On("ShortenURL", mock.Anything, "https://evil.example", mock.Anything).
+		Return(nil, domain.RejectURL("destination domain is blocklisted"))
+
+	body, _ := json.Marshal(map[string]string{"url": "https://evil.example"})
+	rr := httptest.NewRecorder()
+	handlers.ShortenURL(rr, httptest.NewRequest("POST", "/api/shorten", bytes.NewReader(body)))
+
+	assert.Equal(t, nethttp.StatusUnprocessableEntity, rr.Code)
+	var response http.JSONResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "URL rejected: destination domain is blocklisted", response.Error)
+}
+
+func TestHandlers_ShortenURL_TooLong(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "https://sho.rt", 0)
+
+	mockService.On("ShortenURL", mock.Anything, "https://example.com/long", mock.Anything).Return(nil, domain.ErrURLTooLong)
+
+	body, _ := json.Marshal(map[string]string{"url": "https://example.com/long"})
+	rr := httptest.NewRecorder()
+	handlers.ShortenURL(rr, httptest.NewRequest("POST", "/api/shorten", bytes.NewReader(body)))
+
+	assert.Equal(t, nethttp.StatusBadRequest, rr.Code)
+	var response http.JSONResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, "URL is too long", response.Error)
+}
+
+func TestHandlers_Redirect_ResolvesHost(t *testing.T) {
+	mockService := new(MockURLService)
+	mockDomains := new(MockDomainService)
+	mockRecorder := new(MockClickRecorder)
+	handlers := http.NewHandlers(mockService, mockRecorder, mockDomains, "https://sho.rt", 0)
+
+	mockDomains.On("Resolve", "go.example.com:443").Return("go.example.com")
+	mockService.On("Redirect", mock.Anything, "go.example.com", "abc123").Return(&domain.URL{OriginalURL: "https://example.org"}, nil)
+	mockRecorder.On("Record", mock.MatchedBy(func(click *domain.Click) bool {
+		return click.Domain == "go.example.com" && click.ShortCode == "abc123"
+	}), mock.Anything).Return()
+
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	req.Host = "go.example.com:443"
+	req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
+
+	rr := httptest.NewRecorder()
+	handlers.Redirect(rr, req)
+
+	assert.Equal(t, nethttp.StatusFound, rr.Code)
+	assert.Equal(t, "https://example.org", rr.Header().Get("Location"))
+
+	mockService.AssertExpectations(t)
+	mockRecorder.AssertExpectations(t)
+}
+
+func TestHandlers_GetLink_Domain(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "https://sho.rt", 0)
+
+	mockService.On("GetURL", mock.Anything, "", "go.example.com", "abc123").Return(&domain.URL{
+		OriginalURL: "https://example.org",
+		ShortCode:   "abc123",
+		Domain:      "go.example.com",
+	}, nil)
+
+	req := httptest.NewRequest("GET", "/api/links/abc123?domain=go.example.com", nil)
+	req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
+
+	rr := httptest.NewRecorder()
+	handlers.GetLink(rr, req)
+
+	assert.Equal(t, nethttp.StatusOK, rr.Code)
+
+	var response http.JSONResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, "https://go.example.com/abc123", response.Data.(map[string]interface{})["short_url"])
+
+	mockService.AssertExpectations(t)
+}
+
 func TestHandlers_Redirect_Success(t *testing.T) {
 	mockService := new(MockURLService)
-	handlers := http.NewHandlers(mockService, "http://localhost:8080")
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
 
-	mockService.On("Redirect", mock.Anything, "abc123").Return("https://example.com", nil)
+	mockService.On("Redirect", mock.Anything, "", "abc123").Return(&domain.URL{
+		OriginalURL:  "https://example.com",
+		RedirectType: domain.RedirectMovedPermanently,
+	}, nil)
 
 	req := httptest.NewRequest("GET", "/abc123", nil)
+	req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
 
 	rr := httptest.NewRecorder()
 	handlers.Redirect(rr, req)
 
 	assert.Equal(t, nethttp.StatusMovedPermanently, rr.Code)
 	assert.Equal(t, "https://example.com", rr.Header().Get("Location"))
+	assert.Contains(t, rr.Header().Get("Cache-Control"), "no-store")
+	assert.Equal(t, "Thu, 01 Jan 1970 00:00:00 GMT", rr.Header().Get("Expires"))
 
 	mockService.AssertExpectations(t)
 }
 
 func TestHandlers_Redirect_NotFound(t *testing.T) {
 	mockService := new(MockURLService)
-	handlers := http.NewHandlers(mockService, "http://localhost:8080")
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
 
-	mockService.On("Redirect", mock.Anything, "notfound").Return("", domain.ErrURLNotFound)
+	mockService.On("Redirect", mock.Anything, "", "notfound").Return(nil, domain.ErrURLNotFound)
 
 	req := httptest.NewRequest("GET", "/notfound", nil)
+	req = mux.SetURLVars(req, map[string]string{"code": "notfound"})
 
 	rr := httptest.NewRecorder()
 	handlers.Redirect(rr, req)
@@ -125,9 +429,173 @@ func TestHandlers_Redirect_NotFound(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestHandlers_Redirect_DefaultsToUncachedFound(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", time.Hour)
+
+	mockService.On("Redirect", mock.Anything, "", "abc123").Return(&domain.URL{OriginalURL: "https://example.com"}, nil)
+
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
+
+	rr := httptest.NewRecorder()
+	handlers.Redirect(rr, req)
+
+	assert.Equal(t, nethttp.StatusFound, rr.Code)
+	assert.Contains(t, rr.Header().Get("Cache-Control"), "no-store")
+}
+
+func TestHandlers_Redirect_CachesPermanentRedirects(t *testing.T) {
+	for _, status := range []int{domain.RedirectMovedPermanently, domain.RedirectPermanent} {
+		mockService := new(MockURLService)
+		handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", time.Hour)
+
+		mockService.On("Redirect", mock.Anything, "", "abc123").Return(&domain.URL{
+			OriginalURL:  "https://example.com",
+			RedirectType: status,
+		}, nil)
+
+		req := httptest.NewRequest("GET", "/abc123", nil)
+		req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
+
+		rr := httptest.NewRecorder()
+		handlers.Redirect(rr, req)
+
+		assert.Equal(t, status, rr.Code)
+		assert.Equal(t, "public, max-age=3600", rr.Header().Get("Cache-Control"))
+		expires, err := nethttp.ParseTime(rr.Header().Get("Expires"))
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), expires, 5*time.Second)
+	}
+}
+
+func TestHandlers_Redirect_CacheStopsAtExpiry(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", time.Hour)
+
+	expiresAt := time.Now().Add(10 * time.Minute)
+	mockService.On("Redirect", mock.Anything, "", "abc123").Return(&domain.URL{
+		OriginalURL:  "https://example.com",
+		RedirectType: domain.RedirectPermanent,
+		ExpiresAt:    &expiresAt,
+	}, nil)
+
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
+
+	rr := httptest.NewRecorder()
+	handlers.Redirect(rr, req)
+
+	assert.Equal(t, nethttp.StatusPermanentRedirect, rr.Code)
+	assert.Regexp(t, `^public, max-age=(599|600)$`, rr.Header().Get("Cache-Control"))
+}
+
+func TestHandlers_Redirect_Expired(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
+
+	mockService.On("Redirect", mock.Anything, "", "abc123").Return(nil, domain.ErrURLExpired)
+
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
+
+	rr := httptest.NewRecorder()
+	handlers.Redirect(rr, req)
+
+	assert.Equal(t, nethttp.StatusGone, rr.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlers_Redirect_RecordsClick(t *testing.T) {
+	mockService := new(MockURLService)
+	mockRecorder := new(MockClickRecorder)
+	handlers := http.NewHandlers(mockService, mockRecorder, nil, "http://localhost:8080", 0)
+
+	mockService.On("Redirect", mock.Anything, "", "abc123").Return(&domain.URL{OriginalURL: "https://example.com"}, nil)
+	mockRecorder.On("Record", mock.MatchedBy(func(click *domain.Click) bool {
+		return click.ShortCode == "abc123" &&
+			click.Referrer == "https://news.example" &&
+			click.UserAgent == "test-agent" &&
+			click.AcceptLanguage == "en-US"
+	}), "192.0.2.1").Return()
+
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
+	req.Header.Set("Referer", "https://news.example")
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("Accept-Language", "en-US")
+
+	rr := httptest.NewRecorder()
+	handlers.Redirect(rr, req)
+
+	assert.Equal(t, nethttp.StatusFound, rr.Code)
+
+	mockService.AssertExpectations(t)
+	mockRecorder.AssertExpectations(t)
+}
+
+type MockStatsService struct {
+	mock.Mock
+}
+
+func (m *MockStatsService) GetLinkStats(ctx context.Context, ownerID, host, shortCode string, query domain.StatsQuery) (*domain.LinkStats, error) {
+	args := m.Called(ctx, ownerID, host, shortCode, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.LinkStats), args.Error(1)
+}
+
+func TestStatsHandler_LinkStats(t *testing.T) {
+	mockStats := new(MockStatsService)
+	handler := http.NewStatsHandler(mockStats)
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+	query := domain.StatsQuery{From: from, To: to, Interval: domain.StatsIntervalHour, TopLimit: 5}
+
+	mockStats.On("GetLinkStats", mock.Anything, "", "", "abc123", query).Return(&domain.LinkStats{
+		ShortCode:   "abc123",
+		TotalClicks: 42,
+	}, nil)
+
+	req := httptest.NewRequest("GET", "/api/links/abc123/stats?from=2026-03-01T00:00:00Z&to=2026-03-08T00:00:00Z&interval=hour&top=5", nil)
+	req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
+
+	rr := httptest.NewRecorder()
+	handler.LinkStats(rr, req)
+
+	assert.Equal(t, nethttp.StatusOK, rr.Code)
+
+	var response http.JSONResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	dataMap, ok := response.Data.(map[string]interface{})
+	assert.True(t, ok, "Data should be a map")
+	assert.Equal(t, float64(42), dataMap["total_clicks"])
+
+	mockStats.AssertExpectations(t)
+}
+
+func TestStatsHandler_LinkStats_InvalidInterval(t *testing.T) {
+	mockStats := new(MockStatsService)
+	handler := http.NewStatsHandler(mockStats)
+
+	req := httptest.NewRequest("GET", "/api/links/abc123/stats?interval=month", nil)
+	req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
+
+	rr := httptest.NewRecorder()
+	handler.LinkStats(rr, req)
+
+	assert.Equal(t, nethttp.StatusBadRequest, rr.Code)
+
+	mockStats.AssertNotCalled(t, "GetLinkStats", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestHandlers_HealthCheck(t *testing.T) {
 	mockService := new(MockURLService)
-	handlers := http.NewHandlers(mockService, "http://localhost:8080")
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
 
 	req := httptest.NewRequest("GET", "/health", nil)
 
@@ -142,3 +610,692 @@ func TestHandlers_HealthCheck(t *testing.T) {
 	assert.True(t, response.Success)
 	assert.Equal(t, "URL Shortener Service is healthy", response.Data)
 }
+
+func TestHandlers_GetLink_NotFound(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
+
+	mockService.On("GetURL", mock.Anything, "", "", "abc123").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+
+	req := httptest.NewRequest("GET", "/api/links/abc123", nil)
+	req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
+
+	rr := httptest.NewRecorder()
+	handlers.GetLink(rr, req)
+
+	assert.Equal(t, nethttp.StatusNotFound, rr.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlers_UpdateLink_ClearExpiry(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
+
+	newTarget := "https://example.org"
+	update := domain.URLUpdate{OriginalURL: &newTarget, ClearExpiry: true}
+	updated := &domain.URL{OriginalURL: newTarget, ShortCode: "abc123"}
+
+	mockService.On("UpdateURL", mock.Anything, "", "", "abc123", update).Return(updated, nil)
+
+	body := []byte(`{"url": "https://example.org", "expires_at": null}`)
+	req := httptest.NewRequest("PATCH", "/api/links/abc123", bytes.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
+
+	rr := httptest.NewRecorder()
+	handlers.UpdateLink(rr, req)
+
+	assert.Equal(t, nethttp.StatusOK, rr.Code)
+
+	var response http.JSONResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	dataMap, ok := response.Data.(map[string]interface{})
+	assert.True(t, ok, "Data should be a map")
+	assert.Equal(t, newTarget, dataMap["original_url"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlers_DeleteLink(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
+
+	mockService.On("DeleteURL", mock.Anything, "", "", "abc123").Return(nil)
+
+	req := httptest.NewRequest("DELETE", "/api/links/abc123", nil)
+	req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
+
+	rr := httptest.NewRecorder()
+	handlers.DeleteLink(rr, req)
+
+	assert.Equal(t, nethttp.StatusNoContent, rr.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlers_ListLinks_Pagination(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
+
+	next := &domain.Cursor{CreatedAt: time.Now().UTC(), ID: "next-id"}
+	page := &domain.URLPage{
+		URLs:       []*domain.URL{{OriginalURL: "https://example.com", ShortCode: "abc123"}},
+		NextCursor: next,
+	}
+
+	expectedFilter := domain.ListFilter{Limit: 1, Status: domain.LinkStatusActive}
+	mockService.On("ListURLs", mock.Anything, expectedFilter).Return(page, nil)
+
+	req := httptest.NewRequest("GET", "/api/links?limit=1&status=active", nil)
+
+	rr := httptest.NewRecorder()
+	handlers.ListLinks(rr, req)
+
+	assert.Equal(t, nethttp.StatusOK, rr.Code)
+
+	var response http.JSONResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	dataMap, ok := response.Data.(map[string]interface{})
+	assert.True(t, ok, "Data should be a map")
+	assert.Len(t, dataMap["links"], 1)
+	assert.Equal(t, next.Encode(), dataMap["next_cursor"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlers_ListLinks_Broken(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
+
+	checkedAt := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	page := &domain.URLPage{URLs: []*domain.URL{{
+		OriginalURL:     "https://example.com/gone",
+		ShortCode:       "abc123",
+		LastCheckStatus: nethttp.StatusNotFound,
+		LastCheckedAt:   &checkedAt,
+	}}}
+	mockService.On("ListURLs", mock.Anything, domain.ListFilter{Status: domain.LinkStatusBroken}).Return(page, nil)
+
+	req := httptest.NewRequest("GET", "/api/links?status=broken", nil)
+
+	rr := httptest.NewRecorder()
+	handlers.ListLinks(rr, req)
+
+	assert.Equal(t, nethttp.StatusOK, rr.Code)
+
+	var response struct {
+		Data http.LinkListResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Len(t, response.Data.Links, 1)
+	assert.Equal(t, &http.LinkCheckResponse{Status: 404, CheckedAt: checkedAt, Broken: true}, response.Data.Links[0].LastCheck)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlers_GetLink_NotChecked(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
+
+	mockService.On("GetURL", mock.Anything, "", "", "abc123").Return(&domain.URL{OriginalURL: "https://example.com", ShortCode: "abc123"}, nil)
+
+	req := httptest.NewRequest("GET", "/api/links/abc123", nil)
+	req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
+
+	rr := httptest.NewRecorder()
+	handlers.GetLink(rr, req)
+
+	assert.Equal(t, nethttp.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "last_check")
+}
+
+func TestHandlers_ListLinks_InvalidCursor(t *testing.T) {
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
+
+	req := httptest.NewRequest("GET", "/api/links?cursor=not-a-cursor", nil)
+
+	rr := httptest.NewRecorder()
+	handlers.ListLinks(rr, req)
+
+	assert.Equal(t, nethttp.StatusBadRequest, rr.Code)
+
+	mockService.AssertNotCalled(t, "ListURLs", mock.Anything, mock.Anything)
+}
+
+type MockAPIKeyService struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyService) Create(ctx context.Context, ownerID, name string) (*domain.APIKey, string, error) {
+	args := m.Called(ctx, ownerID, name)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).(*domain.APIKey), args.String(1), args.Error(2)
+}
+
+func (m *MockAPIKeyService) Authenticate(ctx context.Context, secret string) (*domain.APIKey, error) {
+	args := m.Called(ctx, secret)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) Revoke(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyService) List(ctx context.Context, ownerID string) ([]*domain.APIKey, error) {
+	args := m.Called(ctx, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.APIKey), args.Error(1)
+}
+
+func TestAuthMiddleware_MissingKey(t *testing.T) {
+	mockKeys := new(MockAPIKeyService)
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
+	handler := http.NewAuthMiddleware(mockKeys).Authenticate(nethttp.HandlerFunc(handlers.ListLinks))
+
+	req := httptest.NewRequest("GET", "/api/links", nil)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, nethttp.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "Bearer")
+	mockKeys.AssertNotCalled(t, "Authenticate", mock.Anything, mock.Anything)
+	mockService.AssertNotCalled(t, "ListURLs", mock.Anything, mock.Anything)
+}
+
+func TestAuthMiddleware_InvalidKey(t *testing.T) {
+	mockKeys := new(MockAPIKeyService)
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
+	handler := http.NewAuthMiddleware(mockKeys).Authenticate(nethttp.HandlerFunc(handlers.ListLinks))
+
+	mockKeys.On("Authenticate", mock.Anything, "usk_revoked").Return(nil, domain.ErrInvalidAPIKey)
+
+	req := httptest.NewRequest("GET", "/api/links", nil)
+	req.Header.Set("Authorization", "Bearer usk_revoked")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, nethttp.StatusUnauthorized, rr.Code)
+	mockService.AssertNotCalled(t, "ListURLs", mock.Anything, mock.Anything)
+}
+
+func TestAuthMiddleware_ScopesRequestToOwner(t *testing.T) {
+	mockKeys := new(MockAPIKeyService)
+	mockService := new(MockURLService)
+	handlers := http.NewHandlers(mockService, nil, nil, "http://localhost:8080", 0)
+	handler := http.NewAuthMiddleware(mockKeys).Authenticate(nethttp.HandlerFunc(handlers.DeleteLink))
+
+	mockKeys.On("Authenticate", mock.Anything, "usk_valid").Return(&domain.APIKey{ID: "key-1", OwnerID: "owner-1"}, nil)
+	mockService.On("DeleteURL", mock.Anything, "owner-1", "", "abc123").Return(nil)
+
+	req := httptest.NewRequest("DELETE", "/api/links/abc123", nil)
+	req.Header.Set("Authorization", "bearer usk_valid")
+	req = mux.SetURLVars(req, map[string]string{"code": "abc123"})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, nethttp.StatusNoContent, rr.Code)
+	mockKeys.AssertExpectations(t)
+	mockService.AssertExpectations(t)
+}
+
+type MockRateLimiter struct {
+	mock.Mock
+}
+
+func (m *MockRateLimiter) Allow(ctx context.Context, key string) (domain.RateLimitResult, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(domain.RateLimitResult), args.Error(1)
+}
+
+func TestRateLimiter_SetsHeaders(t *testing.T) {
+	mockLimiter := new(MockRateLimiter)
+	next := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.WriteHeader(nethttp.StatusOK)
+	})
+	handler := http.NewRateLimiter(mockLimiter, 100).Limit(next)
+
+	mockLimiter.On("Allow", mock.Anything, "192.0.2.1").Return(domain.RateLimitResult{
+		Allowed:    true,
+		Limit:      100,
+		Remaining:  99,
+		ResetAfter: 10 * time.Millisecond,
+	}, nil)
+
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, nethttp.StatusOK, rr.Code)
+	assert.Equal(t, "100", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "99", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, rr.Header().Get("Retry-After"))
+}
+
+func TestRateLimiter_RejectsWithRetryAfter(t *testing.T) {
+	mockLimiter := new(MockRateLimiter)
+	next := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		t.Fatal("limited request must not reach the handler")
+	})
+	handler := http.NewRateLimiter(mockLimiter, 100).Limit(next)
+
+	mockLimiter.On("Allow", mock.Anything, "192.0.2.1").Return(domain.RateLimitResult{
+		Limit:      100,
+		RetryAfter: 1500 * time.Millisecond,
+		ResetAfter: 100 * time.Second,
+	}, nil)
+
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, nethttp.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "100", rr.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+}
+
+func TestRateLimiter_FailsOpen(t *testing.T) {
+	mockLimiter := new(MockRateLimiter)
+	next := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.WriteHeader(nethttp.StatusOK)
+	})
+	handler := http.NewRateLimiter(mockLimiter, 100).Limit(next)
+
+	mockLimiter.On("Allow", mock.Anything, mock.Anything).Return(domain.RateLimitResult{}, assert.AnError)
+
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, nethttp.StatusOK, rr.Code)
+	assert.Equal(t, "100", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "100", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Reset"))
+}
+
+func TestClientIPResolver_Resolve(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "2001:db8:cafe::/48", "192.0.2.1"}
+
+	tests := []struct {
+		name       string
+		header     string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{
+			name:       "untrusted peer ignores headers",
+			remoteAddr: "203.0.113.7:5000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"},
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "trusted peer without headers",
+			remoteAddr: "10.0.0.5:5000",
+			expected:   "10.0.0.5",
+		},
+		{
+			name:       "trusted peer ignores X-Real-IP unless configured",
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.2"},
+			expected:   "10.0.0.5",
+		},
+		{
+			name:       "trusted peer uses X-Real-IP when configured",
+			header:     http.ClientIPHeaderXRealIP,
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.2", "X-Forwarded-For": "1.2.3.4"},
+			expected:   "198.51.100.2",
+		},
+		{
+			name:       "Forwarded from the client is ignored behind an X-Forwarded-For proxy",
+			remoteAddr: "10.0.0.5:1234",
+			headers:    map[string]string{"Forwarded": "for=1.2.3.4", "X-Forwarded-For": "203.0.113.9"},
+			expected:   "203.0.113.9",
+		},
+		{
+			name:       "spoofed left-most X-Forwarded-For entry is skipped",
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.9, 10.0.0.7"},
+			expected:   "198.51.100.9",
+		},
+		{
+			name:       "all hops trusted resolves to the left-most",
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"X-Forwarded-For": "10.1.1.1, 10.0.0.7"},
+			expected:   "10.1.1.1",
+		},
+		{
+			name:       "garbage hop stops the walk",
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.9, not-an-ip, 10.0.0.7"},
+			expected:   "10.0.0.7",
+		},
+		{
+			name:       "single trusted address",
+			remoteAddr: "192.0.2.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.9"},
+			expected:   "198.51.100.9",
+		},
+		{
+			name:       "Forwarded handles IPv6 with port",
+			header:     http.ClientIPHeaderForwarded,
+			remoteAddr: "[2001:db8:cafe::1]:443",
+			headers: map[string]string{
+				"X-Forwarded-For": "198.51.100.1",
+				"Forwarded":       `for=1.2.3.4, for="[2001:db8:beef::17]:4711";proto=https, For="10.0.0.9";by=10.0.0.1`,
+			},
+			expected: "2001:db8:beef::17",
+		},
+		{
+			name:       "Forwarded obfuscated identifier stops the walk",
+			header:     http.ClientIPHeaderForwarded,
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"Forwarded": "for=198.51.100.9, for=_hidden, for=10.0.0.7"},
+			expected:   "10.0.0.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, err := http.NewClientIPResolver(trusted, tt.header)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest("GET", "/abc123", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			assert.Equal(t, tt.expected, resolver.Resolve(req))
+		})
+	}
+}
+
+func TestNewClientIPResolver_InvalidEntry(t *testing.T) {
+	_, err := http.NewClientIPResolver([]string{"10.0.0.0/8", "proxy.internal"}, "")
+	assert.Error(t, err)
+
+	_, err = http.NewClientIPResolver([]string{"10.0.0.0/8"}, "True-Client-IP")
+	assert.Error(t, err)
+}
+
+func TestClientIPResolver_RateLimiterUsesResolvedIP(t *testing.T) {
+	resolver, err := http.NewClientIPResolver([]string{"10.0.0.0/8"}, "")
+	assert.NoError(t, err)
+
+	mockLimiter := new(MockRateLimiter)
+	var seen string
+	next := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		seen = http.ClientIP(r)
+		w.WriteHeader(nethttp.StatusOK)
+	})
+	handler := resolver.Middleware(http.NewRateLimiter(mockLimiter, 100).Limit(next))
+
+	mockLimiter.On("Allow", mock.Anything, "198.51.100.9").Return(domain.RateLimitResult{Allowed: true, Limit: 100}, nil)
+
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	req.RemoteAddr = "10.0.0.5:5000"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 198.51.100.9")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, nethttp.StatusOK, rr.Code)
+	assert.Equal(t, "198.51.100.9", seen)
+	mockLimiter.AssertExpectations(t)
+}
+
+// fakeIdempotencyStore keeps Idempotency-Keys in memory.
+type fakeIdempotencyStore struct {
+	keys map[string]*domain.IdempotencyKey
+	err  error
+	// storeCtxErr is the error of the context the last outcome was stored with.
+	storeCtxErr error
+}
+
+func newFakeIdempotencyStore() *fakeIdempotencyStore {
+	return &fakeIdempotencyStore{keys: make(map[string]*domain.IdempotencyKey)}
+}
+
+func (s *fakeIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string) (*domain.IdempotencyKey, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if held, ok := s.keys[key]; ok {
+		return held, nil
+	}
+	s.keys[key] = &domain.IdempotencyKey{Key: key, Fingerprint: fingerprint}
+	return nil, nil
+}
+
+func (s *fakeIdempotencyStore) Complete(ctx context.Context, key *domain.IdempotencyKey) error {
+	if s.storeCtxErr = ctx.Err(); s.storeCtxErr != nil {
+		return s.storeCtxErr
+	}
+	s.keys[key.Key] = key
+	return nil
+}
+
+func (s *fakeIdempotencyStore) Release(ctx context.Context, key, fingerprint string) error {
+	if s.storeCtxErr = ctx.Err(); s.storeCtxErr != nil {
+		return s.storeCtxErr
+	}
+	delete(s.keys, key)
+	return nil
+}
+
+func idempotentRequest(method, key, body string) *nethttp.Request {
+	req := httptest.NewRequest(method, "/api/shorten", bytes.NewBufferString(body))
+	req.Header.Set("Idempotency-Key", key)
+	return req
+}
+
+func TestIdempotencyMiddleware_ReplaysResponse(t *testing.T) {
+	calls := 0
+	next := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(nethttp.StatusCreated)
+		w.Write([]byte(`{"short_code":"abc123"}`))
+	})
+	handler := http.NewIdempotencyMiddleware(newFakeIdempotencyStore()).Handle(next)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("POST", "key-1", `{"url":"https://example.com"}`))
+	assert.Equal(t, nethttp.StatusCreated, rr.Code)
+	assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("POST", "key-1", `{"url":"https://example.com"}`))
+	assert.Equal(t, nethttp.StatusCreated, rr.Code)
+	assert.Equal(t, "true", rr.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"short_code":"abc123"}`, rr.Body.String())
+	assert.Equal(t, 1, calls)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("POST", "key-2", `{"url":"https://example.com"}`))
+	assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyMiddleware_ScopesKeysToOwner(t *testing.T) {
+	calls := 0
+	next := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		calls++
+		w.WriteHeader(nethttp.StatusCreated)
+	})
+	mockAPIKeys := new(MockAPIKeyService)
+	mockAPIKeys.On("Authenticate", mock.Anything, "secret-a").Return(&domain.APIKey{OwnerID: "owner-a"}, nil)
+	mockAPIKeys.On("Authenticate", mock.Anything, "secret-b").Return(&domain.APIKey{OwnerID: "owner-b"}, nil)
+	idempotent := http.NewIdempotencyMiddleware(newFakeIdempotencyStore()).Handle(next)
+	handler := http.NewAuthMiddleware(mockAPIKeys).Authenticate(idempotent)
+
+	for _, secret := range []string{"secret-a", "secret-b"} {
+		req := idempotentRequest("POST", "key-1", `{"url":"https://example.com"}`)
+		req.Header.Set("Authorization", "Bearer "+secret)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyMiddleware_RejectsDifferentRequest(t *testing.T) {
+	next := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.WriteHeader(nethttp.StatusCreated)
+	})
+	handler := http.NewIdempotencyMiddleware(newFakeIdempotencyStore()).Handle(next)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("POST", "key-1", `{"url":"https://example.com"}`))
+	require.Equal(t, nethttp.StatusCreated, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("POST", "key-1", `{"url":"https://example.org"}`))
+	assert.Equal(t, nethttp.StatusUnprocessableEntity, rr.Code)
+}
+
+func TestIdempotencyMiddleware_InProgress(t *testing.T) {
+	store := newFakeIdempotencyStore()
+	var handler nethttp.Handler
+	var retry *httptest.ResponseRecorder
+	next := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if retry == nil {
+			retry = httptest.NewRecorder()
+			handler.ServeHTTP(retry, idempotentRequest("POST", "key-1", `{"url":"https://example.com"}`))
+		}
+		w.WriteHeader(nethttp.StatusCreated)
+	})
+	handler = http.NewIdempotencyMiddleware(store).Handle(next)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("POST", "key-1", `{"url":"https://example.com"}`))
+
+	assert.Equal(t, nethttp.StatusCreated, rr.Code)
+	assert.Equal(t, nethttp.StatusConflict, retry.Code)
+}
+
+func TestIdempotencyMiddleware_DoesNotStoreServerErrors(t *testing.T) {
+	status := nethttp.StatusInternalServerError
+	next := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.WriteHeader(status)
+	})
+	handler := http.NewIdempotencyMiddleware(newFakeIdempotencyStore()).Handle(next)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("POST", "key-1", `{"url":"https://example.com"}`))
+	require.Equal(t, nethttp.StatusInternalServerError, rr.Code)
+
+	status = nethttp.StatusCreated
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("POST", "key-1", `{"url":"https://example.com"}`))
+	assert.Equal(t, nethttp.StatusCreated, rr.Code)
+	assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+}
+
+func TestIdempotencyMiddleware_StoresAfterClientDisconnects(t *testing.T) {
+	calls := 0
+	var cancel context.CancelFunc
+	next := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		calls++
+		w.WriteHeader(nethttp.StatusCreated)
+		w.Write([]byte(`{"short_code":"abc123"}`))
+		cancel()
+	})
+	store := newFakeIdempotencyStore()
+	handler := http.NewIdempotencyMiddleware(store).Handle(next)
+
+	ctx, cancelRequest := context.WithCancel(context.Background())
+	cancel = cancelRequest
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("POST", "key-1", `{"url":"https://example.com"}`).WithContext(ctx))
+	require.Equal(t, nethttp.StatusCreated, rr.Code)
+	assert.NoError(t, store.storeCtxErr)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("POST", "key-1", `{"url":"https://example.com"}`))
+	assert.Equal(t, nethttp.StatusCreated, rr.Code)
+	assert.Equal(t, "true", rr.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, `{"short_code":"abc123"}`, rr.Body.String())
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyMiddleware_ReleasesAfterClientDisconnects(t *testing.T) {
+	status := nethttp.StatusInternalServerError
+	var cancel context.CancelFunc
+	next := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.WriteHeader(status)
+		if cancel != nil {
+			cancel()
+		}
+	})
+	store := newFakeIdempotencyStore()
+	handler := http.NewIdempotencyMiddleware(store).Handle(next)
+
+	ctx, cancelRequest := context.WithCancel(context.Background())
+	cancel = cancelRequest
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("POST", "key-1", `{"url":"https://example.com"}`).WithContext(ctx))
+	require.Equal(t, nethttp.StatusInternalServerError, rr.Code)
+	assert.NoError(t, store.storeCtxErr)
+
+	status, cancel = nethttp.StatusCreated, nil
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("POST", "key-1", `{"url":"https://example.com"}`))
+	assert.Equal(t, nethttp.StatusCreated, rr.Code, "the key is not left reserved")
+}
+
+func TestIdempotencyMiddleware_RejectsOversizedBody(t *testing.T) {
+	calls := 0
+	next := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		calls++
+	})
+	store := newFakeIdempotencyStore()
+	handler := http.NewIdempotencyMiddleware(store).Handle(next)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("POST", "key-1", strings.Repeat("a", 1<<20+1)))
+	assert.Equal(t, nethttp.StatusRequestEntityTooLarge, rr.Code)
+	assert.Equal(t, 0, calls)
+	assert.Empty(t, store.keys)
+}
+
+func TestIdempotencyMiddleware_PassesThrough(t *testing.T) {
+	calls := 0
+	next := nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		calls++
+		w.WriteHeader(nethttp.StatusOK)
+	})
+	store := newFakeIdempotencyStore()
+	handler := http.NewIdempotencyMiddleware(store).Handle(next)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/shorten", bytes.NewBufferString(`{}`)))
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("GET", "key-1", ""))
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("OPTIONS", "key-1", ""))
+	assert.Equal(t, 3, calls)
+	assert.Empty(t, store.keys)
+
+	store.err = assert.AnError
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, idempotentRequest("POST", "key-1", `{}`))
+	assert.Equal(t, nethttp.StatusOK, rr.Code)
+	assert.Equal(t, 4, calls)
+}
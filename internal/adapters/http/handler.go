@@ -2,8 +2,11 @@ package http
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/mikiasyonas/url-shortener/internal/core/domain"
@@ -11,14 +14,23 @@ import (
 )
 
 type Handlers struct {
-	urlService ports.URLService
-	baseUrl    string
+	urlService    ports.URLService
+	clickRecorder ports.ClickRecorder
+	// domains, when nil, confines every link to the default domain.
+	domains ports.DomainService
+	baseUrl string
+	// redirectCacheMaxAge is how long browsers may cache permanent
+	// redirects; zero keeps every redirect out of caches.
+	redirectCacheMaxAge time.Duration
 }
 
-func NewHandlers(urlService ports.URLService, baseUrl string) *Handlers {
+func NewHandlers(urlService ports.URLService, clickRecorder ports.ClickRecorder, domains ports.DomainService, baseUrl string, redirectCacheMaxAge time.Duration) *Handlers {
 	return &Handlers{
-		urlService: urlService,
-		baseUrl:    baseUrl,
+		urlService:          urlService,
+		clickRecorder:       clickRecorder,
+		domains:             domains,
+		baseUrl:             strings.TrimSuffix(baseUrl, "/"),
+		redirectCacheMaxAge: redirectCacheMaxAge,
 	}
 }
 
@@ -29,13 +41,24 @@ type JSONResponse struct {
 }
 
 type ShortenRequest struct {
-	URL string `json:"url"`
+	URL       string     `json:"url"`
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresIn int64      `json:"expires_in,omitempty"` // seconds from now
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Domain is a registered host to create the link on instead of the
+	// default domain.
+	Domain string `json:"domain,omitempty"`
+	// RedirectType is 301, 302, 307 or 308; 302 when omitted.
+	RedirectType int `json:"redirect_type,omitempty"`
 }
 
 type ShortenResponse struct {
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
-	ShortCode   string `json:"short_code"`
+	ShortURL     string     `json:"short_url"`
+	OriginalURL  string     `json:"original_url"`
+	ShortCode    string     `json:"short_code"`
+	Domain       string     `json:"domain,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RedirectType int        `json:"redirect_type"`
 }
 
 func (h *Handlers) ShortenURL(w http.ResponseWriter, r *http.Request) {
@@ -50,11 +73,59 @@ func (h *Handlers) ShortenURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	url, err := h.urlService.ShortenURL(r.Context(), req.URL)
+	if req.ExpiresIn != 0 && req.ExpiresAt != nil {
+		h.respondError(w, http.StatusBadRequest, "Only one of expires_in and expires_at may be set")
+		return
+	}
+	if req.ExpiresIn < 0 {
+		h.respondError(w, http.StatusBadRequest, "expires_in must be positive")
+		return
+	}
+
+	host, err := h.authorizeDomain(r, req.Domain)
 	if err != nil {
-		switch err {
-		case domain.ErrInvalidURL:
+		switch {
+		case errors.Is(err, domain.ErrDomainNotAllowed):
+			h.respondError(w, http.StatusForbidden, "Domain is not available to this caller")
+		case errors.Is(err, domain.ErrDomainPending):
+			h.respondError(w, http.StatusConflict, "Domain was registered recently and is not active yet; try again shortly")
+		default:
+			h.respondError(w, http.StatusInternalServerError, "Failed to shorten URL")
+		}
+		return
+	}
+
+	opts := domain.ShortenOptions{
+		Alias:        req.Alias,
+		ExpiresAt:    req.ExpiresAt,
+		OwnerID:      ownerID(r),
+		Domain:       host,
+		RedirectType: req.RedirectType,
+	}
+	if req.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+		opts.ExpiresAt = &expiresAt
+	}
+
+	url, err := h.urlService.ShortenURL(r.Context(), req.URL, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidURL):
 			h.respondError(w, http.StatusBadRequest, "Invalid URL")
+		case errors.Is(err, domain.ErrURLTooLong):
+			h.respondError(w, http.StatusBadRequest, "URL is too long")
+		case errors.Is(err, domain.ErrURLRejected):
+			h.respondError(w, http.StatusUnprocessableEntity, rejectionMessage(err))
+		case errors.Is(err, domain.ErrInvalidExpiry):
+			h.respondError(w, http.StatusBadRequest, "Expiry must be in the future")
+		case errors.Is(err, domain.ErrInvalidRedirectType):
+			h.respondError(w, http.StatusBadRequest, "Invalid redirect type")
+		case errors.Is(err, domain.ErrInvalidAlias):
+			h.respondError(w, http.StatusBadRequest, "Invalid alias")
+		case errors.Is(err, domain.ErrReservedAlias):
+			h.respondError(w, http.StatusBadRequest, "Alias is reserved")
+		case errors.Is(err, domain.ErrShortCodeTaken):
+			h.respondError(w, http.StatusConflict, "Alias is already taken")
 		default:
 			h.respondError(w, http.StatusInternalServerError, "Failed to shorten URL")
 		}
@@ -62,9 +133,12 @@ func (h *Handlers) ShortenURL(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := ShortenResponse{
-		ShortURL:    "http://" + r.Host + "/" + url.ShortCode,
-		OriginalURL: url.OriginalURL,
-		ShortCode:   url.ShortCode,
+		ShortURL:     h.shortURL(url),
+		OriginalURL:  url.OriginalURL,
+		ShortCode:    url.ShortCode,
+		Domain:       url.Domain,
+		ExpiresAt:    url.ExpiresAt,
+		RedirectType: url.RedirectStatus(),
 	}
 
 	h.respondJSON(w, http.StatusCreated, JSONResponse{
@@ -81,11 +155,14 @@ func (h *Handlers) Redirect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	originalURL, err := h.urlService.Redirect(r.Context(), shortCode)
+	host := h.resolveDomain(r)
+	url, err := h.urlService.Redirect(r.Context(), host, shortCode)
 	if err != nil {
 		switch err {
 		case domain.ErrURLNotFound:
 			http.NotFound(w, r)
+		case domain.ErrURLExpired:
+			h.respondError(w, http.StatusGone, "Link has expired")
 		case domain.ErrInvalidShortCode:
 			h.respondError(w, http.StatusBadRequest, "Invalid short code")
 		default:
@@ -94,9 +171,51 @@ func (h *Handlers) Redirect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Println("Original", originalURL)
+	h.recordClick(r, domain.LinkRef{Domain: host, ShortCode: shortCode})
 
-	http.Redirect(w, r, originalURL, http.StatusFound)
+	h.setRedirectCacheHeaders(w, url, time.Now())
+	http.Redirect(w, r, url.OriginalURL, url.RedirectStatus())
+}
+
+// setRedirectCacheHeaders keeps redirects out of browser and proxy caches so
+// that every click reaches the service and is counted. Only permanent
+// redirects may be cached, for redirectCacheMaxAge and never past the
+// link's expiry.
+func (h *Handlers) setRedirectCacheHeaders(w http.ResponseWriter, url *domain.URL, now time.Time) {
+	maxAge := h.redirectCacheMaxAge
+	if !domain.IsPermanentRedirect(url.RedirectStatus()) {
+		maxAge = 0
+	}
+	if url.ExpiresAt != nil {
+		if remaining := url.ExpiresAt.Sub(now); remaining < maxAge {
+			maxAge = remaining
+		}
+	}
+
+	seconds := int64(maxAge / time.Second)
+	if seconds <= 0 {
+		w.Header().Set("Cache-Control", "private, no-cache, no-store, max-age=0, must-revalidate")
+		w.Header().Set("Expires", "Thu, 01 Jan 1970 00:00:00 GMT")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.FormatInt(seconds, 10))
+	w.Header().Set("Expires", now.Add(time.Duration(seconds)*time.Second).UTC().Format(http.TimeFormat))
+}
+
+func (h *Handlers) recordClick(r *http.Request, link domain.LinkRef) {
+	if h.clickRecorder == nil {
+		return
+	}
+
+	click := domain.NewClick(
+		link,
+		r.Referer(),
+		r.UserAgent(),
+		r.Header.Get("Accept-Language"),
+		time.Now(),
+	)
+	h.clickRecorder.Record(click, ClientIP(r))
 }
 
 func (h *Handlers) HealthCheck(w http.ResponseWriter, r *http.Request) {
@@ -106,12 +225,61 @@ func (h *Handlers) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// shortURL builds a link's public URL: under the base URL on the default
+// domain, and with the base URL's scheme on a branded one.
+func (h *Handlers) shortURL(url *domain.URL) string {
+	if url.Domain == domain.DefaultDomain {
+		return h.baseUrl + "/" + url.ShortCode
+	}
+
+	scheme, _, ok := strings.Cut(h.baseUrl, "://")
+	if !ok {
+		scheme = "https"
+	}
+	return scheme + "://" + url.Domain + "/" + url.ShortCode
+}
+
+// resolveDomain maps the request's Host to the domain whose links it serves.
+func (h *Handlers) resolveDomain(r *http.Request) string {
+	if h.domains == nil {
+		return domain.DefaultDomain
+	}
+	return h.domains.Resolve(r.Host)
+}
+
+// authorizeDomain checks that the caller may create links on the requested
+// domain and returns it normalized.
+func (h *Handlers) authorizeDomain(r *http.Request, requested string) (string, error) {
+	if requested == "" {
+		return domain.DefaultDomain, nil
+	}
+	if h.domains == nil {
+		return "", domain.ErrDomainNotAllowed
+	}
+	return h.domains.Authorize(r.Context(), ownerID(r), requested)
+}
+
+// requestDomain reads the domain a management request addresses from the
+// "domain" query parameter; links on the default domain omit it.
+func requestDomain(r *http.Request) string {
+	return domain.NormalizeHost(r.URL.Query().Get("domain"))
+}
+
 func (h *Handlers) respondJSON(w http.ResponseWriter, status int, response JSONResponse) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(response)
 }
 
+// rejectionMessage tells the caller why screening refused a destination.
+func rejectionMessage(err error) string {
+	var rejection *domain.URLRejectedError
+	if errors.As(err, &rejection) {
+		return "URL rejected: " + rejection.Reason
+	}
+	return "URL rejected"
+}
+
 func (h *Handlers) respondError(w http.ResponseWriter, status int, message string) {
 	h.respondJSON(w, status, JSONResponse{
 		Success: false,
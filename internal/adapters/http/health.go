@@ -2,6 +2,7 @@ package http
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 
 	"github.com/mikiasyonas/url-shortener/pkg/monitoring"
@@ -37,10 +38,10 @@ func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *HealthHandler) Metrics(w http.ResponseWriter, r *http.Request) {
-	metrics := h.metrics.GetMetrics()
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(metrics)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := h.metrics.WritePrometheus(w); err != nil {
+		log.Printf("Failed to write metrics: %v", err)
+	}
 }
 
 func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,168 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader marks a response replayed from an earlier
+	// request rather than produced by this one.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodyBytes bounds the request body buffered to fingerprint it.
+	maxIdempotentBodyBytes = 1 << 20
+	// idempotencyStoreTimeout bounds storing the outcome of a request, which
+	// outlives the client when it has already disconnected.
+	idempotencyStoreTimeout = 5 * time.Second
+)
+
+// IdempotencyMiddleware makes write requests carrying an Idempotency-Key
+// header safe to retry: the response of the first request with a key is
+// stored and replayed to later requests with the same key and body, and
+// reusing a key for a different request is refused. Keys are scoped to the
+// caller, so it must run after authentication.
+type IdempotencyMiddleware struct {
+	store ports.IdempotencyStore
+}
+
+func NewIdempotencyMiddleware(store ports.IdempotencyStore) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		store: store,
+	}
+}
+
+func (m *IdempotencyMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(idempotencyKeyHeader)
+		if header == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(header) > maxIdempotencyKeyLength {
+			m.respondError(w, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				m.respondError(w, http.StatusRequestEntityTooLarge, "Request body is too large")
+				return
+			}
+			m.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := hashFields(ownerID(r), header)
+		fingerprint := hashFields(r.Method, r.URL.Path, r.URL.RawQuery, string(body))
+
+		held, err := m.store.Reserve(r.Context(), key, fingerprint)
+		if err != nil {
+			// Fail open, as the rate limiter does: retries may then repeat
+			// the request, but the API stays up.
+			log.Printf("Idempotency store unavailable, handling request without it: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch {
+		case held == nil:
+		case held.Fingerprint != fingerprint:
+			m.respondError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+			return
+		case !held.Completed():
+			m.respondError(w, http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+			return
+		default:
+			if held.ContentType != "" {
+				w.Header().Set("Content-Type", held.ContentType)
+			}
+			w.Header().Set(idempotentReplayedHeader, "true")
+			w.WriteHeader(held.Status)
+			w.Write(held.Body)
+			return
+		}
+
+		capture := &capturingWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(capture, r)
+
+		// The key must not stay reserved because the client went away, so
+		// the outcome is stored even after the request context is cancelled.
+		storeCtx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), idempotencyStoreTimeout)
+		defer cancel()
+
+		// Server errors are not stored, so that a retry gets another try.
+		if capture.statusCode >= http.StatusInternalServerError {
+			if err := m.store.Release(storeCtx, key, fingerprint); err != nil {
+				log.Printf("Failed to release idempotency key: %v", err)
+			}
+			return
+		}
+
+		err = m.store.Complete(storeCtx, &domain.IdempotencyKey{
+			Key:         key,
+			Fingerprint: fingerprint,
+			Status:      capture.statusCode,
+			ContentType: capture.Header().Get("Content-Type"),
+			Body:        capture.body.Bytes(),
+		})
+		if err != nil {
+			log.Printf("Failed to store idempotent response: %v", err)
+		}
+	})
+}
+
+func (m *IdempotencyMiddleware) respondError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(JSONResponse{Success: false, Error: message})
+}
+
+// hashFields hashes fields separated by NUL bytes, which header values and
+// request paths cannot contain; only the last field may be arbitrary.
+func hashFields(fields ...string) string {
+	h := sha256.New()
+	for _, field := range fields {
+		io.WriteString(h, field)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// capturingWriter keeps a copy of the response it passes on.
+type capturingWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (cw *capturingWriter) WriteHeader(code int) {
+	if !cw.wroteHeader {
+		cw.statusCode = code
+		cw.wroteHeader = true
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *capturingWriter) Write(b []byte) (int, error) {
+	cw.wroteHeader = true
+	cw.body.Write(b)
+	return cw.ResponseWriter.Write(b)
+}
@@ -0,0 +1,225 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+)
+
+type LinkResponse struct {
+	ShortURL     string     `json:"short_url"`
+	OriginalURL  string     `json:"original_url"`
+	ShortCode    string     `json:"short_code"`
+	Domain       string     `json:"domain,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ClickCount   int64      `json:"click_count"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RedirectType int        `json:"redirect_type"`
+	// LastCheck is absent until the destination has been checked.
+	LastCheck *LinkCheckResponse `json:"last_check,omitempty"`
+}
+
+// LinkCheckResponse is the result of the latest dead-link check. Status is
+// 0 when the destination could not be reached.
+type LinkCheckResponse struct {
+	Status    int       `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+	Broken    bool      `json:"broken"`
+}
+
+type LinkListResponse struct {
+	Links      []LinkResponse `json:"links"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// UpdateLinkRequest is the PATCH body. Omitted fields are left untouched and
+// an explicit "expires_at": null removes the expiry.
+type UpdateLinkRequest struct {
+	URL          *string         `json:"url,omitempty"`
+	ExpiresAt    json.RawMessage `json:"expires_at,omitempty"`
+	RedirectType *int            `json:"redirect_type,omitempty"`
+}
+
+func (h *Handlers) GetLink(w http.ResponseWriter, r *http.Request) {
+	shortCode := mux.Vars(r)["code"]
+
+	url, err := h.urlService.GetURL(r.Context(), ownerID(r), requestDomain(r), shortCode)
+	if err != nil {
+		h.respondLinkError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, JSONResponse{
+		Success: true,
+		Data:    h.linkResponse(url),
+	})
+}
+
+func (h *Handlers) UpdateLink(w http.ResponseWriter, r *http.Request) {
+	shortCode := mux.Vars(r)["code"]
+
+	var req UpdateLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	update := domain.URLUpdate{OriginalURL: req.URL, RedirectType: req.RedirectType}
+	if len(req.ExpiresAt) > 0 {
+		if bytes.Equal(req.ExpiresAt, []byte("null")) {
+			update.ClearExpiry = true
+		} else {
+			var expiresAt time.Time
+			if err := json.Unmarshal(req.ExpiresAt, &expiresAt); err != nil {
+				h.respondError(w, http.StatusBadRequest, "Invalid expires_at")
+				return
+			}
+			update.ExpiresAt = &expiresAt
+		}
+	}
+
+	url, err := h.urlService.UpdateURL(r.Context(), ownerID(r), requestDomain(r), shortCode, update)
+	if err != nil {
+		h.respondLinkError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, JSONResponse{
+		Success: true,
+		Data:    h.linkResponse(url),
+	})
+}
+
+func (h *Handlers) DeleteLink(w http.ResponseWriter, r *http.Request) {
+	shortCode := mux.Vars(r)["code"]
+
+	if err := h.urlService.DeleteURL(r.Context(), ownerID(r), requestDomain(r), shortCode); err != nil {
+		h.respondLinkError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) ListLinks(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseListFilter(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid query: "+err.Error())
+		return
+	}
+
+	page, err := h.urlService.ListURLs(r.Context(), filter)
+	if err != nil {
+		h.respondLinkError(w, err)
+		return
+	}
+
+	response := LinkListResponse{Links: make([]LinkResponse, 0, len(page.URLs))}
+	for _, url := range page.URLs {
+		response.Links = append(response.Links, h.linkResponse(url))
+	}
+	if page.NextCursor != nil {
+		response.NextCursor = page.NextCursor.Encode()
+	}
+
+	h.respondJSON(w, http.StatusOK, JSONResponse{
+		Success: true,
+		Data:    response,
+	})
+}
+
+func parseListFilter(r *http.Request) (domain.ListFilter, error) {
+	query := r.URL.Query()
+	filter := domain.ListFilter{
+		OwnerID: ownerID(r),
+		Query:   query.Get("q"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return filter, errors.New("invalid limit")
+		}
+		filter.Limit = n
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		c, err := domain.DecodeCursor(cursor)
+		if err != nil {
+			return filter, errors.New("invalid cursor")
+		}
+		filter.Cursor = c
+	}
+
+	switch status := domain.LinkStatus(query.Get("status")); status {
+	case "", domain.LinkStatusActive, domain.LinkStatusExpired, domain.LinkStatusBroken:
+		filter.Status = status
+	default:
+		return filter, errors.New("invalid status")
+	}
+
+	for param, target := range map[string]**time.Time{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+	} {
+		if value := query.Get(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, errors.New("invalid " + param)
+			}
+			*target = &t
+		}
+	}
+
+	return filter, nil
+}
+
+func (h *Handlers) linkResponse(url *domain.URL) LinkResponse {
+	response := LinkResponse{
+		ShortURL:     h.shortURL(url),
+		OriginalURL:  url.OriginalURL,
+		ShortCode:    url.ShortCode,
+		Domain:       url.Domain,
+		CreatedAt:    url.CreatedAt,
+		ClickCount:   url.ClickCount,
+		ExpiresAt:    url.ExpiresAt,
+		RedirectType: url.RedirectStatus(),
+	}
+	if url.LastCheckedAt != nil {
+		response.LastCheck = &LinkCheckResponse{
+			Status:    url.LastCheckStatus,
+			CheckedAt: *url.LastCheckedAt,
+			Broken:    url.IsBroken(),
+		}
+	}
+	return response
+}
+
+func (h *Handlers) respondLinkError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrURLNotFound):
+		h.respondError(w, http.StatusNotFound, "Link not found")
+	case errors.Is(err, domain.ErrInvalidShortCode):
+		h.respondError(w, http.StatusBadRequest, "Invalid short code")
+	case errors.Is(err, domain.ErrInvalidURL):
+		h.respondError(w, http.StatusBadRequest, "Invalid URL")
+	case errors.Is(err, domain.ErrURLTooLong):
+		h.respondError(w, http.StatusBadRequest, "URL is too long")
+	case errors.Is(err, domain.ErrURLRejected):
+		h.respondError(w, http.StatusUnprocessableEntity, rejectionMessage(err))
+	case errors.Is(err, domain.ErrInvalidExpiry):
+		h.respondError(w, http.StatusBadRequest, "Expiry must be in the future")
+	case errors.Is(err, domain.ErrInvalidRedirectType):
+		h.respondError(w, http.StatusBadRequest, "Invalid redirect type")
+	case errors.Is(err, domain.ErrInvalidCursor):
+		h.respondError(w, http.StatusBadRequest, "Invalid cursor")
+	default:
+		h.respondError(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
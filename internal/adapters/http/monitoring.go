@@ -31,8 +31,11 @@ func (m *MonitoringMiddleware) Middleware(next http.Handler) http.Handler {
 
 		duration := time.Since(start)
 		route := m.getRouteName(r)
-		m.metrics.RecordRequest(r.Method, rw.statusCode, duration)
+		m.metrics.RecordRequest(r.Method, route, rw.statusCode, duration)
 
+		if rw.statusCode >= http.StatusBadRequest {
+			return
+		}
 		switch route {
 		case "shorten":
 			m.metrics.RecordURLShortened()
@@ -42,6 +45,9 @@ func (m *MonitoringMiddleware) Middleware(next http.Handler) http.Handler {
 	})
 }
 
+// getRouteName returns the label used for a request's metrics. Unmatched
+// requests share a single label so that arbitrary paths cannot create an
+// unbounded number of series.
 func (m *MonitoringMiddleware) getRouteName(r *http.Request) string {
 	if route := mux.CurrentRoute(r); route != nil {
 		if name := route.GetName(); name != "" {
@@ -51,7 +57,7 @@ func (m *MonitoringMiddleware) getRouteName(r *http.Request) string {
 			return path
 		}
 	}
-	return r.URL.Path
+	return "unmatched"
 }
 
 type responseWriter struct {
@@ -1,74 +1,49 @@
 package http
 
 import (
-	"net"
+	"log"
 	"net/http"
-	"strings"
-	"sync"
+	"strconv"
 	"time"
 
-	"golang.org/x/time/rate"
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
 )
 
+// RateLimiter is middleware that enforces a per-client request budget and
+// advertises it with the RateLimit-* headers on every response.
 type RateLimiter struct {
-	visitors map[string]*visitor
-	mu       sync.RWMutex
-	rate     rate.Limit
-	burst    int
+	limiter ports.RateLimiter
+	// limit is the configured budget, advertised when the limiter cannot be
+	// asked.
+	limit int
 }
 
-type visitor struct {
-	limiter  *rate.Limiter
-	lastSeen time.Time
-}
-
-func NewRateLimiter(r rate.Limit, b int) *RateLimiter {
-	rl := &RateLimiter{
-		visitors: make(map[string]*visitor),
-		rate:     r,
-		burst:    b,
-	}
-
-	go rl.cleanupVisitors()
-
-	return rl
-}
-
-func (rl *RateLimiter) getVisitor(ip string) *rate.Limiter {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	v, exists := rl.visitors[ip]
-	if !exists {
-		limiter := rate.NewLimiter(rl.rate, rl.burst)
-		rl.visitors[ip] = &visitor{limiter, time.Now()}
-		return limiter
-	}
-
-	v.lastSeen = time.Now()
-	return v.limiter
-}
-
-func (rl *RateLimiter) cleanupVisitors() {
-	for {
-		time.Sleep(time.Minute)
-
-		rl.mu.Lock()
-		for ip, v := range rl.visitors {
-			if time.Since(v.lastSeen) > 3*time.Minute {
-				delete(rl.visitors, ip)
-			}
-		}
-		rl.mu.Unlock()
+func NewRateLimiter(limiter ports.RateLimiter, limit int) *RateLimiter {
+	return &RateLimiter{
+		limiter: limiter,
+		limit:   limit,
 	}
 }
 
 func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := getIPAddress(r)
-		limiter := rl.getVisitor(ip)
+		ip := ClientIP(r)
+		result, err := rl.limiter.Allow(r.Context(), ip)
+		if err != nil {
+			// Fail open: an unavailable limiter must not take the service down.
+			// Nothing is counted against the client meanwhile, so the full
+			// budget is advertised.
+			log.Printf("Rate limiter unavailable, allowing request from %s: %v", ip, err)
+			result = domain.RateLimitResult{Allowed: true, Limit: rl.limit, Remaining: rl.limit}
+		}
 
-		if !limiter.Allow() {
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
 			http.Error(w, `{"success": false, "error": "Rate limit exceeded"}`, http.StatusTooManyRequests)
 			return
 		}
@@ -77,22 +52,6 @@ func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
 	})
 }
 
-func getIPAddress(r *http.Request) string {
-	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-		ips := strings.Split(forwarded, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
-	}
-
-	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
-		return realIP
-	}
-
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
-	}
-
-	return ip
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
 }
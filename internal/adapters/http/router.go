@@ -1,25 +1,81 @@
 package http
 
 import (
+	"time"
+
 	"github.com/mikiasyonas/url-shortener/internal/core/ports"
 	"github.com/mikiasyonas/url-shortener/pkg/monitoring"
 
 	"github.com/gorilla/mux"
 )
 
-func NewRouter(urlService ports.URLService, baseUrl string, healthChecker *monitoring.HealthChecker, metrics *monitoring.Metrics) *mux.Router {
+// RouterOption configures an optional part of the router.
+type RouterOption func(*routerOptions)
+
+type routerOptions struct {
+	domainService       ports.DomainService
+	clickRecorder       ports.ClickRecorder
+	idempotencyStore    ports.IdempotencyStore
+	redirectCacheMaxAge time.Duration
+}
+
+// WithDomainService serves links on branded domains and lists them.
+func WithDomainService(domainService ports.DomainService) RouterOption {
+	return func(o *routerOptions) {
+		o.domainService = domainService
+	}
+}
+
+// WithClickRecorder records an analytics event for every redirect.
+func WithClickRecorder(clickRecorder ports.ClickRecorder) RouterOption {
+	return func(o *routerOptions) {
+		o.clickRecorder = clickRecorder
+	}
+}
+
+// WithIdempotencyStore honors Idempotency-Key headers on the API.
+func WithIdempotencyStore(store ports.IdempotencyStore) RouterOption {
+	return func(o *routerOptions) {
+		o.idempotencyStore = store
+	}
+}
+
+// WithRedirectCacheMaxAge lets clients cache permanent redirects for maxAge.
+func WithRedirectCacheMaxAge(maxAge time.Duration) RouterOption {
+	return func(o *routerOptions) {
+		o.redirectCacheMaxAge = maxAge
+	}
+}
+
+func NewRouter(urlService ports.URLService, statsService ports.StatsService, apiKeyService ports.APIKeyService, baseUrl string, healthChecker *monitoring.HealthChecker, metrics *monitoring.Metrics, opts ...RouterOption) *mux.Router {
+	var o routerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	router := mux.NewRouter()
-	handlers := NewHandlers(urlService, baseUrl)
+	handlers := NewHandlers(urlService, o.clickRecorder, o.domainService, baseUrl, o.redirectCacheMaxAge)
+	statsHandler := NewStatsHandler(statsService)
 
 	healthHandler := NewHealthHandler(healthChecker, metrics)
-	router.HandleFunc("/health", healthHandler.HealthCheck).Methods("GET")
-	router.HandleFunc("/metrics", healthHandler.Metrics).Methods("GET")
-	router.HandleFunc("/ready", healthHandler.Readiness).Methods("GET")
-	router.HandleFunc("/live", healthHandler.Liveness).Methods("GET")
+	router.HandleFunc("/health", healthHandler.HealthCheck).Methods("GET").Name("health")
+	router.HandleFunc("/metrics", healthHandler.Metrics).Methods("GET").Name("metrics")
+	router.HandleFunc("/ready", healthHandler.Readiness).Methods("GET").Name("ready")
+	router.HandleFunc("/live", healthHandler.Liveness).Methods("GET").Name("live")
 
 	api := router.PathPrefix("/api").Subrouter()
-	router.HandleFunc("/{code}", handlers.Redirect).Methods("GET")
-	api.HandleFunc("/shorten", handlers.ShortenURL).Methods("POST")
+	api.Use(NewAuthMiddleware(apiKeyService).Authenticate)
+	if o.idempotencyStore != nil {
+		api.Use(NewIdempotencyMiddleware(o.idempotencyStore).Handle)
+	}
+	router.HandleFunc("/{code}", handlers.Redirect).Methods("GET").Name("redirect")
+	api.HandleFunc("/shorten", handlers.ShortenURL).Methods("POST").Name("shorten")
+	api.HandleFunc("/links", handlers.ListLinks).Methods("GET").Name("list_links")
+	api.HandleFunc("/links/{code}", handlers.GetLink).Methods("GET").Name("get_link")
+	api.HandleFunc("/links/{code}", handlers.UpdateLink).Methods("PATCH").Name("update_link")
+	api.HandleFunc("/links/{code}", handlers.DeleteLink).Methods("DELETE").Name("delete_link")
+	api.HandleFunc("/links/{code}/stats", statsHandler.LinkStats).Methods("GET").Name("link_stats")
+	api.HandleFunc("/domains", handlers.ListDomains).Methods("GET").Name("list_domains")
 
 	return router
 }
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+const defaultStatsRange = 7 * 24 * time.Hour
+
+type StatsHandler struct {
+	statsService ports.StatsService
+}
+
+func NewStatsHandler(statsService ports.StatsService) *StatsHandler {
+	return &StatsHandler{
+		statsService: statsService,
+	}
+}
+
+func (h *StatsHandler) LinkStats(w http.ResponseWriter, r *http.Request) {
+	shortCode := mux.Vars(r)["code"]
+
+	query, err := parseStatsQuery(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid query: "+err.Error())
+		return
+	}
+
+	stats, err := h.statsService.GetLinkStats(r.Context(), ownerID(r), requestDomain(r), shortCode, query)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrURLNotFound):
+			h.respondError(w, http.StatusNotFound, "Link not found")
+		case errors.Is(err, domain.ErrInvalidStatsQuery):
+			h.respondError(w, http.StatusBadRequest, "Invalid stats range or interval")
+		default:
+			h.respondError(w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(JSONResponse{
+		Success: true,
+		Data:    stats,
+	})
+}
+
+func parseStatsQuery(r *http.Request) (domain.StatsQuery, error) {
+	params := r.URL.Query()
+	query := domain.StatsQuery{
+		To:       time.Now().UTC(),
+		Interval: domain.StatsIntervalDay,
+	}
+
+	if to := params.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return query, errors.New("invalid to")
+		}
+		query.To = t
+	}
+
+	query.From = query.To.Add(-defaultStatsRange)
+	if from := params.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return query, errors.New("invalid from")
+		}
+		query.From = t
+	}
+
+	if interval := params.Get("interval"); interval != "" {
+		query.Interval = domain.StatsInterval(interval)
+		if !query.Interval.IsValid() {
+			return query, errors.New("invalid interval")
+		}
+	}
+
+	if top := params.Get("top"); top != "" {
+		n, err := strconv.Atoi(top)
+		if err != nil || n <= 0 {
+			return query, errors.New("invalid top")
+		}
+		query.TopLimit = n
+	}
+
+	return query, nil
+}
+
+func (h *StatsHandler) respondError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(JSONResponse{
+		Success: false,
+		Error:   message,
+	})
+}
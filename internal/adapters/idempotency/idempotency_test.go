@@ -0,0 +1,114 @@
+package idempotency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/adapters/idempotency"
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedisStore(t *testing.T) (*idempotency.RedisStore, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return idempotency.NewRedisStore(client, time.Hour), mr
+}
+
+func TestRedisStore_ReplaysCompletedKey(t *testing.T) {
+	ctx := context.Background()
+	store, mr := newRedisStore(t)
+
+	held, err := store.Reserve(ctx, "k1", "fp1")
+	require.NoError(t, err)
+	assert.Nil(t, held)
+
+	held, err = store.Reserve(ctx, "k1", "fp1")
+	require.NoError(t, err)
+	require.NotNil(t, held)
+	assert.False(t, held.Completed())
+
+	require.NoError(t, store.Complete(ctx, &domain.IdempotencyKey{
+		Key:         "k1",
+		Fingerprint: "fp1",
+		Status:      201,
+		ContentType: "application/json",
+		Body:        []byte(`{"success":true}`),
+	}))
+
+	held, err = store.Reserve(ctx, "k1", "fp2")
+	require.NoError(t, err)
+	require.NotNil(t, held)
+	assert.Equal(t, "fp1", held.Fingerprint)
+	assert.Equal(t, 201, held.Status)
+	assert.Equal(t, `{"success":true}`, string(held.Body))
+
+	mr.FastForward(time.Hour)
+	held, err = store.Reserve(ctx, "k1", "fp2")
+	require.NoError(t, err)
+	assert.Nil(t, held)
+}
+
+func TestRedisStore_ClaimLapses(t *testing.T) {
+	ctx := context.Background()
+	store, mr := newRedisStore(t)
+
+	_, err := store.Reserve(ctx, "k1", "fp1")
+	require.NoError(t, err)
+
+	mr.FastForward(2 * time.Minute)
+	held, err := store.Reserve(ctx, "k1", "fp1")
+	require.NoError(t, err)
+	assert.Nil(t, held)
+}
+
+func TestRedisStore_Release(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newRedisStore(t)
+
+	_, err := store.Reserve(ctx, "k1", "fp1")
+	require.NoError(t, err)
+	require.NoError(t, store.Release(ctx, "k1", "fp1"))
+
+	held, err := store.Reserve(ctx, "k1", "fp1")
+	require.NoError(t, err)
+	assert.Nil(t, held)
+}
+
+func TestRedisStore_LapsedClaimDoesNotEndTakeover(t *testing.T) {
+	ctx := context.Background()
+	store, mr := newRedisStore(t)
+
+	_, err := store.Reserve(ctx, "k1", "fp1")
+	require.NoError(t, err)
+
+	mr.FastForward(2 * time.Minute)
+	held, err := store.Reserve(ctx, "k1", "fp2")
+	require.NoError(t, err)
+	require.Nil(t, held, "the lapsed claim is taken over")
+
+	// The first request finishes after its claim lapsed.
+	require.NoError(t, store.Complete(ctx, &domain.IdempotencyKey{Key: "k1", Fingerprint: "fp1", Status: 201}))
+	require.NoError(t, store.Release(ctx, "k1", "fp1"))
+
+	held, err = store.Reserve(ctx, "k1", "fp2")
+	require.NoError(t, err)
+	require.NotNil(t, held, "the second request still holds the key")
+	assert.Equal(t, "fp2", held.Fingerprint)
+	assert.False(t, held.Completed())
+
+	require.NoError(t, store.Complete(ctx, &domain.IdempotencyKey{Key: "k1", Fingerprint: "fp2", Status: 201}))
+	require.NoError(t, store.Release(ctx, "k1", "fp2"))
+
+	held, err = store.Reserve(ctx, "k1", "fp2")
+	require.NoError(t, err)
+	require.NotNil(t, held, "a completed key is not released")
+	assert.Equal(t, 201, held.Status)
+	assert.Equal(t, time.Hour, mr.TTL("idempotency:k1"))
+}
@@ -0,0 +1,127 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+
+	"gorm.io/gorm"
+)
+
+// claimTimeout is how long a key stays claimed by a request that neither
+// completes nor releases it, as when its instance stops mid-request.
+const claimTimeout = time.Minute
+
+// purgeInterval is how often expired keys are deleted from the table.
+const purgeInterval = 10 * time.Minute
+
+// PostgresStore keeps Idempotency-Keys in the idempotency_keys table. An
+// expired key is taken over in place by the next request using it, and
+// expired keys are purged in the background between Start and Stop.
+type PostgresStore struct {
+	db  *gorm.DB
+	ttl time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewPostgresStore returns a store replaying responses for ttl after they
+// were stored.
+func NewPostgresStore(db *gorm.DB, ttl time.Duration) *PostgresStore {
+	return &PostgresStore{
+		db:   db,
+		ttl:  ttl,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+func (s *PostgresStore) Reserve(ctx context.Context, key, fingerprint string) (*domain.IdempotencyKey, error) {
+	// The held key may lapse or be released between the two statements; a
+	// few attempts settle who holds it.
+	const maxAttempts = 3
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		now := time.Now()
+
+		var claimed []string
+		err := s.db.WithContext(ctx).Raw(`
+			INSERT INTO idempotency_keys ("key", fingerprint, status, content_type, body, expires_at)
+			VALUES (?, ?, 0, '', NULL, ?)
+			ON CONFLICT ("key") DO UPDATE
+			SET fingerprint = EXCLUDED.fingerprint, status = 0, content_type = '', body = NULL, expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= ?
+			RETURNING "key"`, key, fingerprint, now.Add(claimTimeout), now).Scan(&claimed).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(claimed) > 0 {
+			return nil, nil
+		}
+
+		var held domain.IdempotencyKey
+		err = s.db.WithContext(ctx).Where(`"key" = ? AND expires_at > ?`, key, now).First(&held).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &held, nil
+	}
+
+	return nil, fmt.Errorf("failed to reserve idempotency key after %d attempts", maxAttempts)
+}
+
+func (s *PostgresStore) Complete(ctx context.Context, key *domain.IdempotencyKey) error {
+	return s.db.WithContext(ctx).Model(&domain.IdempotencyKey{}).
+		Where(`"key" = ? AND fingerprint = ? AND status = 0`, key.Key, key.Fingerprint).
+		Updates(map[string]interface{}{
+			"status":       key.Status,
+			"content_type": key.ContentType,
+			"body":         key.Body,
+			"expires_at":   time.Now().Add(s.ttl),
+		}).Error
+}
+
+func (s *PostgresStore) Release(ctx context.Context, key, fingerprint string) error {
+	return s.db.WithContext(ctx).Where(`"key" = ? AND fingerprint = ? AND status = 0`, key, fingerprint).Delete(&domain.IdempotencyKey{}).Error
+}
+
+func (s *PostgresStore) Start() {
+	go s.run()
+}
+
+func (s *PostgresStore) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+func (s *PostgresStore) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if err := s.purge(ctx); err != nil {
+				log.Printf("Failed to purge expired idempotency keys: %v", err)
+			}
+			cancel()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *PostgresStore) purge(ctx context.Context) error {
+	return s.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&domain.IdempotencyKey{}).Error
+}
@@ -0,0 +1,101 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "idempotency:"
+
+// endClaimScript ends a claim on KEYS[1] if the key is still claimed with
+// fingerprint ARGV[1]: a request that outlived its claim must not overwrite
+// or delete the claim of the request that took the key over. With ARGV[2]
+// it stores that response for ARGV[3] milliseconds, otherwise it deletes
+// the key.
+var endClaimScript = redis.NewScript(`
+local data = redis.call("GET", KEYS[1])
+if not data then
+	return 0
+end
+local held = cjson.decode(data)
+if held.Status ~= 0 or held.Fingerprint ~= ARGV[1] then
+	return 0
+end
+if ARGV[2] then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+else
+	redis.call("DEL", KEYS[1])
+end
+return 1
+`)
+
+// RedisStore keeps each Idempotency-Key as a JSON value that expires on its
+// own; SET NX lets exactly one request claim a key.
+type RedisStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisStore returns a store replaying responses for ttl after they were
+// stored.
+func NewRedisStore(client *redis.Client, ttl time.Duration) *RedisStore {
+	return &RedisStore{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (s *RedisStore) Reserve(ctx context.Context, key, fingerprint string) (*domain.IdempotencyKey, error) {
+	claim, err := json.Marshal(&domain.IdempotencyKey{Key: key, Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+
+	// The held key may lapse or be released between SET and GET; a few
+	// attempts settle who holds it.
+	const maxAttempts = 3
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		claimed, err := s.client.SetNX(ctx, redisKeyPrefix+key, claim, claimTimeout).Result()
+		if err != nil {
+			return nil, err
+		}
+		if claimed {
+			return nil, nil
+		}
+
+		data, err := s.client.Get(ctx, redisKeyPrefix+key).Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var held domain.IdempotencyKey
+		if err := json.Unmarshal(data, &held); err != nil {
+			return nil, err
+		}
+		return &held, nil
+	}
+
+	return nil, fmt.Errorf("failed to reserve idempotency key after %d attempts", maxAttempts)
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key *domain.IdempotencyKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return endClaimScript.Run(ctx, s.client, []string{redisKeyPrefix + key.Key}, key.Fingerprint, data, s.ttl.Milliseconds()).Err()
+}
+
+func (s *RedisStore) Release(ctx context.Context, key, fingerprint string) error {
+	return endClaimScript.Run(ctx, s.client, []string{redisKeyPrefix + key}, fingerprint).Err()
+}
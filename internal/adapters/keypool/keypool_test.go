@@ -0,0 +1,189 @@
+package keypool_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/adapters/keypool"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sequentialGenerator struct {
+	mu   sync.Mutex
+	next int
+}
+
+func (g *sequentialGenerator) Generate(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.next++
+	return fmt.Sprintf("c%05d", g.next), nil
+}
+
+func (g *sequentialGenerator) Validate(code string) bool { return len(code) == 6 }
+
+func (g *sequentialGenerator) Resembles(code string) bool { return len(code) == 6 }
+
+type takenCodes map[string]bool
+
+func (t takenCodes) ExistsOnAnyDomain(ctx context.Context, shortCode string) (bool, error) {
+	return t[shortCode], nil
+}
+
+type depthRecorder struct {
+	mu    sync.Mutex
+	depth int64
+}
+
+func (r *depthRecorder) RecordKeyPoolDepth(depth int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.depth = depth
+}
+
+var testOptions = keypool.Options{
+	LowWater:       5,
+	Target:         20,
+	BatchSize:      4,
+	RefillInterval: time.Hour,
+}
+
+func newRedisPool(t *testing.T, taken takenCodes, metrics ports.KeyPoolMetrics) (*keypool.Pool, *redis.Client) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return keypool.NewRedisPool(client, &sequentialGenerator{}, taken, metrics, testOptions), client
+}
+
+func TestPool_RefillSkipsTakenCodes(t *testing.T) {
+	ctx := context.Background()
+	metrics := &depthRecorder{}
+	pool, _ := newRedisPool(t, takenCodes{"c00002": true}, metrics)
+
+	require.NoError(t, pool.Refill(ctx))
+
+	depth, err := pool.Depth(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), depth)
+	assert.Equal(t, int64(20), metrics.depth)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		code, err := pool.Take(ctx)
+		require.NoError(t, err)
+		assert.NotEqual(t, "c00002", code)
+		assert.False(t, seen[code], "code %s handed out twice", code)
+		seen[code] = true
+	}
+
+	_, err = pool.Take(ctx)
+	assert.ErrorIs(t, err, ports.ErrPoolEmpty)
+}
+
+func TestPool_RefillOnlyBelowLowWater(t *testing.T) {
+	ctx := context.Background()
+	pool, _ := newRedisPool(t, takenCodes{}, nil)
+
+	require.NoError(t, pool.Refill(ctx))
+	for i := 0; i < 8; i++ {
+		_, err := pool.Take(ctx)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, pool.Refill(ctx))
+	depth, err := pool.Depth(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), depth, "the pool is above its low-water mark")
+}
+
+func TestPool_InstancesTakeDisjointBatches(t *testing.T) {
+	ctx := context.Background()
+	pool, client := newRedisPool(t, takenCodes{}, nil)
+	other := keypool.NewRedisPool(client, &sequentialGenerator{}, takenCodes{}, nil, testOptions)
+
+	require.NoError(t, pool.Refill(ctx))
+
+	a, err := pool.Take(ctx)
+	require.NoError(t, err)
+	b, err := other.Take(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+
+	depth, err := pool.Depth(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), depth, "each instance holds a batch of four")
+}
+
+func TestPool_ConcurrentTakesHandOutDistinctCodes(t *testing.T) {
+	ctx := context.Background()
+	pool, _ := newRedisPool(t, takenCodes{}, nil)
+	require.NoError(t, pool.Refill(ctx))
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, err := pool.Take(ctx)
+			if errors.Is(err, ports.ErrPoolEmpty) {
+				// Another request may be holding the last batch.
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			assert.False(t, seen[code], "code %s handed out twice", code)
+			seen[code] = true
+		}()
+	}
+	wg.Wait()
+
+	assert.NotEmpty(t, seen)
+}
+
+func TestPool_HealthCheck(t *testing.T) {
+	ctx := context.Background()
+	pool, _ := newRedisPool(t, takenCodes{}, nil)
+
+	healthy, err := pool.HealthCheck(ctx)
+	assert.False(t, healthy)
+	assert.Error(t, err)
+
+	require.NoError(t, pool.Refill(ctx))
+	healthy, err = pool.HealthCheck(ctx)
+	assert.True(t, healthy)
+	assert.NoError(t, err)
+}
+
+func TestPool_StartRefillsAndStopReturnsBatch(t *testing.T) {
+	ctx := context.Background()
+	pool, _ := newRedisPool(t, takenCodes{}, nil)
+	pool.Start()
+
+	require.Eventually(t, func() bool {
+		depth, err := pool.Depth(ctx)
+		return err == nil && depth == 20
+	}, time.Second, 10*time.Millisecond)
+
+	_, err := pool.Take(ctx)
+	require.NoError(t, err)
+	require.NoError(t, pool.Stop(ctx))
+
+	depth, err := pool.Depth(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(19), depth, "the three unused codes of the batch are returned")
+}
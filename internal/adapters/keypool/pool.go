@@ -0,0 +1,253 @@
+// Package keypool keeps a shared pool of short codes generated ahead of time,
+// so that creating a link only has to take one.
+package keypool
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+// refillChunk is how many codes are generated and stored per round trip.
+const refillChunk = 500
+
+// CodeChecker reports whether a short code is in use on any domain; it is
+// satisfied by ports.URLRepository. Pooled codes are free on every domain
+// when they are generated, so a link on any domain may use one. A code may
+// still be taken between then and its use, by an alias or by a code
+// generated for a branded domain; saving the link then fails with
+// domain.ErrShortCodeTaken and the caller takes another.
+type CodeChecker interface {
+	ExistsOnAnyDomain(ctx context.Context, shortCode string) (bool, error)
+}
+
+// store holds the shared pool.
+type store interface {
+	push(ctx context.Context, codes []string) error
+	// pop removes and returns up to n codes.
+	pop(ctx context.Context, n int) ([]string, error)
+	size(ctx context.Context) (int64, error)
+}
+
+type Options struct {
+	// LowWater is the depth below which the pool is refilled, up to Target.
+	LowWater int
+	Target   int
+	// BatchSize is how many codes each instance takes from the shared pool
+	// at a time.
+	BatchSize      int
+	RefillInterval time.Duration
+}
+
+// Pool implements ports.ShortCodePool. Each instance keeps a small batch
+// taken from the shared store and refills the store in the background.
+type Pool struct {
+	store     store
+	generator ports.ShortCodeGenerator
+	codes     CodeChecker
+	metrics   ports.KeyPoolMetrics
+	opts      Options
+
+	mu    sync.Mutex
+	batch []string
+
+	refill chan struct{}
+	// ctx is cancelled by Stop, cutting short a refill in progress.
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newPool(store store, generator ports.ShortCodeGenerator, codes CodeChecker, metrics ports.KeyPoolMetrics, opts Options) *Pool {
+	if metrics == nil {
+		metrics = noopMetrics{}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Pool{
+		store:     store,
+		generator: generator,
+		codes:     codes,
+		metrics:   metrics,
+		opts:      opts,
+		refill:    make(chan struct{}, 1),
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+}
+
+func (p *Pool) Take(ctx context.Context) (string, error) {
+	if code, ok := p.takeFromBatch(nil); ok {
+		return code, nil
+	}
+
+	// The store is called without the lock, so that a slow round trip only
+	// holds up the requests that found the batch empty. Requests racing
+	// here each take a batch, and what one does not use is left for the
+	// others.
+	codes, err := p.store.pop(ctx, p.opts.BatchSize)
+	if err != nil {
+		return "", err
+	}
+	if len(codes) < p.opts.BatchSize {
+		p.requestRefill()
+	}
+
+	if code, ok := p.takeFromBatch(codes); ok {
+		return code, nil
+	}
+	return "", ports.ErrPoolEmpty
+}
+
+// takeFromBatch adds codes to this instance's batch and takes one from it.
+func (p *Pool) takeFromBatch(codes []string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.batch = append(p.batch, codes...)
+	if len(p.batch) == 0 {
+		return "", false
+	}
+	code := p.batch[0]
+	p.batch = p.batch[1:]
+	return code, true
+}
+
+// Depth returns the number of codes in the shared pool, not counting the
+// batches already taken by instances.
+func (p *Pool) Depth(ctx context.Context) (int64, error) {
+	return p.store.size(ctx)
+}
+
+// Refill tops the shared pool up to Target once it has fallen below
+// LowWater. Generated codes already in use are skipped.
+func (p *Pool) Refill(ctx context.Context) error {
+	depth, err := p.store.size(ctx)
+	if err != nil {
+		return err
+	}
+	p.metrics.RecordKeyPoolDepth(depth)
+	if depth >= int64(p.opts.LowWater) {
+		return nil
+	}
+
+	missing := p.opts.Target - int(depth)
+	// Collisions are rare, so a budget of twice the shortfall only runs out
+	// when the code space is nearly full.
+	attempts := 2 * missing
+	chunk := make([]string, 0, refillChunk)
+	for missing > 0 && attempts > 0 {
+		attempts--
+
+		code, err := p.generator.Generate(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to generate short code: %w", err)
+		}
+		exists, err := p.codes.ExistsOnAnyDomain(ctx, code)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		chunk = append(chunk, code)
+		missing--
+		if len(chunk) == refillChunk || missing == 0 {
+			if err := p.store.push(ctx, chunk); err != nil {
+				return err
+			}
+			chunk = chunk[:0]
+		}
+	}
+	if len(chunk) > 0 {
+		if err := p.store.push(ctx, chunk); err != nil {
+			return err
+		}
+	}
+
+	if depth, err := p.store.size(ctx); err == nil {
+		p.metrics.RecordKeyPoolDepth(depth)
+	}
+	return nil
+}
+
+// HealthCheck reports the pool unhealthy once the shared pool has run dry,
+// at which point links get codes generated on the write path again.
+func (p *Pool) HealthCheck(ctx context.Context) (bool, error) {
+	depth, err := p.store.size(ctx)
+	if err != nil {
+		return false, fmt.Errorf("key pool unavailable: %w", err)
+	}
+	if depth == 0 {
+		return false, fmt.Errorf("key pool is empty")
+	}
+	return true, nil
+}
+
+func (p *Pool) Start() {
+	go p.run()
+}
+
+// Stop halts the refiller and returns this instance's unused batch to the
+// shared pool.
+func (p *Pool) Stop(ctx context.Context) error {
+	p.cancel()
+	<-p.done
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.batch) == 0 {
+		return nil
+	}
+	if err := p.store.push(ctx, p.batch); err != nil {
+		return err
+	}
+	p.batch = nil
+	return nil
+}
+
+func (p *Pool) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.opts.RefillInterval)
+	defer ticker.Stop()
+
+	p.refillNow()
+	for {
+		select {
+		case <-ticker.C:
+			p.refillNow()
+		case <-p.refill:
+			p.refillNow()
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Pool) refillNow() {
+	ctx, cancel := context.WithTimeout(p.ctx, time.Minute)
+	defer cancel()
+
+	if err := p.Refill(ctx); err != nil && p.ctx.Err() == nil {
+		log.Printf("Failed to refill short code pool: %v", err)
+	}
+}
+
+// requestRefill wakes the refiller without waiting for it.
+func (p *Pool) requestRefill() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) RecordKeyPoolDepth(int64) {}
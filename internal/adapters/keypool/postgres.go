@@ -0,0 +1,50 @@
+package keypool
+
+import (
+	"context"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// postgresStore keeps the pool in the pooled_short_codes table. Instances
+// taking codes at the same time skip each other's locked rows instead of
+// waiting on them.
+type postgresStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresPool(db *gorm.DB, generator ports.ShortCodeGenerator, codes CodeChecker, metrics ports.KeyPoolMetrics, opts Options) *Pool {
+	return newPool(&postgresStore{db: db}, generator, codes, metrics, opts)
+}
+
+func (s *postgresStore) push(ctx context.Context, codes []string) error {
+	rows := make([]domain.PooledShortCode, len(codes))
+	for i, code := range codes {
+		rows[i] = domain.PooledShortCode{ShortCode: code}
+	}
+	return s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&rows).Error
+}
+
+func (s *postgresStore) pop(ctx context.Context, n int) ([]string, error) {
+	var codes []string
+	err := s.db.WithContext(ctx).Raw(`
+		DELETE FROM pooled_short_codes
+		WHERE short_code IN (
+			SELECT short_code FROM pooled_short_codes
+			LIMIT ? FOR UPDATE SKIP LOCKED
+		)
+		RETURNING short_code`, n).Scan(&codes).Error
+	return codes, err
+}
+
+func (s *postgresStore) size(ctx context.Context) (int64, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&domain.PooledShortCode{}).Count(&count).Error
+	return count, err
+}
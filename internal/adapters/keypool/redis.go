@@ -0,0 +1,41 @@
+package keypool
+
+import (
+	"context"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKey = "keypool:codes"
+
+// redisStore keeps the pool in a Redis set; SPOP hands each code to exactly
+// one instance.
+type redisStore struct {
+	client *redis.Client
+}
+
+func NewRedisPool(client *redis.Client, generator ports.ShortCodeGenerator, codes CodeChecker, metrics ports.KeyPoolMetrics, opts Options) *Pool {
+	return newPool(&redisStore{client: client}, generator, codes, metrics, opts)
+}
+
+func (s *redisStore) push(ctx context.Context, codes []string) error {
+	members := make([]interface{}, len(codes))
+	for i, code := range codes {
+		members[i] = code
+	}
+	return s.client.SAdd(ctx, redisKey, members...).Err()
+}
+
+func (s *redisStore) pop(ctx context.Context, n int) ([]string, error) {
+	codes, err := s.client.SPopN(ctx, redisKey, int64(n)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return codes, err
+}
+
+func (s *redisStore) size(ctx context.Context) (int64, error) {
+	return s.client.SCard(ctx, redisKey).Result()
+}
@@ -0,0 +1,290 @@
+// Package linkcheck periodically requests link destinations and records how
+// they answer, so that links pointing at dead pages can be found.
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/pkg/netaddr"
+)
+
+const userAgent = "url-shortener-link-checker/1.0"
+
+// LinkStore hands out links that are due for a check and records the
+// results; it is satisfied by ports.URLRepository.
+type LinkStore interface {
+	ClaimLinksToCheck(ctx context.Context, now, nextCheckAt time.Time, limit int) ([]*domain.URL, error)
+	RecordLinkCheck(ctx context.Context, id string, status int, checkedAt time.Time) error
+}
+
+type Options struct {
+	// Interval is how often due links are claimed, BatchSize links at a
+	// time; each link is checked again RecheckAfter later.
+	Interval     time.Duration
+	BatchSize    int
+	RecheckAfter time.Duration
+	// Concurrency is how many hosts are checked at once. Links on the same
+	// host are checked one after the other, HostDelay apart, also across
+	// batches.
+	Concurrency int
+	HostDelay   time.Duration
+	// Timeout bounds each request, redirects included.
+	Timeout time.Duration
+	// AllowPrivateNetworks lets requests reach loopback, private and
+	// link-local addresses, which are refused by default in case a
+	// destination's DNS has been pointed into the internal network.
+	AllowPrivateNetworks bool
+}
+
+// Checker checks link destinations in the background. A destination is
+// requested with HEAD, and with GET when HEAD fails or answers with an error,
+// as some servers do not implement HEAD; redirects are followed.
+type Checker struct {
+	store  LinkStore
+	client *http.Client
+	opts   Options
+
+	mu sync.Mutex
+	// lastChecked is when each recently checked host last finished a check.
+	lastChecked map[string]time.Time
+
+	// ctx is cancelled by Stop, cutting short the checks in progress.
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewChecker(store LinkStore, opts Options) *Checker {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivateNetworks {
+		dialer.Control = refusePrivateAddresses
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Checker{
+		store: store,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+		},
+		opts:        opts,
+		lastChecked: make(map[string]time.Time),
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+}
+
+func (c *Checker) Start() {
+	go c.run()
+}
+
+func (c *Checker) Stop() {
+	c.cancel()
+	<-c.done
+}
+
+func (c *Checker) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := c.CheckDue(c.ctx); err != nil && c.ctx.Err() == nil {
+				log.Printf("Failed to check links: %v", err)
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// CheckDue claims a batch of due links, checks them and records the
+// results. It returns the number of links checked.
+func (c *Checker) CheckDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	links, err := c.store.ClaimLinksToCheck(ctx, now, now.Add(c.opts.RecheckAfter), c.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	c.forgetIdleHosts()
+
+	var hosts []string
+	byHost := make(map[string][]*domain.URL)
+	for _, link := range links {
+		host := destinationHost(link.OriginalURL)
+		if _, ok := byHost[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		byHost[host] = append(byHost[host], link)
+	}
+
+	var (
+		mu      sync.Mutex
+		checked int
+		wg      sync.WaitGroup
+	)
+	slots := make(chan struct{}, c.opts.Concurrency)
+	for _, host := range hosts {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(host string, links []*domain.URL) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			for _, link := range links {
+				if !c.waitForHost(ctx, host) {
+					return
+				}
+				ok := c.check(ctx, link)
+				c.markHostChecked(host)
+				if ok {
+					mu.Lock()
+					checked++
+					mu.Unlock()
+				}
+			}
+		}(host, byHost[host])
+	}
+	wg.Wait()
+
+	return checked, ctx.Err()
+}
+
+// waitForHost waits until HostDelay has passed since host was last checked,
+// returning false if ctx ends first.
+func (c *Checker) waitForHost(ctx context.Context, host string) bool {
+	c.mu.Lock()
+	last, ok := c.lastChecked[host]
+	c.mu.Unlock()
+
+	if !ok {
+		return ctx.Err() == nil
+	}
+	return sleep(ctx, time.Until(last.Add(c.opts.HostDelay)))
+}
+
+func (c *Checker) markHostChecked(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastChecked[host] = time.Now()
+}
+
+// forgetIdleHosts drops the hosts that may be checked again right away, so
+// that lastChecked does not grow with every host ever checked.
+func (c *Checker) forgetIdleHosts() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for host, last := range c.lastChecked {
+		if time.Since(last) >= c.opts.HostDelay {
+			delete(c.lastChecked, host)
+		}
+	}
+}
+
+// check requests the link's destination and records its status, or 0 when
+// it could not be reached. Nothing is recorded when ctx ends first.
+func (c *Checker) check(ctx context.Context, link *domain.URL) bool {
+	status, err := c.Probe(ctx, link.OriginalURL)
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		log.Printf("Link %s is unreachable: %v", link.ShortCode, err)
+	}
+
+	if err := c.store.RecordLinkCheck(ctx, link.ID, status, time.Now()); err != nil {
+		log.Printf("Failed to record check of link %s: %v", link.ShortCode, err)
+		return false
+	}
+	return true
+}
+
+// Probe returns the status rawURL answers with, falling back from HEAD to
+// GET. The status is 0 when the destination could not be reached.
+func (c *Checker) Probe(ctx context.Context, rawURL string) (int, error) {
+	status, err := c.request(ctx, http.MethodHead, rawURL)
+	if err == nil && status < 400 {
+		return status, nil
+	}
+	return c.request(ctx, http.MethodGet, rawURL)
+}
+
+func (c *Checker) request(ctx context.Context, method, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	// The body is not needed; closing it unread gives up the connection,
+	// which is cheaper than downloading a large page.
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func destinationHost(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return parsed.Hostname()
+}
+
+// sleep waits for d, returning false if ctx ends first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+var errPrivateAddress = errors.New("destination resolves to a private address")
+
+// refusePrivateAddresses is a net.Dialer Control function that refuses
+// connections to addresses links must not lead into. It sees the resolved
+// address, so it also covers redirects and DNS changes.
+func refusePrivateAddresses(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || netaddr.IsPrivate(ip) {
+		return fmt.Errorf("%w: %s", errPrivateAddress, host)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package linkcheck_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/adapters/linkcheck"
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	mu          sync.Mutex
+	links       []*domain.URL
+	nextCheckAt time.Time
+	results     map[string]int
+}
+
+func (s *fakeStore) ClaimLinksToCheck(ctx context.Context, now, nextCheckAt time.Time, limit int) ([]*domain.URL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextCheckAt = nextCheckAt
+	n := min(limit, len(s.links))
+	claimed := s.links[:n]
+	s.links = s.links[n:]
+	return claimed, nil
+}
+
+func (s *fakeStore) RecordLinkCheck(ctx context.Context, id string, status int, checkedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.results == nil {
+		s.results = make(map[string]int)
+	}
+	s.results[id] = status
+	return nil
+}
+
+func newChecker(store linkcheck.LinkStore, opts linkcheck.Options) *linkcheck.Checker {
+	if opts.BatchSize == 0 {
+		opts.BatchSize = 100
+	}
+	opts.RecheckAfter = 24 * time.Hour
+	opts.Timeout = 2 * time.Second
+	opts.AllowPrivateNetworks = true
+	return linkcheck.NewChecker(store, opts)
+}
+
+// destination serves /ok, /gone (404) and /no-head (405 to HEAD, 200 to
+// GET), recording the requests it receives.
+func destination(t *testing.T) (*httptest.Server, *[]string) {
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/ok":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case r.URL.Path == "/no-head" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/no-head":
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestProbe(t *testing.T) {
+	server, requests := destination(t)
+	checker := newChecker(&fakeStore{}, linkcheck.Options{})
+	ctx := context.Background()
+
+	status, err := checker.Probe(ctx, server.URL+"/ok")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"HEAD /ok"}, *requests, "a successful HEAD is enough")
+
+	*requests = nil
+	status, err = checker.Probe(ctx, server.URL+"/no-head")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"HEAD /no-head", "GET /no-head"}, *requests)
+
+	status, err = checker.Probe(ctx, server.URL+"/gone")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	*requests = nil
+	status, err = checker.Probe(ctx, server.URL+"/moved")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status, "redirects are followed")
+	assert.Equal(t, []string{"HEAD /moved", "HEAD /ok"}, *requests)
+}
+
+func TestProbe_Unreachable(t *testing.T) {
+	server, _ := destination(t)
+	url := server.URL + "/ok"
+	server.Close()
+
+	status, err := newChecker(&fakeStore{}, linkcheck.Options{}).Probe(context.Background(), url)
+	assert.Error(t, err)
+	assert.Equal(t, 0, status)
+}
+
+func TestProbe_RefusesPrivateNetworks(t *testing.T) {
+	server, requests := destination(t)
+	checker := linkcheck.NewChecker(&fakeStore{}, linkcheck.Options{Timeout: 2 * time.Second})
+
+	status, err := checker.Probe(context.Background(), server.URL+"/ok")
+	assert.ErrorContains(t, err, "private address")
+	assert.Equal(t, 0, status)
+	assert.Empty(t, *requests)
+}
+
+func TestCheckDue_RecordsResults(t *testing.T) {
+	server, _ := destination(t)
+	closed, _ := destination(t)
+	closed.Close()
+
+	store := &fakeStore{links: []*domain.URL{
+		{ID: "1", ShortCode: "ok", OriginalURL: server.URL + "/ok"},
+		{ID: "2", ShortCode: "gone", OriginalURL: server.URL + "/gone"},
+		{ID: "3", ShortCode: "nohead", OriginalURL: server.URL + "/no-head"},
+		{ID: "4", ShortCode: "down", OriginalURL: closed.URL + "/ok"},
+	}}
+	checker := newChecker(store, linkcheck.Options{Concurrency: 2})
+
+	before := time.Now()
+	checked, err := checker.CheckDue(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, checked)
+	assert.Equal(t, map[string]int{"1": 200, "2": 404, "3": 200, "4": 0}, store.results)
+	assert.WithinDuration(t, before.Add(24*time.Hour), store.nextCheckAt, time.Minute)
+}
+
+func TestCheckDue_PoliteToHosts(t *testing.T) {
+	const hostDelay = 50 * time.Millisecond
+
+	var inFlight, maxInFlight atomic.Int32
+	var mu sync.Mutex
+	arrivals := make(map[string][]time.Time)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			max := maxInFlight.Load()
+			if n <= max || maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+
+		host, _, _ := strings.Cut(r.Host, ":")
+		mu.Lock()
+		arrivals[host] = append(arrivals[host], time.Now())
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	// localhost and 127.0.0.1 reach the same server but count as two hosts.
+	port := server.URL[strings.LastIndex(server.URL, ":"):]
+	var links []*domain.URL
+	for i, host := range []string{"127.0.0.1", "localhost", "127.0.0.1", "localhost", "127.0.0.1"} {
+		links = append(links, &domain.URL{ID: string(rune('a' + i)), OriginalURL: "http://" + host + port + "/"})
+	}
+	store := &fakeStore{links: links}
+	checker := newChecker(store, linkcheck.Options{Concurrency: 1, HostDelay: hostDelay})
+
+	checked, err := checker.CheckDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 5, checked)
+
+	assert.Equal(t, int32(1), maxInFlight.Load(), "at most Concurrency hosts are checked at once")
+	for host, times := range arrivals {
+		for i := 1; i < len(times); i++ {
+			assert.GreaterOrEqual(t, times[i].Sub(times[i-1]), hostDelay, host)
+		}
+	}
+}
+
+func TestCheckDue_PoliteToHostsAcrossBatches(t *testing.T) {
+	const hostDelay = 50 * time.Millisecond
+
+	var mu sync.Mutex
+	var arrivals []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		arrivals = append(arrivals, time.Now())
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	store := &fakeStore{links: []*domain.URL{
+		{ID: "1", OriginalURL: server.URL + "/a"},
+		{ID: "2", OriginalURL: server.URL + "/b"},
+	}}
+	checker := newChecker(store, linkcheck.Options{BatchSize: 1, HostDelay: hostDelay})
+
+	for i := 0; i < 2; i++ {
+		checked, err := checker.CheckDue(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, checked)
+	}
+
+	require.Len(t, arrivals, 2)
+	assert.GreaterOrEqual(t, arrivals[1].Sub(arrivals[0]), hostDelay, "the delay holds across batches")
+}
+
+func TestCheckDue_StopsWithContext(t *testing.T) {
+	server, requests := destination(t)
+
+	store := &fakeStore{links: []*domain.URL{
+		{ID: "1", OriginalURL: server.URL + "/ok"},
+		{ID: "2", OriginalURL: server.URL + "/gone"},
+	}}
+	checker := newChecker(store, linkcheck.Options{HostDelay: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	checked, err := checker.CheckDue(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, checked)
+	assert.Equal(t, map[string]int{"1": 200}, store.results)
+	assert.Equal(t, []string{"HEAD /ok"}, *requests)
+}
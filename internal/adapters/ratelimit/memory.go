@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+
+	"golang.org/x/time/rate"
+)
+
+// MemoryLimiter is a per-process token bucket limiter. Each replica enforces
+// the budget on its own, so it is only suitable for single instances.
+type MemoryLimiter struct {
+	visitors map[string]*visitor
+	mu       sync.Mutex
+	rate     rate.Limit
+	burst    int
+}
+
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func NewMemoryLimiter(perSecond float64, burst int) *MemoryLimiter {
+	l := &MemoryLimiter{
+		visitors: make(map[string]*visitor),
+		rate:     rate.Limit(perSecond),
+		burst:    burst,
+	}
+
+	go l.cleanupVisitors()
+
+	return l
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string) (domain.RateLimitResult, error) {
+	limiter := l.getVisitor(key)
+	now := time.Now()
+
+	result := domain.RateLimitResult{Limit: l.burst}
+
+	reservation := limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		result.RetryAfter = delay
+	} else {
+		result.Allowed = true
+	}
+
+	tokens := limiter.TokensAt(now)
+	if tokens > 0 {
+		result.Remaining = int(tokens)
+	}
+	result.ResetAfter = time.Duration((float64(l.burst) - tokens) / float64(l.rate) * float64(time.Second))
+
+	return result, nil
+}
+
+func (l *MemoryLimiter) getVisitor(key string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	v, exists := l.visitors[key]
+	if !exists {
+		limiter := rate.NewLimiter(l.rate, l.burst)
+		l.visitors[key] = &visitor{limiter, time.Now()}
+		return limiter
+	}
+
+	v.lastSeen = time.Now()
+	return v.limiter
+}
+
+func (l *MemoryLimiter) cleanupVisitors() {
+	for {
+		time.Sleep(time.Minute)
+
+		l.mu.Lock()
+		for key, v := range l.visitors {
+			if time.Since(v.lastSeen) > 3*time.Minute {
+				delete(l.visitors, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
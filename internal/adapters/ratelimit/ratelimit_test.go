@@ -0,0 +1,109 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/adapters/ratelimit"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedisLimiter(t *testing.T, perSecond float64, burst int) (*ratelimit.RedisLimiter, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return ratelimit.NewRedisLimiter(client, perSecond, burst), mr
+}
+
+func assertBurstThenLimited(t *testing.T, limiter ports.RateLimiter) {
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(ctx, "1.2.3.4")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed, "request %d should be allowed", i)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, 2-i, result.Remaining)
+	}
+
+	result, err := limiter.Allow(ctx, "1.2.3.4")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+	assert.LessOrEqual(t, result.RetryAfter, time.Second)
+	assert.Greater(t, result.ResetAfter, result.RetryAfter)
+
+	other, err := limiter.Allow(ctx, "5.6.7.8")
+	require.NoError(t, err)
+	assert.True(t, other.Allowed, "other clients have their own budget")
+}
+
+func TestMemoryLimiter_BurstThenLimited(t *testing.T) {
+	assertBurstThenLimited(t, ratelimit.NewMemoryLimiter(1, 3))
+}
+
+func TestRedisLimiter_BurstThenLimited(t *testing.T) {
+	limiter, _ := newRedisLimiter(t, 1, 3)
+	assertBurstThenLimited(t, limiter)
+}
+
+func TestRedisLimiter_SharedAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+
+	var limiters []*ratelimit.RedisLimiter
+	for i := 0; i < 2; i++ {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+		limiters = append(limiters, ratelimit.NewRedisLimiter(client, 1, 2))
+	}
+
+	first, err := limiters[0].Allow(ctx, "1.2.3.4")
+	require.NoError(t, err)
+	second, err := limiters[1].Allow(ctx, "1.2.3.4")
+	require.NoError(t, err)
+	third, err := limiters[0].Allow(ctx, "1.2.3.4")
+	require.NoError(t, err)
+
+	assert.True(t, first.Allowed)
+	assert.True(t, second.Allowed)
+	assert.False(t, third.Allowed, "the budget is shared by every instance")
+}
+
+func TestRedisLimiter_Replenishes(t *testing.T) {
+	ctx := context.Background()
+	limiter, mr := newRedisLimiter(t, 10, 1)
+
+	start := time.Now()
+	mr.SetTime(start)
+
+	result, err := limiter.Allow(ctx, "1.2.3.4")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = limiter.Allow(ctx, "1.2.3.4")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	mr.SetTime(start.Add(100 * time.Millisecond))
+
+	result, err = limiter.Allow(ctx, "1.2.3.4")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRedisLimiter_Unavailable(t *testing.T) {
+	limiter, mr := newRedisLimiter(t, 1, 1)
+	mr.Close()
+
+	_, err := limiter.Allow(context.Background(), "1.2.3.4")
+	assert.Error(t, err)
+}
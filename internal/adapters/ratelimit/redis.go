@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "ratelimit:"
+
+// gcraScript implements the generic cell rate algorithm. The key holds the
+// theoretical arrival time (TAT) in microseconds of Redis server time, so
+// every replica shares one budget and one clock.
+//
+// ARGV[1] is the emission interval and ARGV[2] the burst. The script returns
+// {allowed, remaining, retry_after, reset_after}, durations in microseconds.
+var gcraScript = redis.NewScript(`
+if redis.replicate_commands then
+  redis.replicate_commands()
+end
+
+local emission = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local tolerance = emission * burst
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if not tat or tat < now then
+  tat = now
+end
+
+local new_tat = tat + emission
+local allow_at = new_tat - tolerance
+if now < allow_at then
+  return {0, 0, allow_at - now, tat - now}
+end
+
+local reset_after = new_tat - now
+redis.call('SET', KEYS[1], new_tat, 'PX', math.ceil(reset_after / 1000))
+return {1, math.floor((tolerance - reset_after) / emission), 0, reset_after}
+`)
+
+// RedisLimiter enforces a budget shared by all replicas using a GCRA script.
+// It allows burst requests at once and then perSecond requests per second.
+type RedisLimiter struct {
+	client   *redis.Client
+	emission time.Duration
+	burst    int
+}
+
+func NewRedisLimiter(client *redis.Client, perSecond float64, burst int) *RedisLimiter {
+	return &RedisLimiter{
+		client:   client,
+		emission: time.Duration(float64(time.Second) / perSecond),
+		burst:    burst,
+	}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string) (domain.RateLimitResult, error) {
+	values, err := gcraScript.Run(ctx, l.client, []string{keyPrefix + key},
+		l.emission.Microseconds(), l.burst).Int64Slice()
+	if err != nil {
+		return domain.RateLimitResult{}, fmt.Errorf("failed to evaluate rate limit: %w", err)
+	}
+	if len(values) != 4 {
+		return domain.RateLimitResult{}, fmt.Errorf("unexpected rate limit reply: %v", values)
+	}
+
+	return domain.RateLimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      l.burst,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Microsecond,
+		ResetAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func (r *APIKeyRepository) Save(ctx context.Context, key *domain.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *APIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	result := r.db.WithContext(ctx).Where("key_hash = ?", keyHash).First(&key)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &key, nil
+}
+
+func (r *APIKeyRepository) List(ctx context.Context, ownerID string) ([]*domain.APIKey, error) {
+	var keys []*domain.APIKey
+	result := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Order("created_at").Find(&keys)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return keys, nil
+}
+
+// Revoke marks a key as revoked. Revoking an already revoked key keeps the
+// original revocation time.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id string, revokedAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&domain.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&domain.APIKey{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+	return nil
+}
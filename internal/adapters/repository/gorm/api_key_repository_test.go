@@ -0,0 +1,76 @@
+package gorm
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/pkg/config"
+	"github.com/mikiasyonas/url-shortener/pkg/database"
+	"gorm.io/gorm"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type APIKeyRepositoryTestSuite struct {
+	suite.Suite
+	db   *gorm.DB
+	repo *APIKeyRepository
+	ctx  context.Context
+}
+
+func (suite *APIKeyRepositoryTestSuite) SetupTest() {
+	cfg := config.Load()
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+
+	db, err := database.Connect(&cfg.Database)
+	suite.Require().NoError(err)
+
+	suite.db = db
+	suite.repo = NewAPIKeyRepository(db)
+	suite.ctx = context.Background()
+
+	suite.db.Exec("DELETE FROM api_keys")
+}
+
+func (suite *APIKeyRepositoryTestSuite) TestSaveAndFindByHash() {
+	key, err := domain.NewAPIKey("owner-1", "ci")
+	suite.Require().NoError(err)
+	key.Prefix = "usk_abcdefgh"
+	key.KeyHash = "hash-1"
+
+	suite.NoError(suite.repo.Save(suite.ctx, key))
+
+	found, err := suite.repo.FindByHash(suite.ctx, "hash-1")
+	suite.NoError(err)
+	suite.Equal("owner-1", found.OwnerID)
+
+	_, err = suite.repo.FindByHash(suite.ctx, "missing")
+	suite.ErrorIs(err, domain.ErrAPIKeyNotFound)
+}
+
+func (suite *APIKeyRepositoryTestSuite) TestRevoke() {
+	key, _ := domain.NewAPIKey("owner-1", "ci")
+	key.Prefix = "usk_abcdefgh"
+	key.KeyHash = "hash-1"
+	suite.Require().NoError(suite.repo.Save(suite.ctx, key))
+
+	suite.NoError(suite.repo.Revoke(suite.ctx, key.ID, time.Now()))
+	suite.NoError(suite.repo.Revoke(suite.ctx, key.ID, time.Now()))
+
+	found, err := suite.repo.FindByHash(suite.ctx, "hash-1")
+	suite.NoError(err)
+	suite.True(found.IsRevoked())
+
+	err = suite.repo.Revoke(suite.ctx, "00000000-0000-0000-0000-000000000000", time.Now())
+	suite.ErrorIs(err, domain.ErrAPIKeyNotFound)
+}
+
+func TestAPIKeyRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(APIKeyRepositoryTestSuite))
+}
@@ -0,0 +1,24 @@
+package gorm
+
+import (
+	"context"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+
+	"gorm.io/gorm"
+)
+
+type ClickRepository struct {
+	db *gorm.DB
+}
+
+func NewClickRepository(db *gorm.DB) *ClickRepository {
+	return &ClickRepository{db: db}
+}
+
+func (r *ClickRepository) SaveBatch(ctx context.Context, clicks []*domain.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(clicks, clickBatchSize).Error
+}
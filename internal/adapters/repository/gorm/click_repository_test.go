@@ -0,0 +1,57 @@
+package gorm
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/pkg/config"
+	"github.com/mikiasyonas/url-shortener/pkg/database"
+	"gorm.io/gorm"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ClickRepositoryTestSuite struct {
+	suite.Suite
+	db   *gorm.DB
+	repo *ClickRepository
+	ctx  context.Context
+}
+
+func (suite *ClickRepositoryTestSuite) SetupTest() {
+	cfg := config.Load()
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+
+	db, err := database.Connect(&cfg.Database)
+	suite.Require().NoError(err)
+
+	suite.db = db
+	suite.repo = NewClickRepository(db)
+	suite.ctx = context.Background()
+
+	suite.db.Exec("DELETE FROM clicks")
+}
+
+func (suite *ClickRepositoryTestSuite) TestSaveBatch() {
+	clicks := []*domain.Click{
+		domain.NewClick(domain.LinkRef{ShortCode: "abc123"}, "https://news.example", "agent", "en-US", time.Now()),
+		domain.NewClick(domain.LinkRef{ShortCode: "abc123"}, "", "agent", "de-DE", time.Now()),
+	}
+
+	err := suite.repo.SaveBatch(suite.ctx, clicks)
+	suite.NoError(err)
+
+	var count int64
+	suite.db.Model(&domain.Click{}).Where("short_code = ?", "abc123").Count(&count)
+	suite.Equal(int64(2), count)
+}
+
+func TestClickRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(ClickRepositoryTestSuite))
+}
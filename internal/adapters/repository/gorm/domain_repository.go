@@ -0,0 +1,50 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type DomainRepository struct {
+	db *gorm.DB
+}
+
+func NewDomainRepository(db *gorm.DB) *DomainRepository {
+	return &DomainRepository{db: db}
+}
+
+func (r *DomainRepository) Save(ctx context.Context, d *domain.Domain) error {
+	err := r.db.WithContext(ctx).Create(d).Error
+	if err != nil && database.IsDuplicateKeyError(err) {
+		return domain.ErrDomainExists
+	}
+	return err
+}
+
+func (r *DomainRepository) FindByHost(ctx context.Context, host string) (*domain.Domain, error) {
+	var d domain.Domain
+	result := r.db.WithContext(ctx).Where("host = ?", host).First(&d)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrDomainNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &d, nil
+}
+
+func (r *DomainRepository) List(ctx context.Context) ([]*domain.Domain, error) {
+	var domains []*domain.Domain
+	result := r.db.WithContext(ctx).Order("host").Find(&domains)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return domains, nil
+}
@@ -0,0 +1,67 @@
+package gorm
+
+import (
+	"context"
+	"log"
+	"testing"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/pkg/config"
+	"github.com/mikiasyonas/url-shortener/pkg/database"
+	"gorm.io/gorm"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DomainRepositoryTestSuite struct {
+	suite.Suite
+	db   *gorm.DB
+	repo *DomainRepository
+	ctx  context.Context
+}
+
+func (suite *DomainRepositoryTestSuite) SetupTest() {
+	cfg := config.Load()
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+
+	db, err := database.Connect(&cfg.Database)
+	suite.Require().NoError(err)
+
+	suite.db = db
+	suite.repo = NewDomainRepository(db)
+	suite.ctx = context.Background()
+
+	suite.db.Exec("DELETE FROM domains")
+}
+
+func (suite *DomainRepositoryTestSuite) TestSaveAndFindByHost() {
+	suite.NoError(suite.repo.Save(suite.ctx, &domain.Domain{Host: "go.example.com", OwnerID: "owner-1"}))
+
+	found, err := suite.repo.FindByHost(suite.ctx, "go.example.com")
+	suite.NoError(err)
+	suite.Equal("owner-1", found.OwnerID)
+
+	err = suite.repo.Save(suite.ctx, &domain.Domain{Host: "go.example.com"})
+	suite.ErrorIs(err, domain.ErrDomainExists)
+
+	_, err = suite.repo.FindByHost(suite.ctx, "other.example.com")
+	suite.ErrorIs(err, domain.ErrDomainNotFound)
+}
+
+func (suite *DomainRepositoryTestSuite) TestList() {
+	suite.NoError(suite.repo.Save(suite.ctx, &domain.Domain{Host: "b.example.com", OwnerID: "owner-1"}))
+	suite.NoError(suite.repo.Save(suite.ctx, &domain.Domain{Host: "a.example.com"}))
+
+	domains, err := suite.repo.List(suite.ctx)
+	suite.NoError(err)
+	suite.Len(domains, 2)
+	suite.Equal("a.example.com", domains[0].Host)
+	suite.Equal("b.example.com", domains[1].Host)
+}
+
+func TestDomainRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(DomainRepositoryTestSuite))
+}
@@ -0,0 +1,221 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// rollupLockKey serialises RollupHour across replicas via a Postgres
+	// transaction-level advisory lock.
+	rollupLockKey = 7263001
+
+	// referrerHostPattern extracts the host from a referrer URL. It is passed
+	// as a parameter because gorm treats '?' in query text as a placeholder.
+	referrerHostPattern = `^[A-Za-z][A-Za-z0-9+.-]*://([^/?#]+)`
+)
+
+type rollupStatement struct {
+	sql  string
+	args []interface{}
+}
+
+type StatsRepository struct {
+	db *gorm.DB
+}
+
+func NewStatsRepository(db *gorm.DB) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+func (r *StatsRepository) GetLinkStats(ctx context.Context, host, shortCode string, query domain.StatsQuery) (*domain.LinkStats, error) {
+	db := r.db.WithContext(ctx)
+	stats := &domain.LinkStats{
+		Domain:    host,
+		ShortCode: shortCode,
+		From:      query.From,
+		To:        query.To,
+		Interval:  query.Interval,
+	}
+
+	err := db.Model(&domain.ClickRollup{}).
+		Select("bucket_start AS start, clicks, unique_visitors").
+		Where("domain = ? AND short_code = ? AND granularity = ? AND bucket_start >= ? AND bucket_start < ?",
+			host, shortCode, string(query.Interval), query.From, query.To).
+		Order("bucket_start").
+		Scan(&stats.Series).Error
+	if err != nil {
+		return nil, err
+	}
+
+	hourFrom := domain.StatsIntervalHour.Truncate(query.From)
+	err = db.Model(&domain.ClickRollup{}).
+		Select("COALESCE(SUM(clicks), 0)").
+		Where("domain = ? AND short_code = ? AND granularity = ? AND bucket_start >= ? AND bucket_start < ?",
+			host, shortCode, string(domain.StatsIntervalHour), hourFrom, query.To).
+		Scan(&stats.TotalClicks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Visitors are deduplicated per day, so the range is widened to whole days.
+	dayFrom := domain.StatsIntervalDay.Truncate(query.From)
+	err = db.Model(&domain.ClickDailyVisitor{}).
+		Select("COUNT(DISTINCT ip_hash)").
+		Where("domain = ? AND short_code = ? AND day >= ? AND day < ?", host, shortCode, dayFrom, query.To).
+		Scan(&stats.UniqueVisitors).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.TopReferrers, err = r.topValues(ctx, host, shortCode, domain.StatsDimensionReferrer, hourFrom, query); err != nil {
+		return nil, err
+	}
+	if stats.TopUserAgents, err = r.topValues(ctx, host, shortCode, domain.StatsDimensionUserAgent, hourFrom, query); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (r *StatsRepository) topValues(ctx context.Context, host, shortCode, dimension string, from time.Time, query domain.StatsQuery) ([]domain.TopValue, error) {
+	values := make([]domain.TopValue, 0, query.TopLimit)
+	err := r.db.WithContext(ctx).Model(&domain.ClickDimensionRollup{}).
+		Select("value, SUM(clicks) AS clicks").
+		Where("domain = ? AND short_code = ? AND dimension = ? AND bucket_start >= ? AND bucket_start < ?",
+			host, shortCode, dimension, from, query.To).
+		Group("value").
+		Order("clicks DESC, value").
+		Limit(query.TopLimit).
+		Scan(&values).Error
+	return values, err
+}
+
+func (r *StatsRepository) RollupHour(ctx context.Context, hour time.Time) error {
+	hourStart := domain.StatsIntervalHour.Truncate(hour)
+	hourEnd := hourStart.Add(time.Hour)
+	dayStart := domain.StatsIntervalDay.Truncate(hourStart)
+	weekStart := domain.StatsIntervalWeek.Truncate(hourStart)
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", rollupLockKey).Error; err != nil {
+			return err
+		}
+
+		statements := []rollupStatement{
+			{
+				sql: `INSERT INTO click_rollups (domain, short_code, granularity, bucket_start, clicks, unique_visitors)
+					SELECT domain, short_code, 'hour', CAST(? AS timestamptz), COUNT(*), COUNT(DISTINCT NULLIF(ip_hash, ''))
+					FROM clicks WHERE clicked_at >= ? AND clicked_at < ?
+					GROUP BY domain, short_code
+					ON CONFLICT (domain, short_code, granularity, bucket_start)
+					DO UPDATE SET clicks = EXCLUDED.clicks, unique_visitors = EXCLUDED.unique_visitors`,
+				args: []interface{}{hourStart, hourStart, hourEnd},
+			},
+			{
+				sql: `INSERT INTO click_daily_visitors (domain, short_code, day, ip_hash)
+					SELECT DISTINCT domain, short_code, CAST(? AS timestamptz), ip_hash
+					FROM clicks WHERE clicked_at >= ? AND clicked_at < ? AND ip_hash <> ''
+					ON CONFLICT DO NOTHING`,
+				args: []interface{}{dayStart, hourStart, hourEnd},
+			},
+			{
+				sql: `INSERT INTO click_dimension_rollups (domain, short_code, dimension, bucket_start, value, clicks)
+					SELECT domain, short_code, 'referrer', CAST(? AS timestamptz),
+						LEFT(COALESCE(substring(referrer from CAST(? AS text)), ''), 512), COUNT(*)
+					FROM clicks WHERE clicked_at >= ? AND clicked_at < ?
+					GROUP BY 1, 2, 5
+					ON CONFLICT (domain, short_code, dimension, bucket_start, value)
+					DO UPDATE SET clicks = EXCLUDED.clicks`,
+				args: []interface{}{hourStart, referrerHostPattern, hourStart, hourEnd},
+			},
+			{
+				sql: `INSERT INTO click_dimension_rollups (domain, short_code, dimension, bucket_start, value, clicks)
+					SELECT domain, short_code, 'user_agent', CAST(? AS timestamptz), LEFT(COALESCE(user_agent, ''), 512), COUNT(*)
+					FROM clicks WHERE clicked_at >= ? AND clicked_at < ?
+					GROUP BY 1, 2, 5
+					ON CONFLICT (domain, short_code, dimension, bucket_start, value)
+					DO UPDATE SET clicks = EXCLUDED.clicks`,
+				args: []interface{}{hourStart, hourStart, hourEnd},
+			},
+			r.periodRollup(domain.StatsIntervalDay, dayStart, hourStart, hourEnd),
+			r.periodRollup(domain.StatsIntervalWeek, weekStart, hourStart, hourEnd),
+		}
+
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt.sql, stmt.args...).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// periodRollup rebuilds the day or week bucket starting at periodStart for
+// the links clicked during [hourStart, hourEnd). Clicks are summed from the
+// hourly rollups and visitors counted from the daily visitor sets.
+func (r *StatsRepository) periodRollup(interval domain.StatsInterval, periodStart, hourStart, hourEnd time.Time) rollupStatement {
+	periodEnd := interval.Next(periodStart)
+
+	return rollupStatement{
+		sql: `INSERT INTO click_rollups (domain, short_code, granularity, bucket_start, clicks, unique_visitors)
+			SELECT h.domain, h.short_code, CAST(? AS text), CAST(? AS timestamptz), SUM(h.clicks), COALESCE(MAX(v.visitors), 0)
+			FROM click_rollups h
+			LEFT JOIN (
+				SELECT domain, short_code, COUNT(DISTINCT ip_hash) AS visitors
+				FROM click_daily_visitors WHERE day >= ? AND day < ?
+				GROUP BY domain, short_code
+			) v ON v.domain = h.domain AND v.short_code = h.short_code
+			WHERE h.granularity = 'hour' AND h.bucket_start >= ? AND h.bucket_start < ?
+				AND (h.domain, h.short_code) IN (SELECT DISTINCT domain, short_code FROM clicks WHERE clicked_at >= ? AND clicked_at < ?)
+			GROUP BY h.domain, h.short_code
+			ON CONFLICT (domain, short_code, granularity, bucket_start)
+			DO UPDATE SET clicks = EXCLUDED.clicks, unique_visitors = EXCLUDED.unique_visitors`,
+		args: []interface{}{
+			string(interval), periodStart,
+			periodStart, periodEnd,
+			periodStart, periodEnd,
+			hourStart, hourEnd,
+		},
+	}
+}
+
+func (r *StatsRepository) EarliestClick(ctx context.Context) (time.Time, bool, error) {
+	var click domain.Click
+	result := r.db.WithContext(ctx).Order("clicked_at").Limit(1).Find(&click)
+	if result.Error != nil {
+		return time.Time{}, false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return time.Time{}, false, nil
+	}
+	return click.ClickedAt, true, nil
+}
+
+func (r *StatsRepository) GetCheckpoint(ctx context.Context, name string) (time.Time, bool, error) {
+	var checkpoint domain.RollupCheckpoint
+	result := r.db.WithContext(ctx).Where("name = ?", name).First(&checkpoint)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return time.Time{}, false, nil
+	}
+	if result.Error != nil {
+		return time.Time{}, false, result.Error
+	}
+
+	return checkpoint.ProcessedUntil, true, nil
+}
+
+func (r *StatsRepository) SaveCheckpoint(ctx context.Context, name string, processedUntil time.Time) error {
+	checkpoint := domain.RollupCheckpoint{Name: name, ProcessedUntil: processedUntil}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"processed_until"}),
+	}).Create(&checkpoint).Error
+}
@@ -0,0 +1,97 @@
+package gorm
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/pkg/config"
+	"github.com/mikiasyonas/url-shortener/pkg/database"
+	"gorm.io/gorm"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StatsRepositoryTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	repo   *StatsRepository
+	clicks *ClickRepository
+	ctx    context.Context
+}
+
+func (suite *StatsRepositoryTestSuite) SetupTest() {
+	cfg := config.Load()
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+
+	db, err := database.Connect(&cfg.Database)
+	suite.Require().NoError(err)
+
+	suite.db = db
+	suite.repo = NewStatsRepository(db)
+	suite.clicks = NewClickRepository(db)
+	suite.ctx = context.Background()
+
+	for _, table := range []string{"clicks", "click_rollups", "click_dimension_rollups", "click_daily_visitors", "rollup_checkpoints"} {
+		suite.db.Exec("DELETE FROM " + table)
+	}
+}
+
+func (suite *StatsRepositoryTestSuite) TestRollupHourAndGetLinkStats() {
+	hour := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	clicks := []*domain.Click{
+		domain.NewClick(domain.LinkRef{ShortCode: "abc123"}, "https://news.example/a?x=1", "agent-a", "en", hour.Add(5*time.Minute)),
+		domain.NewClick(domain.LinkRef{ShortCode: "abc123"}, "https://news.example/b", "agent-a", "en", hour.Add(10*time.Minute)),
+		domain.NewClick(domain.LinkRef{ShortCode: "abc123"}, "", "agent-b", "de", hour.Add(20*time.Minute)),
+	}
+	clicks[0].IPHash = "visitor-1"
+	clicks[1].IPHash = "visitor-1"
+	clicks[2].IPHash = "visitor-2"
+	suite.Require().NoError(suite.clicks.SaveBatch(suite.ctx, clicks))
+
+	suite.Require().NoError(suite.repo.RollupHour(suite.ctx, hour))
+	// Rolling up the same hour again must not double count.
+	suite.Require().NoError(suite.repo.RollupHour(suite.ctx, hour))
+
+	stats, err := suite.repo.GetLinkStats(suite.ctx, "", "abc123", domain.StatsQuery{
+		From:     hour.Truncate(24 * time.Hour),
+		To:       hour.Add(24 * time.Hour),
+		Interval: domain.StatsIntervalDay,
+		TopLimit: 5,
+	})
+	suite.NoError(err)
+
+	suite.Equal(int64(3), stats.TotalClicks)
+	suite.Equal(int64(2), stats.UniqueVisitors)
+	suite.Require().Len(stats.Series, 1)
+	suite.Equal(int64(3), stats.Series[0].Clicks)
+	suite.Equal(int64(2), stats.Series[0].UniqueVisitors)
+	suite.Require().NotEmpty(stats.TopReferrers)
+	suite.Equal(domain.TopValue{Value: "news.example", Clicks: 2}, stats.TopReferrers[0])
+	suite.Equal(domain.TopValue{Value: "agent-a", Clicks: 2}, stats.TopUserAgents[0])
+}
+
+func (suite *StatsRepositoryTestSuite) TestCheckpoint() {
+	_, ok, err := suite.repo.GetCheckpoint(suite.ctx, "click_rollups")
+	suite.NoError(err)
+	suite.False(ok)
+
+	processedUntil := time.Date(2026, 3, 2, 11, 0, 0, 0, time.UTC)
+	suite.NoError(suite.repo.SaveCheckpoint(suite.ctx, "click_rollups", processedUntil))
+	suite.NoError(suite.repo.SaveCheckpoint(suite.ctx, "click_rollups", processedUntil.Add(time.Hour)))
+
+	got, ok, err := suite.repo.GetCheckpoint(suite.ctx, "click_rollups")
+	suite.NoError(err)
+	suite.True(ok)
+	suite.True(processedUntil.Add(time.Hour).Equal(got))
+}
+
+func TestStatsRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(StatsRepositoryTestSuite))
+}
@@ -3,13 +3,19 @@ package gorm
 import (
 	"context"
 	"errors"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/mikiasyonas/url-shortener/internal/core/domain"
 	"github.com/mikiasyonas/url-shortener/pkg/database"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const clickBatchSize = 500
+
 type URLRepository struct {
 	db *gorm.DB
 }
@@ -18,24 +24,54 @@ func NewURLRepository(db *gorm.DB) *URLRepository {
 	return &URLRepository{db: db}
 }
 
+// sharedURLIndex keeps an owner from sharing two links to one destination.
+const sharedURLIndex = "idx_urls_shared_canonical_url"
+
 func (r *URLRepository) Save(ctx context.Context, url *domain.URL) error {
-	result := r.db.WithContext(ctx).Create(url)
-	if result.Error != nil {
-		if database.IsDuplicateKeyError(result.Error) {
-			return domain.ErrShortCodeTaken
-		}
+	return createError(r.db.WithContext(ctx).Create(url).Error)
+}
 
-		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
-			return domain.ErrShortCodeTaken
-		}
+// SaveShared inserts a shared link, or finds the one its owner already
+// shares, in a single statement so that concurrent callers agree on one.
+// The no-op update on conflict makes RETURNING yield the existing row, which
+// is told apart from the new one by its short code.
+func (r *URLRepository) SaveShared(ctx context.Context, url *domain.URL) (*domain.URL, error) {
+	saved := *url
+	err := r.db.WithContext(ctx).
+		Clauses(
+			clause.OnConflict{
+				Columns:   []clause.Column{{Name: "owner_id"}, {Name: "domain"}, {Name: "redirect_type"}, {Name: "canonical_url_hash"}},
+				DoUpdates: clause.AssignmentColumns([]string{"canonical_url_hash"}),
+			},
+			clause.Returning{},
+		).
+		Create(&saved).Error
+	if err != nil {
+		return nil, createError(err)
+	}
 
+	if saved.ShortCode != url.ShortCode {
+		return &saved, nil
 	}
-	return result.Error
+	*url = saved
+	return nil, nil
 }
 
-func (r *URLRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+// createError reports a link whose short code is in use as
+// domain.ErrShortCodeTaken.
+func createError(err error) error {
+	if err == nil || strings.Contains(err.Error(), sharedURLIndex) {
+		return err
+	}
+	if database.IsDuplicateKeyError(err) || errors.Is(err, gorm.ErrDuplicatedKey) {
+		return domain.ErrShortCodeTaken
+	}
+	return err
+}
+
+func (r *URLRepository) FindByShortCode(ctx context.Context, host, shortCode string) (*domain.URL, error) {
 	var url domain.URL
-	result := r.db.WithContext(ctx).Where("short_code = ?", shortCode).First(&url)
+	result := r.db.WithContext(ctx).Where("domain = ? AND short_code = ?", host, shortCode).First(&url)
 
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return nil, domain.ErrURLNotFound
@@ -47,9 +83,11 @@ func (r *URLRepository) FindByShortCode(ctx context.Context, shortCode string) (
 	return &url, nil
 }
 
-func (r *URLRepository) FindByOriginalURL(ctx context.Context, originalURL string) (*domain.URL, error) {
+func (r *URLRepository) FindByCanonicalURL(ctx context.Context, ownerID, host, canonicalURL string, redirectType int) (*domain.URL, error) {
 	var url domain.URL
-	result := r.db.WithContext(ctx).Where("original_url = ?", originalURL).First(&url)
+	result := r.db.WithContext(ctx).
+		Where("owner_id = ? AND domain = ? AND redirect_type = ? AND canonical_url_hash = ?", ownerID, host, redirectType, domain.HashURL(canonicalURL)).
+		First(&url)
 
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return nil, domain.ErrURLNotFound
@@ -61,19 +99,195 @@ func (r *URLRepository) FindByOriginalURL(ctx context.Context, originalURL strin
 	return &url, nil
 }
 
-func (r *URLRepository) Exists(ctx context.Context, shortCode string) (bool, error) {
+func (r *URLRepository) Exists(ctx context.Context, host, shortCode string) (bool, error) {
+	var count int64
+	result := r.db.WithContext(ctx).Model(&domain.URL{}).Where("domain = ? AND short_code = ?", host, shortCode).Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+func (r *URLRepository) ExistsOnAnyDomain(ctx context.Context, shortCode string) (bool, error) {
 	var count int64
-	result := r.db.WithContext(ctx).Model(&domain.URL{}).Where("short_code = ?", shortCode).Count(&count)
+	result := r.db.WithContext(ctx).Model(&domain.URL{}).Where("short_code = ?", shortCode).Limit(1).Count(&count)
 	if result.Error != nil {
 		return false, result.Error
 	}
 	return count > 0, nil
 }
 
-func (r *URLRepository) IncrementClickCount(ctx context.Context, shortCode string) error {
+func (r *URLRepository) IncrementClickCount(ctx context.Context, host, shortCode string) error {
 	result := r.db.WithContext(ctx).Model(&domain.URL{}).
-		Where("short_code = ?", shortCode).
+		Where("domain = ? AND short_code = ?", host, shortCode).
 		Update("click_count", gorm.Expr("click_count + ?", 1))
 
 	return result.Error
 }
+
+// AddClickCounts applies click deltas in batched UPDATE ... FROM (VALUES ...)
+// statements inside one transaction, so a failed flush can be retried without
+// double counting. Links are sorted so concurrent flushes lock rows in the
+// same order.
+func (r *URLRepository) AddClickCounts(ctx context.Context, counts map[domain.LinkRef]int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	links := make([]domain.LinkRef, 0, len(counts))
+	for link := range counts {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].Domain != links[j].Domain {
+			return links[i].Domain < links[j].Domain
+		}
+		return links[i].ShortCode < links[j].ShortCode
+	})
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(links); start += clickBatchSize {
+			end := min(start+clickBatchSize, len(links))
+
+			values := make([]string, 0, end-start)
+			args := make([]interface{}, 0, 3*(end-start))
+			for _, link := range links[start:end] {
+				values = append(values, "(CAST(? AS text), CAST(? AS text), CAST(? AS bigint))")
+				args = append(args, link.Domain, link.ShortCode, counts[link])
+			}
+
+			query := "UPDATE urls SET click_count = urls.click_count + v.delta " +
+				"FROM (VALUES " + strings.Join(values, ", ") + ") AS v(domain, short_code, delta) " +
+				"WHERE urls.domain = v.domain AND urls.short_code = v.short_code"
+
+			if err := tx.Exec(query, args...).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *URLRepository) Update(ctx context.Context, url *domain.URL) error {
+	result := r.db.WithContext(ctx).Model(&domain.URL{}).
+		Where("domain = ? AND short_code = ? AND owner_id = ?", url.Domain, url.ShortCode, url.OwnerID).
+		Updates(map[string]interface{}{
+			"original_url":       url.OriginalURL,
+			"canonical_url":      url.CanonicalURL,
+			"canonical_url_hash": url.CanonicalURLHash,
+			"expires_at":         url.ExpiresAt,
+			"redirect_type":      url.RedirectType,
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+	return nil
+}
+
+func (r *URLRepository) Delete(ctx context.Context, ownerID, host, shortCode string) error {
+	result := r.db.WithContext(ctx).Where("domain = ? AND short_code = ? AND owner_id = ?", host, shortCode, ownerID).Delete(&domain.URL{})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrURLNotFound
+	}
+	return nil
+}
+
+func (r *URLRepository) List(ctx context.Context, filter domain.ListFilter) (*domain.URLPage, error) {
+	query := r.db.WithContext(ctx).Model(&domain.URL{}).Where("owner_id = ?", filter.OwnerID)
+
+	if filter.Query != "" {
+		query = query.Where("original_url ILIKE ?", "%"+escapeLike(filter.Query)+"%")
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+
+	now := time.Now()
+	switch filter.Status {
+	case domain.LinkStatusActive:
+		query = query.Where("expires_at IS NULL OR expires_at > ?", now)
+	case domain.LinkStatusExpired:
+		query = query.Where("expires_at <= ?", now)
+	case domain.LinkStatusBroken:
+		query = query.Where("last_checked_at IS NOT NULL AND (last_check_status = 0 OR last_check_status >= 400)")
+	}
+
+	if filter.Cursor != nil {
+		query = query.Where("(created_at, id) < (?, ?)", filter.Cursor.CreatedAt, filter.Cursor.ID)
+	}
+
+	// Fetch one extra row to learn whether another page follows.
+	var urls []*domain.URL
+	result := query.Order("created_at DESC, id DESC").Limit(filter.Limit + 1).Find(&urls)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	page := &domain.URLPage{URLs: urls}
+	if len(urls) > filter.Limit {
+		page.URLs = urls[:filter.Limit]
+		last := page.URLs[len(page.URLs)-1]
+		page.NextCursor = &domain.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	return page, nil
+}
+
+// ShortCodes pages through the distinct codes of links created at or after
+// createdSince, in short code order, starting after the given code.
+func (r *URLRepository) ShortCodes(ctx context.Context, createdSince time.Time, after string, limit int) ([]string, error) {
+	query := r.db.WithContext(ctx).Model(&domain.URL{}).Where("short_code > ?", after)
+	if !createdSince.IsZero() {
+		query = query.Where("created_at >= ?", createdSince)
+	}
+
+	var codes []string
+	result := query.Distinct("short_code").Order("short_code").Limit(limit).Pluck("short_code", &codes)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return codes, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (r *URLRepository) ClaimLinksToCheck(ctx context.Context, now, nextCheckAt time.Time, limit int) ([]*domain.URL, error) {
+	var urls []*domain.URL
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE urls SET next_check_at = ?
+		WHERE id IN (
+			SELECT id FROM urls
+			WHERE (next_check_at IS NULL OR next_check_at <= ?)
+				AND (expires_at IS NULL OR expires_at > ?)
+			ORDER BY next_check_at NULLS FIRST, click_count DESC
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, nextCheckAt, now, now, limit).Scan(&urls).Error
+	if err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+func (r *URLRepository) RecordLinkCheck(ctx context.Context, id string, status int, checkedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.URL{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"last_check_status": status,
+			"last_checked_at":   checkedAt,
+		}).Error
+}
@@ -2,8 +2,11 @@ package gorm
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/mikiasyonas/url-shortener/internal/core/domain"
 	"github.com/mikiasyonas/url-shortener/pkg/config"
@@ -44,7 +47,7 @@ func (suite *URLRepositoryTestSuite) TestSaveAndFind() {
 	err = suite.repo.Save(suite.ctx, url)
 	suite.NoError(err)
 
-	found, err := suite.repo.FindByShortCode(suite.ctx, "abc123")
+	found, err := suite.repo.FindByShortCode(suite.ctx, "", "abc123")
 	suite.NoError(err)
 	suite.Equal(url.OriginalURL, found.OriginalURL)
 	suite.Equal(url.ShortCode, found.ShortCode)
@@ -61,23 +64,322 @@ func (suite *URLRepositoryTestSuite) TestSave_DuplicateShortCode() {
 	suite.ErrorIs(err, domain.ErrShortCodeTaken)
 }
 
+func (suite *URLRepositoryTestSuite) TestSave_SameShortCodeOnAnotherDomain() {
+	url1, _ := domain.NewURL("https://example.com", "abc123")
+	url2, _ := domain.NewURL("https://example.org", "abc123")
+	url2.Domain = "go.example.com"
+
+	suite.NoError(suite.repo.Save(suite.ctx, url1))
+	suite.NoError(suite.repo.Save(suite.ctx, url2))
+
+	found, err := suite.repo.FindByShortCode(suite.ctx, "go.example.com", "abc123")
+	suite.NoError(err)
+	suite.Equal("https://example.org", found.OriginalURL)
+
+	found, err = suite.repo.FindByShortCode(suite.ctx, "", "abc123")
+	suite.NoError(err)
+	suite.Equal("https://example.com", found.OriginalURL)
+}
+
+func (suite *URLRepositoryTestSuite) TestExistsOnAnyDomain() {
+	url, _ := domain.NewURL("https://example.org", "abc123")
+	url.Domain = "go.example.com"
+	suite.NoError(suite.repo.Save(suite.ctx, url))
+
+	exists, err := suite.repo.Exists(suite.ctx, "", "abc123")
+	suite.NoError(err)
+	suite.False(exists)
+
+	exists, err = suite.repo.ExistsOnAnyDomain(suite.ctx, "abc123")
+	suite.NoError(err)
+	suite.True(exists)
+
+	exists, err = suite.repo.ExistsOnAnyDomain(suite.ctx, "xyz789")
+	suite.NoError(err)
+	suite.False(exists)
+}
+
 func (suite *URLRepositoryTestSuite) TestFindByShortCode_NotFound() {
-	_, err := suite.repo.FindByShortCode(suite.ctx, "nonexistent")
+	_, err := suite.repo.FindByShortCode(suite.ctx, "", "nonexistent")
+	suite.ErrorIs(err, domain.ErrURLNotFound)
+}
+
+func (suite *URLRepositoryTestSuite) TestFindByCanonicalURL() {
+	url, _ := domain.NewURL("HTTPS://Example.com", "abc123")
+	url.OwnerID = "owner-1"
+	url.CanonicalURL = "https://example.com/"
+	url.Share()
+	suite.NoError(suite.repo.Save(suite.ctx, url))
+
+	found, err := suite.repo.FindByCanonicalURL(suite.ctx, "owner-1", "", "https://example.com/", domain.RedirectFound)
+	suite.NoError(err)
+	suite.Equal("HTTPS://Example.com", found.OriginalURL)
+
+	_, err = suite.repo.FindByCanonicalURL(suite.ctx, "owner-2", "", "https://example.com/", domain.RedirectFound)
+	suite.ErrorIs(err, domain.ErrURLNotFound)
+	_, err = suite.repo.FindByCanonicalURL(suite.ctx, "owner-1", "", "https://example.com/", domain.RedirectPermanent)
+	suite.ErrorIs(err, domain.ErrURLNotFound)
+}
+
+func (suite *URLRepositoryTestSuite) TestSave_OneSharedLinkPerDestination() {
+	url1, _ := domain.NewURL("https://example.com", "abc123")
+	url1.CanonicalURL = "https://example.com/"
+	url1.Share()
+	suite.NoError(suite.repo.Save(suite.ctx, url1))
+
+	// Links that are not shared may point at the same destination.
+	url2, _ := domain.NewURL("https://example.com", "xyz789")
+	url2.CanonicalURL = "https://example.com/"
+	suite.NoError(suite.repo.Save(suite.ctx, url2))
+
+	url3, _ := domain.NewURL("https://example.com", "def456")
+	url3.CanonicalURL = "https://example.com/"
+	url3.Share()
+	err := suite.repo.Save(suite.ctx, url3)
+	suite.Error(err)
+	suite.NotErrorIs(err, domain.ErrShortCodeTaken)
+
+	url1.Unshare()
+	suite.NoError(suite.repo.Update(suite.ctx, url1))
+	_, err = suite.repo.FindByCanonicalURL(suite.ctx, "", "", "https://example.com/", domain.RedirectFound)
 	suite.ErrorIs(err, domain.ErrURLNotFound)
 }
 
+func (suite *URLRepositoryTestSuite) TestSaveShared() {
+	url1, _ := domain.NewURL("https://example.com", "abc123")
+	url1.CanonicalURL = "https://example.com/"
+	url1.Share()
+	existing, err := suite.repo.SaveShared(suite.ctx, url1)
+	suite.NoError(err)
+	suite.Nil(existing)
+	suite.NotEmpty(url1.ID)
+
+	url2, _ := domain.NewURL("HTTPS://Example.com", "xyz789")
+	url2.CanonicalURL = "https://example.com/"
+	url2.Share()
+	existing, err = suite.repo.SaveShared(suite.ctx, url2)
+	suite.NoError(err)
+	suite.Require().NotNil(existing)
+	suite.Equal("abc123", existing.ShortCode)
+	suite.Equal("https://example.com", existing.OriginalURL)
+
+	_, err = suite.repo.FindByShortCode(suite.ctx, "", "xyz789")
+	suite.ErrorIs(err, domain.ErrURLNotFound)
+
+	url3, _ := domain.NewURL("https://example.org", "abc123")
+	url3.CanonicalURL = "https://example.org/"
+	url3.Share()
+	_, err = suite.repo.SaveShared(suite.ctx, url3)
+	suite.ErrorIs(err, domain.ErrShortCodeTaken)
+}
+
+func (suite *URLRepositoryTestSuite) TestSaveShared_Concurrent() {
+	const callers = 10
+	codes := make(chan string, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			url, _ := domain.NewURL("https://example.com", fmt.Sprintf("code%02d", i))
+			url.CanonicalURL = "https://example.com/"
+			url.Share()
+
+			existing, err := suite.repo.SaveShared(suite.ctx, url)
+			suite.NoError(err)
+			if existing != nil {
+				url = existing
+			}
+			codes <- url.ShortCode
+		}(i)
+	}
+	wg.Wait()
+	close(codes)
+
+	shared := map[string]bool{}
+	for code := range codes {
+		shared[code] = true
+	}
+	suite.Len(shared, 1)
+
+	var count int64
+	suite.db.Model(&domain.URL{}).Count(&count)
+	suite.Equal(int64(1), count)
+}
+
 func (suite *URLRepositoryTestSuite) TestIncrementClickCount() {
 	url, _ := domain.NewURL("https://example.com", "abc123")
 	suite.repo.Save(suite.ctx, url)
 
-	err := suite.repo.IncrementClickCount(suite.ctx, "abc123")
+	err := suite.repo.IncrementClickCount(suite.ctx, "", "abc123")
 	suite.NoError(err)
 
-	updated, err := suite.repo.FindByShortCode(suite.ctx, "abc123")
+	updated, err := suite.repo.FindByShortCode(suite.ctx, "", "abc123")
 	suite.NoError(err)
 	suite.Equal(int64(1), updated.ClickCount)
 }
 
+func (suite *URLRepositoryTestSuite) TestAddClickCounts() {
+	url1, _ := domain.NewURL("https://example.com", "abc123")
+	url2, _ := domain.NewURL("https://example.org", "xyz789")
+	suite.repo.Save(suite.ctx, url1)
+	suite.repo.Save(suite.ctx, url2)
+
+	err := suite.repo.AddClickCounts(suite.ctx, map[domain.LinkRef]int64{
+		{ShortCode: "abc123"}:                           5,
+		{ShortCode: "xyz789"}:                           2,
+		{ShortCode: "missing"}:                          1,
+		{Domain: "go.example.com", ShortCode: "abc123"}: 7,
+	})
+	suite.NoError(err)
+
+	updated, err := suite.repo.FindByShortCode(suite.ctx, "", "abc123")
+	suite.NoError(err)
+	suite.Equal(int64(5), updated.ClickCount)
+
+	updated, err = suite.repo.FindByShortCode(suite.ctx, "", "xyz789")
+	suite.NoError(err)
+	suite.Equal(int64(2), updated.ClickCount)
+}
+
+func (suite *URLRepositoryTestSuite) TestUpdate() {
+	url, _ := domain.NewURL("https://example.com", "abc123")
+	suite.repo.Save(suite.ctx, url)
+
+	url.OriginalURL = "https://example.org"
+	err := suite.repo.Update(suite.ctx, url)
+	suite.NoError(err)
+
+	updated, err := suite.repo.FindByShortCode(suite.ctx, "", "abc123")
+	suite.NoError(err)
+	suite.Equal("https://example.org", updated.OriginalURL)
+}
+
+func (suite *URLRepositoryTestSuite) TestDelete() {
+	url, _ := domain.NewURL("https://example.com", "abc123")
+	url.OwnerID = "owner-1"
+	suite.repo.Save(suite.ctx, url)
+
+	err := suite.repo.Delete(suite.ctx, "owner-2", "", "abc123")
+	suite.ErrorIs(err, domain.ErrURLNotFound)
+
+	err = suite.repo.Delete(suite.ctx, "owner-1", "", "abc123")
+	suite.NoError(err)
+
+	_, err = suite.repo.FindByShortCode(suite.ctx, "", "abc123")
+	suite.ErrorIs(err, domain.ErrURLNotFound)
+
+	err = suite.repo.Delete(suite.ctx, "owner-1", "", "abc123")
+	suite.ErrorIs(err, domain.ErrURLNotFound)
+}
+
+func (suite *URLRepositoryTestSuite) TestList_Pagination() {
+	for i, code := range []string{"code01", "code02", "code03"} {
+		url, _ := domain.NewURL("https://example.com/"+code, code)
+		url.CreatedAt = time.Now().Add(time.Duration(i) * time.Second)
+		suite.NoError(suite.repo.Save(suite.ctx, url))
+	}
+
+	page, err := suite.repo.List(suite.ctx, domain.ListFilter{Limit: 2})
+	suite.NoError(err)
+	suite.Len(page.URLs, 2)
+	suite.Equal("code03", page.URLs[0].ShortCode)
+	suite.NotNil(page.NextCursor)
+
+	page, err = suite.repo.List(suite.ctx, domain.ListFilter{Limit: 2, Cursor: page.NextCursor})
+	suite.NoError(err)
+	suite.Len(page.URLs, 1)
+	suite.Equal("code01", page.URLs[0].ShortCode)
+	suite.Nil(page.NextCursor)
+}
+
+func (suite *URLRepositoryTestSuite) TestList_ScopedToOwner() {
+	mine, _ := domain.NewURL("https://example.com/mine", "mine01")
+	mine.OwnerID = "owner-1"
+	theirs, _ := domain.NewURL("https://example.com/theirs", "their1")
+	theirs.OwnerID = "owner-2"
+	suite.NoError(suite.repo.Save(suite.ctx, mine))
+	suite.NoError(suite.repo.Save(suite.ctx, theirs))
+
+	page, err := suite.repo.List(suite.ctx, domain.ListFilter{OwnerID: "owner-1", Limit: 10})
+	suite.NoError(err)
+	suite.Len(page.URLs, 1)
+	suite.Equal("mine01", page.URLs[0].ShortCode)
+}
+
+func (suite *URLRepositoryTestSuite) TestShortCodes() {
+	for i, code := range []string{"code03", "code01", "code02"} {
+		url, _ := domain.NewURL("https://example.com/"+code, code)
+		url.CreatedAt = time.Now().Add(time.Duration(i-3) * time.Hour)
+		suite.NoError(suite.repo.Save(suite.ctx, url))
+	}
+
+	codes, err := suite.repo.ShortCodes(suite.ctx, time.Time{}, "", 2)
+	suite.NoError(err)
+	suite.Equal([]string{"code01", "code02"}, codes)
+
+	codes, err = suite.repo.ShortCodes(suite.ctx, time.Time{}, "code02", 2)
+	suite.NoError(err)
+	suite.Equal([]string{"code03"}, codes)
+
+	codes, err = suite.repo.ShortCodes(suite.ctx, time.Now().Add(-90*time.Minute), "", 10)
+	suite.NoError(err)
+	suite.Equal([]string{"code02"}, codes)
+}
+
+func (suite *URLRepositoryTestSuite) TestClaimLinksToCheck() {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+
+	popular, _ := domain.NewURL("https://example.com/popular", "popular")
+	popular.ClickCount = 100
+	quiet, _ := domain.NewURL("https://example.com/quiet", "quiet1")
+	expired, _ := domain.NewURL("https://example.com/expired", "expired")
+	expired.ExpiresAt = &past
+	for _, url := range []*domain.URL{quiet, popular, expired} {
+		suite.NoError(suite.repo.Save(suite.ctx, url))
+	}
+
+	next := now.Add(24 * time.Hour)
+	claimed, err := suite.repo.ClaimLinksToCheck(suite.ctx, now, next, 10)
+	suite.NoError(err)
+	suite.Require().Len(claimed, 2)
+	suite.Equal("popular", claimed[0].ShortCode)
+	suite.Equal("quiet1", claimed[1].ShortCode)
+
+	claimed, err = suite.repo.ClaimLinksToCheck(suite.ctx, now, next, 10)
+	suite.NoError(err)
+	suite.Empty(claimed, "claimed links are not due again until their next check")
+
+	claimed, err = suite.repo.ClaimLinksToCheck(suite.ctx, next, next.Add(24*time.Hour), 10)
+	suite.NoError(err)
+	suite.Len(claimed, 2)
+}
+
+func (suite *URLRepositoryTestSuite) TestRecordLinkCheck_ListBroken() {
+	ok, _ := domain.NewURL("https://example.com/ok", "ok0001")
+	gone, _ := domain.NewURL("https://example.com/gone", "gone01")
+	unchecked, _ := domain.NewURL("https://example.com/new", "new001")
+	for _, url := range []*domain.URL{ok, gone, unchecked} {
+		suite.NoError(suite.repo.Save(suite.ctx, url))
+	}
+
+	checkedAt := time.Now().UTC().Truncate(time.Second)
+	suite.NoError(suite.repo.RecordLinkCheck(suite.ctx, ok.ID, 200, checkedAt))
+	suite.NoError(suite.repo.RecordLinkCheck(suite.ctx, gone.ID, 404, checkedAt))
+
+	found, err := suite.repo.FindByShortCode(suite.ctx, "", "gone01")
+	suite.NoError(err)
+	suite.Equal(404, found.LastCheckStatus)
+	suite.Require().NotNil(found.LastCheckedAt)
+	suite.True(found.LastCheckedAt.Equal(checkedAt))
+
+	page, err := suite.repo.List(suite.ctx, domain.ListFilter{Status: domain.LinkStatusBroken, Limit: 10})
+	suite.NoError(err)
+	suite.Require().Len(page.URLs, 1)
+	suite.Equal("gone01", page.URLs[0].ShortCode)
+}
+
 func TestURLRepositoryTestSuite(t *testing.T) {
 	suite.Run(t, new(URLRepositoryTestSuite))
 }
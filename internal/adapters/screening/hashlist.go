@@ -0,0 +1,141 @@
+package screening
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+)
+
+const (
+	minPrefixBytes = 4
+
+	// Safe Browsing builds host suffixes from a host's last five
+	// components and looks up at most four path prefixes.
+	maxHostComponents = 5
+	maxPathPrefixes   = 4
+)
+
+// HashPrefixList rejects destinations matching a list of unsafe URL hash
+// prefixes, in the manner of the Safe Browsing API. A destination such as
+// http://a.b.example.com/1/2.html?p=1 is expanded into expressions like
+// "a.b.example.com/1/2.html?p=1", "b.example.com/1/" and "example.com/",
+// and is rejected when the SHA-256 hash of any of them starts with a listed
+// prefix.
+type HashPrefixList struct {
+	// prefixes holds the listed prefixes by their length in bytes.
+	prefixes map[int]map[string]struct{}
+}
+
+func NewHashPrefixList(prefixes [][]byte) (*HashPrefixList, error) {
+	l := &HashPrefixList{prefixes: make(map[int]map[string]struct{})}
+	for _, prefix := range prefixes {
+		if len(prefix) < minPrefixBytes || len(prefix) > sha256.Size {
+			return nil, fmt.Errorf("hash prefix must be %d to %d bytes, got %d", minPrefixBytes, sha256.Size, len(prefix))
+		}
+		if l.prefixes[len(prefix)] == nil {
+			l.prefixes[len(prefix)] = make(map[string]struct{})
+		}
+		l.prefixes[len(prefix)][string(prefix)] = struct{}{}
+	}
+	return l, nil
+}
+
+// LoadHashPrefixList reads hex-encoded hash prefixes, one per line.
+func LoadHashPrefixList(path string) (*HashPrefixList, error) {
+	entries, err := readList(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hash prefix list: %w", err)
+	}
+
+	prefixes := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		prefix, err := hex.DecodeString(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hash prefix %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return NewHashPrefixList(prefixes)
+}
+
+func (l *HashPrefixList) Screen(ctx context.Context, target *url.URL) error {
+	for _, expression := range expressions(target) {
+		sum := sha256.Sum256([]byte(expression))
+		for length, set := range l.prefixes {
+			if _, ok := set[string(sum[:length])]; ok {
+				return domain.RejectURL("destination is on the unsafe URL list")
+			}
+		}
+	}
+	return nil
+}
+
+// expressions returns the host suffix and path prefix combinations that
+// are looked up for target.
+func expressions(target *url.URL) []string {
+	var result []string
+	for _, host := range hostSuffixes(hostname(target)) {
+		for _, path := range pathPrefixes(target) {
+			result = append(result, host+path)
+		}
+	}
+	return result
+}
+
+// hostSuffixes returns the host itself and the suffixes formed from its
+// last components, down to but excluding the top-level domain. IP
+// addresses are only looked up as is.
+func hostSuffixes(host string) []string {
+	suffixes := []string{host}
+	if net.ParseIP(host) != nil {
+		return suffixes
+	}
+
+	labels := strings.Split(host, ".")
+	start := len(labels) - maxHostComponents
+	if start < 1 {
+		start = 1
+	}
+	for i := start; i < len(labels)-1; i++ {
+		suffixes = append(suffixes, strings.Join(labels[i:], "."))
+	}
+	return suffixes
+}
+
+// pathPrefixes returns the path with and without its query, the root and
+// the directories leading up to the path.
+func pathPrefixes(target *url.URL) []string {
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	var prefixes []string
+	seen := make(map[string]bool)
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			prefixes = append(prefixes, p)
+		}
+	}
+
+	if target.RawQuery != "" {
+		add(path + "?" + target.RawQuery)
+	}
+	add(path)
+	add("/")
+
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	dir := "/"
+	for i := 0; i < len(segments)-1 && i < maxPathPrefixes-1; i++ {
+		dir += segments[i] + "/"
+		add(dir)
+	}
+	return prefixes
+}
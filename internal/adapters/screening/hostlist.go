@@ -0,0 +1,75 @@
+package screening
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+)
+
+// HostList rejects destinations on blocked domains and, when it has an
+// allowlist, on every domain not on it. A listed domain covers its
+// subdomains.
+type HostList struct {
+	blocked map[string]struct{}
+	allowed map[string]struct{}
+}
+
+func NewHostList(blocked, allowed []string) *HostList {
+	return &HostList{
+		blocked: domainSet(blocked),
+		allowed: domainSet(allowed),
+	}
+}
+
+// LoadHostList reads the blocklist and allowlist from files holding one
+// domain per line. An empty path leaves that list empty.
+func LoadHostList(blocklistPath, allowlistPath string) (*HostList, error) {
+	var lists [2][]string
+	for i, path := range []string{blocklistPath, allowlistPath} {
+		if path == "" {
+			continue
+		}
+		entries, err := readList(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read host list: %w", err)
+		}
+		lists[i] = entries
+	}
+	return NewHostList(lists[0], lists[1]), nil
+}
+
+func (l *HostList) Screen(ctx context.Context, target *url.URL) error {
+	host := hostname(target)
+	if matchDomain(l.blocked, host) {
+		return domain.RejectURL("destination domain is blocklisted")
+	}
+	if len(l.allowed) > 0 && !matchDomain(l.allowed, host) {
+		return domain.RejectURL("destination domain is not on the allowlist")
+	}
+	return nil
+}
+
+func domainSet(domains []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(domains))
+	for _, d := range domains {
+		set[strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d)), ".")] = struct{}{}
+	}
+	return set
+}
+
+// matchDomain reports whether host or one of its parent domains is in set.
+func matchDomain(set map[string]struct{}, host string) bool {
+	for {
+		if _, ok := set[host]; ok {
+			return true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			return false
+		}
+		host = parent
+	}
+}
@@ -0,0 +1,70 @@
+package screening
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/pkg/netaddr"
+)
+
+// Resolver looks up the addresses of a host; *net.Resolver satisfies it.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// numericLabel matches a last label that makes browsers read a host as an
+// IPv4 address, as in http://2130706433/ or http://0x7f.1/.
+var numericLabel = regexp.MustCompile(`^(0x[0-9a-f]*|[0-9]+)$`)
+
+// PrivateHostScreener rejects destinations given as IP addresses and those
+// on local networks: single-label names, localhost and the .localhost,
+// .local and .internal domains. With a resolver it also rejects names that
+// resolve to loopback, private or link-local addresses. Names that do not
+// resolve are let through, as links are often created before their site
+// goes live.
+type PrivateHostScreener struct {
+	resolver Resolver
+}
+
+func NewPrivateHostScreener(resolver Resolver) *PrivateHostScreener {
+	return &PrivateHostScreener{resolver: resolver}
+}
+
+func (s *PrivateHostScreener) Screen(ctx context.Context, target *url.URL) error {
+	host := hostname(target)
+
+	labels := strings.Split(host, ".")
+	if net.ParseIP(host) != nil || numericLabel.MatchString(labels[len(labels)-1]) {
+		return domain.RejectURL("destination is an IP address")
+	}
+	if len(labels) == 1 || isLocalName(host) {
+		return domain.RejectURL("destination is a private or local host")
+	}
+
+	if s.resolver == nil {
+		return nil
+	}
+	addrs, err := s.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if netaddr.IsPrivate(addr.IP) {
+			return domain.RejectURL("destination is a private or local host")
+		}
+	}
+	return nil
+}
+
+func isLocalName(host string) bool {
+	for _, suffix := range []string{".localhost", ".local", ".internal"} {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
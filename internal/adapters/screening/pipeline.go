@@ -0,0 +1,55 @@
+// Package screening vets link destinations before they are shortened.
+package screening
+
+import (
+	"bufio"
+	"context"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+// Pipeline runs screeners in order and stops at the first that rejects the
+// destination or fails.
+type Pipeline []ports.URLScreener
+
+func NewPipeline(screeners ...ports.URLScreener) Pipeline {
+	return Pipeline(screeners)
+}
+
+func (p Pipeline) Screen(ctx context.Context, target *url.URL) error {
+	for _, screener := range p {
+		if err := screener.Screen(ctx, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hostname returns target's host without port, brackets or trailing dot.
+func hostname(target *url.URL) string {
+	return strings.TrimSuffix(strings.ToLower(target.Hostname()), ".")
+}
+
+// readList reads a list file with one entry per line, skipping blank lines
+// and lines starting with #.
+func readList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, scanner.Err()
+}
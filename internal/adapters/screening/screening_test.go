@@ -0,0 +1,187 @@
+package screening_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mikiasyonas/url-shortener/internal/adapters/screening"
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func screen(t *testing.T, screener ports.URLScreener, rawURL string) error {
+	t.Helper()
+	target, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	return screener.Screen(context.Background(), target)
+}
+
+func writeList(t *testing.T, lines string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "list.txt")
+	require.NoError(t, os.WriteFile(path, []byte(lines), 0o600))
+	return path
+}
+
+func TestHostList(t *testing.T) {
+	blocklist := writeList(t, "# phishing\nevil.example\n\nBAD.example.\n")
+	list, err := screening.LoadHostList(blocklist, "")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, screen(t, list, "https://evil.example/login"), domain.ErrURLRejected)
+	assert.ErrorIs(t, screen(t, list, "https://login.evil.example/"), domain.ErrURLRejected, "subdomains are covered")
+	assert.ErrorIs(t, screen(t, list, "https://bad.example:8443/"), domain.ErrURLRejected)
+	assert.NoError(t, screen(t, list, "https://notevil.example/"))
+	assert.NoError(t, screen(t, list, "https://example.com/"))
+}
+
+func TestHostList_Allowlist(t *testing.T) {
+	list := screening.NewHostList([]string{"blocked.example.com"}, []string{"example.com"})
+
+	assert.NoError(t, screen(t, list, "https://www.example.com/"))
+	assert.ErrorIs(t, screen(t, list, "https://blocked.example.com/"), domain.ErrURLRejected, "the blocklist wins")
+
+	var rejection *domain.URLRejectedError
+	require.ErrorAs(t, screen(t, list, "https://example.org/"), &rejection)
+	assert.Equal(t, "destination domain is not on the allowlist", rejection.Reason)
+}
+
+func TestHostList_MissingFile(t *testing.T) {
+	_, err := screening.LoadHostList(filepath.Join(t.TempDir(), "missing"), "")
+	assert.Error(t, err)
+}
+
+func TestHashPrefixList(t *testing.T) {
+	// The list holds the prefix of one expression generated for the URL
+	// rather than of the URL itself.
+	sum := sha256.Sum256([]byte("example.com/1/"))
+	list, err := screening.LoadHashPrefixList(writeList(t, "# unsafe\n"+hex.EncodeToString(sum[:4])+"\n"))
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, screen(t, list, "http://a.b.example.com/1/2.html?param=1"), domain.ErrURLRejected)
+	assert.ErrorIs(t, screen(t, list, "https://EXAMPLE.com/1/"), domain.ErrURLRejected)
+	assert.NoError(t, screen(t, list, "http://example.com/2/1/"))
+	assert.NoError(t, screen(t, list, "http://example.org/1/"))
+}
+
+func TestHashPrefixList_FullHash(t *testing.T) {
+	sum := sha256.Sum256([]byte("phish.example/login?next=1"))
+	list, err := screening.NewHashPrefixList([][]byte{sum[:]})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, screen(t, list, "https://phish.example/login?next=1"), domain.ErrURLRejected)
+	assert.NoError(t, screen(t, list, "https://phish.example/login"))
+}
+
+func TestHashPrefixList_InvalidPrefix(t *testing.T) {
+	_, err := screening.LoadHashPrefixList(writeList(t, "abc\n"))
+	assert.Error(t, err)
+
+	_, err = screening.LoadHashPrefixList(writeList(t, "0102\n"))
+	assert.Error(t, err, "prefixes are at least four bytes")
+}
+
+type fakeResolver map[string][]net.IPAddr
+
+func (r fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+func TestPrivateHostScreener(t *testing.T) {
+	screener := screening.NewPrivateHostScreener(fakeResolver{
+		"public.example":   {{IP: net.ParseIP("93.184.216.34")}},
+		"rebind.example":   {{IP: net.ParseIP("93.184.216.34")}, {IP: net.ParseIP("10.0.0.7")}},
+		"metadata.example": {{IP: net.ParseIP("169.254.169.254")}},
+		"cgnat.example":    {{IP: net.ParseIP("100.64.1.1")}},
+	})
+
+	for _, rawURL := range []string{
+		"http://127.0.0.1/",
+		"http://93.184.216.34/",
+		"http://[::1]:8080/",
+		"http://2130706433/",
+		"http://0x7f.1/",
+		"http://localhost:3000/",
+		"http://app.localhost/",
+		"http://printer.local/",
+		"http://db.internal/",
+		"http://intranet/",
+		"http://rebind.example/",
+		"http://metadata.example/",
+		"http://cgnat.example/",
+	} {
+		assert.ErrorIs(t, screen(t, screener, rawURL), domain.ErrURLRejected, rawURL)
+	}
+
+	assert.NoError(t, screen(t, screener, "https://public.example/"))
+	assert.NoError(t, screen(t, screener, "https://unresolvable.example/"), "names that do not resolve are let through")
+}
+
+func TestPrivateHostScreener_WithoutResolver(t *testing.T) {
+	screener := screening.NewPrivateHostScreener(nil)
+
+	assert.ErrorIs(t, screen(t, screener, "http://10.0.0.1/"), domain.ErrURLRejected)
+	assert.NoError(t, screen(t, screener, "https://example.com/"))
+}
+
+type fakeDomains map[string]bool
+
+func (d fakeDomains) Resolve(host string) string {
+	if d[host] {
+		return host
+	}
+	return domain.DefaultDomain
+}
+
+func (d fakeDomains) Authorize(ctx context.Context, ownerID, host string) (string, error) {
+	return "", nil
+}
+
+func (d fakeDomains) Register(ctx context.Context, host, ownerID string) (*domain.Domain, error) {
+	return nil, nil
+}
+
+func (d fakeDomains) List(ctx context.Context, ownerID string) ([]*domain.Domain, error) {
+	return nil, nil
+}
+
+func TestSelfLinkScreener(t *testing.T) {
+	screener := screening.NewSelfLinkScreener("https://sho.rt/", fakeDomains{"go.example.com": true})
+
+	var rejection *domain.URLRejectedError
+	require.ErrorAs(t, screen(t, screener, "https://SHO.RT/abc123"), &rejection)
+	assert.Equal(t, "destination points back at this shortener", rejection.Reason)
+
+	assert.ErrorIs(t, screen(t, screener, "http://sho.rt:80/abc123"), domain.ErrURLRejected)
+	assert.ErrorIs(t, screen(t, screener, "https://go.example.com/promo"), domain.ErrURLRejected)
+	assert.NoError(t, screen(t, screener, "https://example.com/sho.rt"))
+}
+
+func TestPipeline_StopsAtFirstRejection(t *testing.T) {
+	pipeline := screening.NewPipeline(
+		screening.NewHostList([]string{"evil.example"}, nil),
+		screening.NewPrivateHostScreener(nil),
+	)
+
+	var rejection *domain.URLRejectedError
+	require.ErrorAs(t, screen(t, pipeline, "http://evil.example/"), &rejection)
+	assert.Equal(t, "destination domain is blocklisted", rejection.Reason)
+
+	require.ErrorAs(t, screen(t, pipeline, "http://127.0.0.1/"), &rejection)
+	assert.Equal(t, "destination is an IP address", rejection.Reason)
+
+	assert.NoError(t, screen(t, pipeline, "https://example.com/"))
+}
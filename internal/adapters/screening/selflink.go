@@ -0,0 +1,33 @@
+package screening
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+// SelfLinkScreener rejects destinations on the shortener's own hosts, which
+// would redirect back into it and possibly loop: the host of the base URL
+// and, given domains, every registered branded domain.
+type SelfLinkScreener struct {
+	baseHost string
+	domains  ports.DomainService
+}
+
+func NewSelfLinkScreener(baseURL string, domains ports.DomainService) *SelfLinkScreener {
+	s := &SelfLinkScreener{domains: domains}
+	if base, err := url.Parse(baseURL); err == nil {
+		s.baseHost = hostname(base)
+	}
+	return s
+}
+
+func (s *SelfLinkScreener) Screen(ctx context.Context, target *url.URL) error {
+	host := hostname(target)
+	if host == s.baseHost || (s.domains != nil && s.domains.Resolve(host) != domain.DefaultDomain) {
+		return domain.RejectURL("destination points back at this shortener")
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+// Package sequence allocates unique, increasing IDs for counter-based short
+// codes.
+package sequence
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// PostgresSequence is the database sequence that backs PostgresAllocator.
+const PostgresSequence = "short_code_ids"
+
+// PostgresAllocator draws IDs from a Postgres sequence. The IDs of one block
+// are unique but, with several instances allocating at once, not necessarily
+// contiguous.
+type PostgresAllocator struct {
+	db *gorm.DB
+}
+
+func NewPostgresAllocator(db *gorm.DB) *PostgresAllocator {
+	return &PostgresAllocator{db: db}
+}
+
+func (a *PostgresAllocator) Allocate(ctx context.Context, n int) ([]uint64, error) {
+	var ids []uint64
+	err := a.db.WithContext(ctx).
+		Raw("SELECT nextval(?::regclass) FROM generate_series(1, ?)", PostgresSequence, n).
+		Scan(&ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate ids: %w", err)
+	}
+	return ids, nil
+}
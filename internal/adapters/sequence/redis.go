@@ -0,0 +1,34 @@
+package sequence
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKey = "shortcode:next_id"
+
+// RedisAllocator reserves ranges of IDs with INCRBY. Redis must persist the
+// counter: if it is lost, IDs restart and new codes collide with issued
+// ones until the counter passes them again.
+type RedisAllocator struct {
+	client *redis.Client
+}
+
+func NewRedisAllocator(client *redis.Client) *RedisAllocator {
+	return &RedisAllocator{client: client}
+}
+
+func (a *RedisAllocator) Allocate(ctx context.Context, n int) ([]uint64, error) {
+	last, err := a.client.IncrBy(ctx, redisKey, int64(n)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate ids: %w", err)
+	}
+
+	ids := make([]uint64, n)
+	for i := range ids {
+		ids[i] = uint64(last) - uint64(n) + 1 + uint64(i)
+	}
+	return ids, nil
+}
@@ -0,0 +1,41 @@
+package sequence_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mikiasyonas/url-shortener/internal/adapters/sequence"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisAllocator_AllocatesDisjointRanges(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	a := sequence.NewRedisAllocator(client)
+	b := sequence.NewRedisAllocator(client)
+	ctx := context.Background()
+
+	first, err := a.Allocate(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3}, first)
+
+	second, err := b.Allocate(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{4, 5}, second)
+}
+
+func TestRedisAllocator_Unavailable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	mr.Close()
+
+	_, err := sequence.NewRedisAllocator(client).Allocate(context.Background(), 3)
+	assert.Error(t, err)
+}
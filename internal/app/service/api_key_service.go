@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+const (
+	apiKeyPrefix      = "usk_"
+	apiKeySecretBytes = 32
+
+	// apiKeyDisplayLength is how much of a key is kept in clear so that
+	// owners can tell their keys apart.
+	apiKeyDisplayLength = len(apiKeyPrefix) + 8
+)
+
+type apiKeyService struct {
+	repo ports.APIKeyRepository
+}
+
+func NewAPIKeyService(repo ports.APIKeyRepository) *apiKeyService {
+	return &apiKeyService{repo: repo}
+}
+
+func (s *apiKeyService) Create(ctx context.Context, ownerID, name string) (*domain.APIKey, string, error) {
+	key, err := domain.NewAPIKey(ownerID, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	raw := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	key.Prefix = secret[:apiKeyDisplayLength]
+	key.KeyHash = hashAPIKey(secret)
+
+	if err := s.repo.Save(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("failed to save API key: %w", err)
+	}
+
+	return key, secret, nil
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, secret string) (*domain.APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.FindByHash(ctx, hashAPIKey(secret))
+	if errors.Is(err, domain.ErrAPIKeyNotFound) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.IsRevoked() {
+		return nil, domain.ErrInvalidAPIKey
+	}
+
+	return key, nil
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, id string) error {
+	return s.repo.Revoke(ctx, id, time.Now())
+}
+
+func (s *apiKeyService) List(ctx context.Context, ownerID string) ([]*domain.APIKey, error) {
+	return s.repo.List(ctx, ownerID)
+}
+
+// hashAPIKey returns the hex SHA-256 of a key. Keys carry 256 bits of
+// randomness, so a fast unsalted hash is sufficient for lookup.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
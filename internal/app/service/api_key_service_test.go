@@ -0,0 +1,99 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/app/service"
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAPIKeyRepository struct {
+	byHash map[string]*domain.APIKey
+}
+
+func newFakeAPIKeyRepository() *fakeAPIKeyRepository {
+	return &fakeAPIKeyRepository{byHash: make(map[string]*domain.APIKey)}
+}
+
+func (r *fakeAPIKeyRepository) Save(ctx context.Context, key *domain.APIKey) error {
+	key.ID = "key-" + key.Prefix
+	r.byHash[key.KeyHash] = key
+	return nil
+}
+
+func (r *fakeAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	key, ok := r.byHash[keyHash]
+	if !ok {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func (r *fakeAPIKeyRepository) List(ctx context.Context, ownerID string) ([]*domain.APIKey, error) {
+	var keys []*domain.APIKey
+	for _, key := range r.byHash {
+		if key.OwnerID == ownerID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *fakeAPIKeyRepository) Revoke(ctx context.Context, id string, revokedAt time.Time) error {
+	for _, key := range r.byHash {
+		if key.ID == id {
+			key.RevokedAt = &revokedAt
+			return nil
+		}
+	}
+	return domain.ErrAPIKeyNotFound
+}
+
+func TestAPIKeyService_CreateAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeAPIKeyRepository()
+	keys := service.NewAPIKeyService(repo)
+
+	key, secret, err := keys.Create(ctx, "owner-1", "ci")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(secret, key.Prefix))
+	assert.NotContains(t, key.KeyHash, secret)
+	assert.Len(t, key.KeyHash, 64)
+
+	authenticated, err := keys.Authenticate(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, "owner-1", authenticated.OwnerID)
+
+	_, err = keys.Authenticate(ctx, secret+"x")
+	assert.ErrorIs(t, err, domain.ErrInvalidAPIKey)
+
+	_, err = keys.Authenticate(ctx, "not-a-key")
+	assert.ErrorIs(t, err, domain.ErrInvalidAPIKey)
+}
+
+func TestAPIKeyService_RevokedKeyIsRejected(t *testing.T) {
+	ctx := context.Background()
+	keys := service.NewAPIKeyService(newFakeAPIKeyRepository())
+
+	key, secret, err := keys.Create(ctx, "owner-1", "ci")
+	require.NoError(t, err)
+
+	require.NoError(t, keys.Revoke(ctx, key.ID))
+
+	_, err = keys.Authenticate(ctx, secret)
+	assert.ErrorIs(t, err, domain.ErrInvalidAPIKey)
+}
+
+func TestAPIKeyService_Create_RequiresOwner(t *testing.T) {
+	keys := service.NewAPIKeyService(newFakeAPIKeyRepository())
+
+	_, _, err := keys.Create(context.Background(), "", "ci")
+	assert.ErrorIs(t, err, domain.ErrInvalidOwner)
+}
@@ -2,67 +2,186 @@ package service
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log"
+	"time"
 
 	"github.com/mikiasyonas/url-shortener/internal/core/domain"
 	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// redisCacheTier labels cache metrics recorded for the shared Redis cache.
+	redisCacheTier = "redis"
+
+	lookupTimeout = 5 * time.Second
 )
 
 type cachedURLService struct {
 	urlService ports.URLService
 	cache      ports.Cache
-	repo       ports.URLRepository
+	metrics    ports.CacheMetrics
+	lookups    singleflight.Group
+
+	// negativeTTL is how long a short code that does not exist is cached as
+	// missing; zero disables negative caching.
+	negativeTTL time.Duration
 }
 
-func NewCachedURLService(urlService ports.URLService, cache ports.Cache, repo ports.URLRepository) *cachedURLService {
+func NewCachedURLService(urlService ports.URLService, cache ports.Cache, metrics ports.CacheMetrics, negativeTTL time.Duration) *cachedURLService {
 	log.Println("Initialized Cached URL Service")
+	if metrics == nil {
+		metrics = noopCacheMetrics{}
+	}
 	return &cachedURLService{
 		urlService: urlService,
 		cache:      cache,
-		repo:       repo,
+		metrics:    metrics,
+
+		negativeTTL: negativeTTL,
 	}
 }
 
-func (s *cachedURLService) ShortenURL(ctx context.Context, originalURL string) (*domain.URL, error) {
-	return s.urlService.ShortenURL(ctx, originalURL)
+func (s *cachedURLService) ShortenURL(ctx context.Context, originalURL string, opts domain.ShortenOptions) (*domain.URL, error) {
+	url, err := s.urlService.ShortenURL(ctx, originalURL, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// The code may have been looked up, and cached as not found, before the
+	// link existed.
+	s.invalidate(ctx, url.Domain, url.ShortCode)
+	return url, nil
 }
 
-func (s *cachedURLService) Redirect(ctx context.Context, shortCode string) (string, error) {
-	if s.cache != nil {
-		if url, err := s.cache.GetURL(ctx, shortCode); err == nil {
-			go s.incrementClickCount(shortCode)
-			return url.OriginalURL, nil
+func (s *cachedURLService) Redirect(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	if s.cache == nil {
+		return s.urlService.Redirect(ctx, host, shortCode)
+	}
+
+	url, err := s.LookupURL(ctx, host, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if url.IsExpired(time.Now()) {
+		return nil, domain.ErrURLExpired
+	}
+
+	go s.incrementClickCount(host, shortCode)
+	return url, nil
+}
+
+// LookupURL returns the link from the cache or, on a miss, from the
+// database. Concurrent misses for the same link share a single lookup,
+// whose result also populates the cache, so a popular link falling out of
+// the cache costs one query rather than one per waiting request.
+func (s *cachedURLService) LookupURL(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	if s.cache == nil {
+		return s.urlService.LookupURL(ctx, host, shortCode)
+	}
+
+	url, err := s.cache.GetURL(ctx, host, shortCode)
+	switch {
+	case err == nil:
+		s.metrics.RecordCacheHit(redisCacheTier)
+		return url, nil
+	case errors.Is(err, domain.ErrURLNotFound):
+		s.metrics.RecordCacheNegativeHit(redisCacheTier)
+		return nil, err
+	}
+	s.metrics.RecordCacheMiss(redisCacheTier)
+
+	result, err, _ := s.lookups.Do(host+"/"+shortCode, func() (interface{}, error) {
+		// The lookup is shared, so it must not be cut short when the
+		// request that happened to start it goes away.
+		lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lookupTimeout)
+		defer cancel()
+
+		url, err := s.urlService.LookupURL(lookupCtx, host, shortCode)
+		if errors.Is(err, domain.ErrURLNotFound) && s.negativeTTL > 0 {
+			if err := s.cache.SetNotFound(lookupCtx, host, shortCode, int(s.negativeTTL.Seconds())); err != nil {
+				log.Printf("Failed to cache missing URL %s: %v", shortCode, err)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.cache.SetURL(lookupCtx, url, 3600); err != nil {
+			log.Printf("Failed to cache URL %s: %v", shortCode, err)
 		}
+		return url, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	originalURL, err := s.urlService.Redirect(ctx, shortCode)
+	return result.(*domain.URL), nil
+}
+
+func (s *cachedURLService) GetURL(ctx context.Context, ownerID, host, shortCode string) (*domain.URL, error) {
+	url, err := s.urlService.GetURL(ctx, ownerID, host, shortCode)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	// Include clicks counted in the cache that have not been flushed yet.
 	if s.cache != nil {
-		go s.cacheURL(context.Background(), shortCode)
+		if pending, err := s.cache.GetClickCount(ctx, host, shortCode); err == nil {
+			url.ClickCount += pending
+		}
 	}
 
-	return originalURL, nil
+	return url, nil
 }
 
-func (s *cachedURLService) cacheURL(ctx context.Context, shortCode string) {
-	url, err := s.repo.FindByShortCode(ctx, shortCode)
+func (s *cachedURLService) UpdateURL(ctx context.Context, ownerID, host, shortCode string, update domain.URLUpdate) (*domain.URL, error) {
+	url, err := s.urlService.UpdateURL(ctx, ownerID, host, shortCode, update)
 	if err != nil {
-		fmt.Printf("Failed to get URL for caching: %v\n", err)
+		return nil, err
+	}
+
+	s.invalidate(ctx, host, shortCode)
+	return url, nil
+}
+
+func (s *cachedURLService) DeleteURL(ctx context.Context, ownerID, host, shortCode string) error {
+	if err := s.urlService.DeleteURL(ctx, ownerID, host, shortCode); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, host, shortCode)
+	return nil
+}
+
+func (s *cachedURLService) ListURLs(ctx context.Context, filter domain.ListFilter) (*domain.URLPage, error) {
+	return s.urlService.ListURLs(ctx, filter)
+}
+
+// invalidate drops the cached entry synchronously so that the next redirect
+// observes the change made in the database.
+func (s *cachedURLService) invalidate(ctx context.Context, host, shortCode string) {
+	if s.cache == nil {
 		return
 	}
 
-	if err := s.cache.SetURL(ctx, url, 3600); err != nil {
-		fmt.Printf("Failed to cache URL: %v\n", err)
+	if err := s.cache.DeleteURL(ctx, host, shortCode); err != nil {
+		log.Printf("Failed to invalidate cached URL %s: %v", shortCode, err)
 	}
 }
 
-func (s *cachedURLService) incrementClickCount(shortCode string) {
+func (s *cachedURLService) incrementClickCount(host, shortCode string) {
 	ctx := context.Background()
 	if s.cache != nil {
-		s.cache.IncrementClickCount(ctx, shortCode)
+		s.cache.IncrementClickCount(ctx, host, shortCode)
 	}
 }
+
+type noopCacheMetrics struct{}
+
+func (noopCacheMetrics) RecordCacheHit(string)         {}
+func (noopCacheMetrics) RecordCacheMiss(string)        {}
+func (noopCacheMetrics) RecordCacheNegativeHit(string) {}
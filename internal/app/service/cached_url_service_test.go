@@ -0,0 +1,398 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/app/service"
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func (m *MockURLService) ShortenURL(ctx context.Context, originalURL string, opts domain.ShortenOptions) (*domain.URL, error) {
+	args := m.Called(ctx, originalURL, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockURLService) Redirect(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	args := m.Called(ctx, host, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockURLService) LookupURL(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	args := m.Called(ctx, host, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockURLService) GetURL(ctx context.Context, ownerID, host, shortCode string) (*domain.URL, error) {
+	args := m.Called(ctx, ownerID, host, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockURLService) UpdateURL(ctx context.Context, ownerID, host, shortCode string, update domain.URLUpdate) (*domain.URL, error) {
+	args := m.Called(ctx, ownerID, host, shortCode, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockURLService) DeleteURL(ctx context.Context, ownerID, host, shortCode string) error {
+	args := m.Called(ctx, ownerID, host, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockURLService) ListURLs(ctx context.Context, filter domain.ListFilter) (*domain.URLPage, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URLPage), args.Error(1)
+}
+
+type MockCache struct {
+	mock.Mock
+}
+
+func (m *MockCache) GetURL(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	args := m.Called(ctx, host, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockCache) SetURL(ctx context.Context, url *domain.URL, ttl int) error {
+	args := m.Called(ctx, url, ttl)
+	return args.Error(0)
+}
+
+func (m *MockCache) SetNotFound(ctx context.Context, host, shortCode string, ttl int) error {
+	args := m.Called(ctx, host, shortCode, ttl)
+	return args.Error(0)
+}
+
+func (m *MockCache) DeleteURL(ctx context.Context, host, shortCode string) error {
+	args := m.Called(ctx, host, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockCache) IncrementClickCount(ctx context.Context, host, shortCode string) error {
+	args := m.Called(ctx, host, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockCache) GetClickCount(ctx context.Context, host, shortCode string) (int64, error) {
+	args := m.Called(ctx, host, shortCode)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCache) DrainClickCounts(ctx context.Context) (map[domain.LinkRef]int64, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[domain.LinkRef]int64), args.Error(1)
+}
+
+func TestCachedURLService_GetURL_IncludesPendingClicks(t *testing.T) {
+	ctx := context.Background()
+	mockService := new(MockURLService)
+	mockCache := new(MockCache)
+
+	cached := service.NewCachedURLService(mockService, mockCache, nil, time.Minute)
+
+	mockService.On("GetURL", ctx, "owner-1", "", "abc123").Return(&domain.URL{ShortCode: "abc123", ClickCount: 10}, nil)
+	mockCache.On("GetClickCount", ctx, "", "abc123").Return(int64(5), nil)
+
+	result, err := cached.GetURL(ctx, "owner-1", "", "abc123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(15), result.ClickCount)
+}
+
+func TestCachedURLService_UpdateURL_InvalidatesCache(t *testing.T) {
+	ctx := context.Background()
+	mockService := new(MockURLService)
+	mockCache := new(MockCache)
+
+	cached := service.NewCachedURLService(mockService, mockCache, nil, time.Minute)
+
+	newTarget := "https://example.org"
+	update := domain.URLUpdate{OriginalURL: &newTarget}
+	updated := &domain.URL{OriginalURL: newTarget, ShortCode: "abc123"}
+
+	mockService.On("UpdateURL", ctx, "owner-1", "", "abc123", update).Return(updated, nil)
+	mockCache.On("DeleteURL", ctx, "", "abc123").Return(nil)
+
+	result, err := cached.UpdateURL(ctx, "owner-1", "", "abc123", update)
+
+	assert.NoError(t, err)
+	assert.Equal(t, updated, result)
+
+	mockService.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestCachedURLService_UpdateURL_FailureKeepsCache(t *testing.T) {
+	ctx := context.Background()
+	mockService := new(MockURLService)
+	mockCache := new(MockCache)
+
+	cached := service.NewCachedURLService(mockService, mockCache, nil, time.Minute)
+
+	update := domain.URLUpdate{ClearExpiry: true}
+	mockService.On("UpdateURL", ctx, "owner-1", "", "abc123", update).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+
+	_, err := cached.UpdateURL(ctx, "owner-1", "", "abc123", update)
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	mockCache.AssertNotCalled(t, "DeleteURL", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCachedURLService_DeleteURL_InvalidatesCache(t *testing.T) {
+	ctx := context.Background()
+	mockService := new(MockURLService)
+	mockCache := new(MockCache)
+
+	cached := service.NewCachedURLService(mockService, mockCache, nil, time.Minute)
+
+	mockService.On("DeleteURL", ctx, "owner-1", "", "abc123").Return(nil)
+	mockCache.On("DeleteURL", ctx, "", "abc123").Return(nil)
+
+	err := cached.DeleteURL(ctx, "owner-1", "", "abc123")
+
+	assert.NoError(t, err)
+
+	mockService.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+type MockCacheMetrics struct {
+	mock.Mock
+}
+
+func (m *MockCacheMetrics) RecordCacheHit(cache string) {
+	m.Called(cache)
+}
+
+func (m *MockCacheMetrics) RecordCacheMiss(cache string) {
+	m.Called(cache)
+}
+
+func (m *MockCacheMetrics) RecordCacheNegativeHit(cache string) {
+	m.Called(cache)
+}
+
+func TestCachedURLService_Redirect_RecordsCacheHit(t *testing.T) {
+	ctx := context.Background()
+	mockService := new(MockURLService)
+	mockCache := new(MockCache)
+	mockMetrics := new(MockCacheMetrics)
+
+	cached := service.NewCachedURLService(mockService, mockCache, mockMetrics, time.Minute)
+
+	mockCache.On("GetURL", ctx, "", "abc123").Return(&domain.URL{OriginalURL: "https://example.com", ShortCode: "abc123"}, nil)
+	mockCache.On("IncrementClickCount", mock.Anything, "", "abc123").Return(nil).Maybe()
+	mockMetrics.On("RecordCacheHit", "redis").Return()
+
+	result, err := cached.Redirect(ctx, "", "abc123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", result.OriginalURL)
+	mockMetrics.AssertExpectations(t)
+	mockMetrics.AssertNotCalled(t, "RecordCacheMiss", mock.Anything)
+	mockService.AssertNotCalled(t, "Redirect", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCachedURLService_Redirect_RecordsCacheMiss(t *testing.T) {
+	ctx := context.Background()
+	mockService := new(MockURLService)
+	mockCache := new(MockCache)
+	mockMetrics := new(MockCacheMetrics)
+
+	cached := service.NewCachedURLService(mockService, mockCache, mockMetrics, time.Minute)
+
+	mockCache.On("GetURL", ctx, "", "abc123").Return(nil, assert.AnError)
+	mockService.On("LookupURL", mock.Anything, "", "abc123").Return(nil, domain.ErrURLNotFound)
+	mockCache.On("SetNotFound", mock.Anything, "", "abc123", 60).Return(nil)
+	mockMetrics.On("RecordCacheMiss", "redis").Return()
+
+	_, err := cached.Redirect(ctx, "", "abc123")
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	mockMetrics.AssertExpectations(t)
+	mockMetrics.AssertNotCalled(t, "RecordCacheHit", mock.Anything)
+}
+
+func TestCachedURLService_Redirect_CoalescesConcurrentMisses(t *testing.T) {
+	const concurrency = 50
+
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+	mockCache := new(MockCache)
+	base := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+	cached := service.NewCachedURLService(base, mockCache, nil, time.Minute)
+
+	url := &domain.URL{OriginalURL: "https://example.com", ShortCode: "abc123"}
+	release := make(chan struct{})
+	var lookups atomic.Int32
+
+	mockGenerator.On("Resembles", "abc123").Return(true)
+	mockCache.On("GetURL", mock.Anything, "", "abc123").Return(nil, ports.ErrCacheMiss)
+	mockCache.On("SetURL", mock.Anything, url, 3600).Return(nil).Once()
+	mockCache.On("IncrementClickCount", mock.Anything, "", "abc123").Return(nil)
+	mockRepo.On("FindByShortCode", mock.Anything, "", "abc123").Run(func(args mock.Arguments) {
+		lookups.Add(1)
+		<-release
+	}).Return(url, nil)
+
+	var started, done sync.WaitGroup
+	results := make(chan *domain.URL, concurrency)
+	for i := 0; i < concurrency; i++ {
+		started.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			started.Done()
+			result, err := cached.Redirect(context.Background(), "", "abc123")
+			assert.NoError(t, err)
+			results <- result
+		}()
+	}
+
+	// Let every request join the in-flight lookup before it completes.
+	started.Wait()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	done.Wait()
+	close(results)
+
+	for result := range results {
+		assert.Equal(t, "https://example.com", result.OriginalURL)
+	}
+	assert.Equal(t, int32(1), lookups.Load())
+	mockRepo.AssertNumberOfCalls(t, "FindByShortCode", 1)
+	mockCache.AssertNumberOfCalls(t, "SetURL", 1)
+}
+
+func TestCachedURLService_Redirect_MissPopulatesCacheWithoutRequery(t *testing.T) {
+	ctx := context.Background()
+	mockService := new(MockURLService)
+	mockCache := new(MockCache)
+
+	cached := service.NewCachedURLService(mockService, mockCache, nil, time.Minute)
+
+	url := &domain.URL{OriginalURL: "https://example.com", ShortCode: "abc123"}
+	mockCache.On("GetURL", ctx, "", "abc123").Return(nil, ports.ErrCacheMiss)
+	mockService.On("LookupURL", mock.Anything, "", "abc123").Return(url, nil).Once()
+	mockCache.On("SetURL", mock.Anything, url, 3600).Return(nil).Once()
+	mockCache.On("IncrementClickCount", mock.Anything, "", "abc123").Return(nil).Maybe()
+
+	result, err := cached.Redirect(ctx, "", "abc123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", result.OriginalURL)
+	mockService.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "Redirect", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCachedURLService_Redirect_ExpiredMiss(t *testing.T) {
+	ctx := context.Background()
+	mockService := new(MockURLService)
+	mockCache := new(MockCache)
+
+	cached := service.NewCachedURLService(mockService, mockCache, nil, time.Minute)
+
+	expiresAt := time.Now().Add(-time.Minute)
+	url := &domain.URL{OriginalURL: "https://example.com", ShortCode: "abc123", ExpiresAt: &expiresAt}
+	mockCache.On("GetURL", ctx, "", "abc123").Return(nil, ports.ErrCacheMiss)
+	mockService.On("LookupURL", mock.Anything, "", "abc123").Return(url, nil)
+	mockCache.On("SetURL", mock.Anything, url, 3600).Return(nil)
+
+	_, err := cached.Redirect(ctx, "", "abc123")
+
+	assert.ErrorIs(t, err, domain.ErrURLExpired)
+	mockCache.AssertNotCalled(t, "IncrementClickCount", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCachedURLService_Redirect_CachesNotFound(t *testing.T) {
+	ctx := context.Background()
+	mockService := new(MockURLService)
+	mockCache := new(MockCache)
+	mockMetrics := new(MockCacheMetrics)
+
+	cached := service.NewCachedURLService(mockService, mockCache, mockMetrics, 30*time.Second)
+
+	mockCache.On("GetURL", ctx, "", "nope42").Return(nil, ports.ErrCacheMiss).Once()
+	mockService.On("LookupURL", mock.Anything, "", "nope42").Return(nil, domain.ErrURLNotFound).Once()
+	mockCache.On("SetNotFound", mock.Anything, "", "nope42", 30).Return(nil).Once()
+	mockMetrics.On("RecordCacheMiss", "redis").Return().Once()
+
+	_, err := cached.Redirect(ctx, "", "nope42")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	// The next lookup is answered by the negative entry.
+	mockCache.On("GetURL", ctx, "", "nope42").Return(nil, domain.ErrURLNotFound).Once()
+	mockMetrics.On("RecordCacheNegativeHit", "redis").Return().Once()
+
+	_, err = cached.Redirect(ctx, "", "nope42")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	mockService.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+	mockMetrics.AssertExpectations(t)
+}
+
+func TestCachedURLService_Redirect_NegativeCachingDisabled(t *testing.T) {
+	ctx := context.Background()
+	mockService := new(MockURLService)
+	mockCache := new(MockCache)
+
+	cached := service.NewCachedURLService(mockService, mockCache, nil, 0)
+
+	mockCache.On("GetURL", ctx, "", "nope42").Return(nil, ports.ErrCacheMiss)
+	mockService.On("LookupURL", mock.Anything, "", "nope42").Return(nil, domain.ErrURLNotFound)
+
+	_, err := cached.Redirect(ctx, "", "nope42")
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	mockCache.AssertNotCalled(t, "SetNotFound", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCachedURLService_ShortenURL_ClearsNegativeEntry(t *testing.T) {
+	ctx := context.Background()
+	mockService := new(MockURLService)
+	mockCache := new(MockCache)
+
+	cached := service.NewCachedURLService(mockService, mockCache, nil, time.Minute)
+
+	opts := domain.ShortenOptions{Alias: "promo", OwnerID: "owner-1"}
+	mockService.On("ShortenURL", ctx, "https://example.com", opts).Return(&domain.URL{OriginalURL: "https://example.com", ShortCode: "promo"}, nil)
+	mockCache.On("DeleteURL", ctx, "", "promo").Return(nil).Once()
+
+	result, err := cached.ShortenURL(ctx, "https://example.com", opts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "promo", result.ShortCode)
+	mockCache.AssertExpectations(t)
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+// ClickFlusher periodically drains the click counters accumulated in the
+// cache on redirect hits and applies them to the repository. Counts that
+// fail to persist are kept in memory and retried on the next flush.
+type ClickFlusher struct {
+	cache    ports.Cache
+	repo     ports.URLRepository
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[domain.LinkRef]int64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewClickFlusher(cache ports.Cache, repo ports.URLRepository, interval time.Duration) *ClickFlusher {
+	return &ClickFlusher{
+		cache:    cache,
+		repo:     repo,
+		interval: interval,
+		pending:  make(map[domain.LinkRef]int64),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (f *ClickFlusher) Start() {
+	go f.run()
+}
+
+// Stop halts the periodic flush and performs a final one so that no counted
+// clicks are left behind in the cache on shutdown.
+func (f *ClickFlusher) Stop(ctx context.Context) error {
+	close(f.stop)
+	<-f.done
+
+	return f.Flush(ctx)
+}
+
+func (f *ClickFlusher) run() {
+	defer close(f.done)
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), f.interval)
+			if err := f.Flush(ctx); err != nil {
+				log.Printf("Failed to flush click counts: %v", err)
+			}
+			cancel()
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+func (f *ClickFlusher) Flush(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	drained, drainErr := f.cache.DrainClickCounts(ctx)
+	for link, count := range drained {
+		f.pending[link] += count
+	}
+
+	if len(f.pending) > 0 {
+		if err := f.repo.AddClickCounts(ctx, f.pending); err != nil {
+			return err
+		}
+		f.pending = make(map[domain.LinkRef]int64)
+	}
+
+	return drainErr
+}
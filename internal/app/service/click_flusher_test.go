@@ -0,0 +1,70 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/app/service"
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClickFlusher_Flush_AppliesDrainedCounts(t *testing.T) {
+	ctx := context.Background()
+	mockCache := new(MockCache)
+	mockRepo := new(MockRepository)
+
+	flusher := service.NewClickFlusher(mockCache, mockRepo, time.Minute)
+
+	counts := map[domain.LinkRef]int64{{ShortCode: "abc123"}: 3, {Domain: "go.example.com", ShortCode: "abc123"}: 1}
+	mockCache.On("DrainClickCounts", ctx).Return(counts, nil)
+	mockRepo.On("AddClickCounts", ctx, counts).Return(nil)
+
+	err := flusher.Flush(ctx)
+
+	assert.NoError(t, err)
+	mockCache.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestClickFlusher_Flush_RetriesFailedCounts(t *testing.T) {
+	ctx := context.Background()
+	mockCache := new(MockCache)
+	mockRepo := new(MockRepository)
+
+	flusher := service.NewClickFlusher(mockCache, mockRepo, time.Minute)
+
+	mockCache.On("DrainClickCounts", ctx).Return(map[domain.LinkRef]int64{{ShortCode: "abc123"}: 3}, nil).Once()
+	mockRepo.On("AddClickCounts", ctx, map[domain.LinkRef]int64{{ShortCode: "abc123"}: 3}).Return(errors.New("db down")).Once()
+
+	err := flusher.Flush(ctx)
+	assert.Error(t, err)
+
+	mockCache.On("DrainClickCounts", ctx).Return(map[domain.LinkRef]int64{{ShortCode: "abc123"}: 2}, nil).Once()
+	mockRepo.On("AddClickCounts", ctx, map[domain.LinkRef]int64{{ShortCode: "abc123"}: 5}).Return(nil).Once()
+
+	err = flusher.Flush(ctx)
+	assert.NoError(t, err)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestClickFlusher_Stop_PerformsFinalFlush(t *testing.T) {
+	ctx := context.Background()
+	mockCache := new(MockCache)
+	mockRepo := new(MockRepository)
+
+	flusher := service.NewClickFlusher(mockCache, mockRepo, time.Hour)
+	flusher.Start()
+
+	mockCache.On("DrainClickCounts", ctx).Return(map[domain.LinkRef]int64{{ShortCode: "abc123"}: 7}, nil)
+	mockRepo.On("AddClickCounts", ctx, map[domain.LinkRef]int64{{ShortCode: "abc123"}: 7}).Return(nil)
+
+	err := flusher.Stop(ctx)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
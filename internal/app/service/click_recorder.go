@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+// AsyncClickRecorder buffers click events in a bounded channel and writes
+// them to the repository in batches from a single background worker. When
+// the buffer is full new events are dropped rather than slowing redirects.
+type AsyncClickRecorder struct {
+	repo          ports.ClickRepository
+	ipSalt        string
+	batchSize     int
+	flushInterval time.Duration
+
+	mu      sync.RWMutex
+	closed  bool
+	events  chan *domain.Click
+	dropped atomic.Int64
+
+	done chan struct{}
+}
+
+func NewAsyncClickRecorder(repo ports.ClickRepository, bufferSize, batchSize int, flushInterval time.Duration, ipSalt string) *AsyncClickRecorder {
+	return &AsyncClickRecorder{
+		repo:          repo,
+		ipSalt:        ipSalt,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		events:        make(chan *domain.Click, bufferSize),
+		done:          make(chan struct{}),
+	}
+}
+
+func (r *AsyncClickRecorder) Start() {
+	go r.run()
+}
+
+func (r *AsyncClickRecorder) Record(click *domain.Click, clientIP string) {
+	if clientIP != "" {
+		click.IPHash = r.hashIP(clientIP)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return
+	}
+
+	select {
+	case r.events <- click:
+	default:
+		if r.dropped.Add(1)%1000 == 1 {
+			log.Printf("Click buffer full, dropped %d events so far", r.dropped.Load())
+		}
+	}
+}
+
+// Dropped returns the number of events discarded because the buffer was full.
+func (r *AsyncClickRecorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// Stop closes the buffer and waits for the worker to write out everything
+// that was already queued, or for ctx to expire.
+func (r *AsyncClickRecorder) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.events)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *AsyncClickRecorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*domain.Click, 0, r.batchSize)
+	for {
+		select {
+		case click, ok := <-r.events:
+			if !ok {
+				r.write(batch)
+				return
+			}
+			batch = append(batch, click)
+			if len(batch) >= r.batchSize {
+				r.write(batch)
+				batch = make([]*domain.Click, 0, r.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				r.write(batch)
+				batch = make([]*domain.Click, 0, r.batchSize)
+			}
+		}
+	}
+}
+
+func (r *AsyncClickRecorder) write(batch []*domain.Click) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.repo.SaveBatch(ctx, batch); err != nil {
+		log.Printf("Failed to save %d click events: %v", len(batch), err)
+	}
+}
+
+func (r *AsyncClickRecorder) hashIP(ip string) string {
+	sum := sha256.Sum256([]byte(r.ipSalt + ip))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,94 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/app/service"
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClickRepository struct {
+	mu      sync.Mutex
+	batches [][]*domain.Click
+}
+
+func (f *fakeClickRepository) SaveBatch(ctx context.Context, clicks []*domain.Click) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, clicks)
+	return nil
+}
+
+func (f *fakeClickRepository) saved() [][]*domain.Click {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]*domain.Click(nil), f.batches...)
+}
+
+func newClick(shortCode string) *domain.Click {
+	return domain.NewClick(domain.LinkRef{ShortCode: shortCode}, "https://referrer.example", "test-agent", "en-US", time.Now())
+}
+
+func TestAsyncClickRecorder_WritesFullBatches(t *testing.T) {
+	repo := &fakeClickRepository{}
+	recorder := service.NewAsyncClickRecorder(repo, 100, 2, time.Hour, "salt")
+	recorder.Start()
+
+	for i := 0; i < 4; i++ {
+		recorder.Record(newClick("abc123"), "203.0.113.7")
+	}
+
+	require.Eventually(t, func() bool { return len(repo.saved()) == 2 }, time.Second, 10*time.Millisecond)
+	for _, batch := range repo.saved() {
+		assert.Len(t, batch, 2)
+	}
+
+	assert.NoError(t, recorder.Stop(context.Background()))
+}
+
+func TestAsyncClickRecorder_StopWritesPartialBatch(t *testing.T) {
+	repo := &fakeClickRepository{}
+	recorder := service.NewAsyncClickRecorder(repo, 100, 50, time.Hour, "salt")
+	recorder.Start()
+
+	recorder.Record(newClick("abc123"), "203.0.113.7")
+
+	assert.NoError(t, recorder.Stop(context.Background()))
+
+	batches := repo.saved()
+	require.Len(t, batches, 1)
+	assert.Len(t, batches[0], 1)
+
+	recorder.Record(newClick("abc123"), "203.0.113.7")
+	assert.Len(t, repo.saved(), 1, "events recorded after Stop are ignored")
+}
+
+func TestAsyncClickRecorder_DropsWhenBufferFull(t *testing.T) {
+	repo := &fakeClickRepository{}
+	recorder := service.NewAsyncClickRecorder(repo, 1, 10, time.Hour, "salt")
+
+	recorder.Record(newClick("abc123"), "203.0.113.7")
+	recorder.Record(newClick("abc123"), "203.0.113.7")
+
+	assert.Equal(t, int64(1), recorder.Dropped())
+}
+
+func TestAsyncClickRecorder_HashesClientIP(t *testing.T) {
+	repo := &fakeClickRepository{}
+	recorder := service.NewAsyncClickRecorder(repo, 10, 10, time.Hour, "salt")
+	recorder.Start()
+
+	click := newClick("abc123")
+	recorder.Record(click, "203.0.113.7")
+
+	assert.NoError(t, recorder.Stop(context.Background()))
+
+	assert.Len(t, click.IPHash, 64)
+	assert.NotContains(t, click.IPHash, "203.0.113.7")
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+// DomainService resolves redirect hosts against the registered domains,
+// which it keeps in memory so that redirects never wait on the database.
+// The set is reloaded every refreshInterval, so a new domain is only served
+// by every instance once that long has passed since it was registered;
+// until then no links can be created on it, which would otherwise redirect
+// from the default domain on instances that have not reloaded yet.
+type DomainService struct {
+	repo            ports.DomainRepository
+	refreshInterval time.Duration
+
+	mu    sync.RWMutex
+	hosts map[string]*domain.Domain
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewDomainService(repo ports.DomainRepository, refreshInterval time.Duration) *DomainService {
+	return &DomainService{
+		repo:            repo,
+		refreshInterval: refreshInterval,
+		hosts:           make(map[string]*domain.Domain),
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+func (s *DomainService) Start() {
+	go s.run()
+}
+
+func (s *DomainService) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+func (s *DomainService) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), s.refreshInterval)
+			if err := s.Refresh(ctx); err != nil {
+				log.Printf("Failed to refresh domains: %v", err)
+			}
+			cancel()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Refresh reloads the registered domains. On error the previous set is
+// kept.
+func (s *DomainService) Refresh(ctx context.Context) error {
+	domains, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	hosts := make(map[string]*domain.Domain, len(domains))
+	for _, d := range domains {
+		hosts[d.Host] = d
+	}
+
+	s.mu.Lock()
+	s.hosts = hosts
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *DomainService) Resolve(host string) string {
+	host = domain.NormalizeHost(host)
+
+	s.mu.RLock()
+	_, ok := s.hosts[host]
+	s.mu.RUnlock()
+
+	if !ok {
+		return domain.DefaultDomain
+	}
+	return host
+}
+
+// Authorize reads the same in-memory set as Resolve, so that links are only
+// created on domains redirects are served from.
+func (s *DomainService) Authorize(ctx context.Context, ownerID, host string) (string, error) {
+	host = domain.NormalizeHost(host)
+	if host == domain.DefaultDomain {
+		return domain.DefaultDomain, nil
+	}
+
+	s.mu.RLock()
+	d, ok := s.hosts[host]
+	s.mu.RUnlock()
+
+	if !ok || !d.UsableBy(ownerID) {
+		return "", domain.ErrDomainNotAllowed
+	}
+	if time.Since(d.CreatedAt) < s.refreshInterval {
+		return "", domain.ErrDomainPending
+	}
+
+	return d.Host, nil
+}
+
+func (s *DomainService) Register(ctx context.Context, host, ownerID string) (*domain.Domain, error) {
+	d, err := domain.NewDomain(host, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Save(ctx, d); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.hosts[d.Host] = d
+	s.mu.Unlock()
+	return d, nil
+}
+
+func (s *DomainService) List(ctx context.Context, ownerID string) ([]*domain.Domain, error) {
+	domains, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usable := make([]*domain.Domain, 0, len(domains))
+	for _, d := range domains {
+		if d.UsableBy(ownerID) {
+			usable = append(usable, d)
+		}
+	}
+	return usable, nil
+}
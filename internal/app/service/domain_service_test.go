@@ -0,0 +1,136 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/app/service"
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDomainRepository struct {
+	mock.Mock
+}
+
+func (m *MockDomainRepository) Save(ctx context.Context, d *domain.Domain) error {
+	args := m.Called(ctx, d)
+	return args.Error(0)
+}
+
+func (m *MockDomainRepository) FindByHost(ctx context.Context, host string) (*domain.Domain, error) {
+	args := m.Called(ctx, host)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Domain), args.Error(1)
+}
+
+func (m *MockDomainRepository) List(ctx context.Context) ([]*domain.Domain, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Domain), args.Error(1)
+}
+
+func TestDomainService_Resolve(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockDomainRepository)
+	domains := service.NewDomainService(mockRepo, time.Minute)
+
+	mockRepo.On("List", ctx).Return([]*domain.Domain{{Host: "go.example.com"}}, nil)
+	require.NoError(t, domains.Refresh(ctx))
+
+	assert.Equal(t, "go.example.com", domains.Resolve("go.example.com"))
+	assert.Equal(t, "go.example.com", domains.Resolve("GO.example.com.:8080"))
+	assert.Equal(t, domain.DefaultDomain, domains.Resolve("localhost:8080"))
+}
+
+func TestDomainService_Authorize(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockDomainRepository)
+	domains := service.NewDomainService(mockRepo, time.Minute)
+
+	registered := time.Now().Add(-time.Hour)
+	mockRepo.On("List", ctx).Return([]*domain.Domain{
+		{Host: "mine.example.com", OwnerID: "owner-1", CreatedAt: registered},
+		{Host: "shared.example.com", CreatedAt: registered},
+	}, nil)
+	require.NoError(t, domains.Refresh(ctx))
+
+	host, err := domains.Authorize(ctx, "owner-1", "Mine.Example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "mine.example.com", host)
+
+	_, err = domains.Authorize(ctx, "owner-2", "mine.example.com")
+	assert.ErrorIs(t, err, domain.ErrDomainNotAllowed)
+
+	host, err = domains.Authorize(ctx, "owner-2", "shared.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "shared.example.com", host)
+
+	_, err = domains.Authorize(ctx, "owner-1", "unknown.example.com")
+	assert.ErrorIs(t, err, domain.ErrDomainNotAllowed)
+
+	host, err = domains.Authorize(ctx, "owner-1", "")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.DefaultDomain, host)
+}
+
+func TestDomainService_Authorize_Pending(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockDomainRepository)
+	domains := service.NewDomainService(mockRepo, time.Minute)
+
+	mockRepo.On("List", ctx).Return([]*domain.Domain{
+		{Host: "new.example.com", CreatedAt: time.Now().Add(-time.Second)},
+	}, nil)
+	require.NoError(t, domains.Refresh(ctx))
+
+	_, err := domains.Authorize(ctx, "owner-1", "new.example.com")
+	assert.ErrorIs(t, err, domain.ErrDomainPending, "other instances may not serve the domain yet")
+	mockRepo.AssertNotCalled(t, "FindByHost", mock.Anything, mock.Anything)
+}
+
+func TestDomainService_Register(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockDomainRepository)
+	domains := service.NewDomainService(mockRepo, time.Minute)
+
+	mockRepo.On("Save", ctx, mock.MatchedBy(func(d *domain.Domain) bool {
+		return d.Host == "go.example.com" && d.OwnerID == "owner-1"
+	})).Return(nil)
+
+	d, err := domains.Register(ctx, "Go.Example.com", "owner-1")
+	require.NoError(t, err)
+	assert.Equal(t, "go.example.com", d.Host)
+	assert.Equal(t, "go.example.com", domains.Resolve("go.example.com"))
+
+	for _, invalid := range []string{"", "localhost", "go.example.com/path", "-bad.example.com", "bad..example.com"} {
+		_, err := domains.Register(ctx, invalid, "owner-1")
+		assert.ErrorIs(t, err, domain.ErrInvalidDomain, invalid)
+	}
+}
+
+func TestDomainService_List(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockDomainRepository)
+	domains := service.NewDomainService(mockRepo, time.Minute)
+
+	mockRepo.On("List", ctx).Return([]*domain.Domain{
+		{Host: "mine.example.com", OwnerID: "owner-1"},
+		{Host: "shared.example.com"},
+		{Host: "theirs.example.com", OwnerID: "owner-2"},
+	}, nil)
+
+	result, err := domains.List(ctx, "owner-1")
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, "mine.example.com", result[0].Host)
+	assert.Equal(t, "shared.example.com", result[1].Host)
+}
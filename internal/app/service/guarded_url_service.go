@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+// guardedURLService answers redirects for short codes that were definitely
+// never issued with ErrURLNotFound, before the cache or database is touched.
+// Scanners probing random codes are thus turned away in memory (or with one
+// Redis call). The filter holds codes issued on any domain, so a code it has
+// never seen exists on none of them. All other operations pass straight
+// through.
+type guardedURLService struct {
+	ports.URLService
+	codes ports.ShortCodeFilter
+}
+
+func NewGuardedURLService(urlService ports.URLService, codes ports.ShortCodeFilter) *guardedURLService {
+	return &guardedURLService{
+		URLService: urlService,
+		codes:      codes,
+	}
+}
+
+func (s *guardedURLService) Redirect(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	if s.definitelyAbsent(ctx, shortCode) {
+		return nil, domain.ErrURLNotFound
+	}
+	return s.URLService.Redirect(ctx, host, shortCode)
+}
+
+func (s *guardedURLService) LookupURL(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	if s.definitelyAbsent(ctx, shortCode) {
+		return nil, domain.ErrURLNotFound
+	}
+	return s.URLService.LookupURL(ctx, host, shortCode)
+}
+
+// definitelyAbsent fails open: if the filter cannot be consulted the request
+// proceeds as if it were not there.
+func (s *guardedURLService) definitelyAbsent(ctx context.Context, shortCode string) bool {
+	maybe, err := s.codes.MayContain(ctx, shortCode)
+	return err == nil && !maybe
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+const (
+	rollupCheckpointName = "click_rollups"
+
+	// maxRollupHoursPerRun bounds how much backlog a single run works
+	// through, e.g. after the aggregator has been down for a while.
+	maxRollupHoursPerRun = 24
+)
+
+// StatsAggregator periodically folds raw click events into the rollup
+// tables read by the stats endpoint. Each run recomputes every hour from the
+// checkpoint up to the current, still open hour; the checkpoint only moves
+// past an hour once it has closed and late events have had settleDelay to
+// arrive.
+type StatsAggregator struct {
+	repo        ports.StatsRepository
+	interval    time.Duration
+	settleDelay time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewStatsAggregator(repo ports.StatsRepository, interval, settleDelay time.Duration) *StatsAggregator {
+	return &StatsAggregator{
+		repo:        repo,
+		interval:    interval,
+		settleDelay: settleDelay,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+func (a *StatsAggregator) Start() {
+	go a.run()
+}
+
+func (a *StatsAggregator) Stop() {
+	close(a.stop)
+	<-a.done
+}
+
+func (a *StatsAggregator) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), a.interval)
+			if err := a.Aggregate(ctx, time.Now()); err != nil {
+				log.Printf("Failed to aggregate click stats: %v", err)
+			}
+			cancel()
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+func (a *StatsAggregator) Aggregate(ctx context.Context, now time.Time) error {
+	from, ok, err := a.repo.GetCheckpoint(ctx, rollupCheckpointName)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		earliest, found, err := a.repo.EarliestClick(ctx)
+		if err != nil || !found {
+			return err
+		}
+		from = earliest
+	}
+
+	from = domain.StatsIntervalHour.Truncate(from)
+	current := domain.StatsIntervalHour.Truncate(now)
+
+	for hour, n := from, 0; !hour.After(current) && n < maxRollupHoursPerRun; hour, n = hour.Add(time.Hour), n+1 {
+		if err := a.repo.RollupHour(ctx, hour); err != nil {
+			return err
+		}
+
+		next := hour.Add(time.Hour)
+		if next.Add(a.settleDelay).After(now) {
+			continue
+		}
+		if err := a.repo.SaveCheckpoint(ctx, rollupCheckpointName, next); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+)
+
+type statsService struct {
+	urlRepo   ports.URLRepository
+	statsRepo ports.StatsRepository
+}
+
+func NewStatsService(urlRepo ports.URLRepository, statsRepo ports.StatsRepository) *statsService {
+	return &statsService{
+		urlRepo:   urlRepo,
+		statsRepo: statsRepo,
+	}
+}
+
+func (s *statsService) GetLinkStats(ctx context.Context, ownerID, host, shortCode string, query domain.StatsQuery) (*domain.LinkStats, error) {
+	if !query.Interval.IsValid() || !query.From.Before(query.To) {
+		return nil, domain.ErrInvalidStatsQuery
+	}
+
+	query.From = query.Interval.Truncate(query.From)
+	query.To = query.To.UTC()
+
+	if query.TopLimit <= 0 {
+		query.TopLimit = domain.DefaultStatsTopLimit
+	}
+	if query.TopLimit > domain.MaxStatsTopLimit {
+		query.TopLimit = domain.MaxStatsTopLimit
+	}
+
+	buckets, ok := bucketStarts(query)
+	if !ok {
+		return nil, domain.ErrInvalidStatsQuery
+	}
+
+	url, err := s.urlRepo.FindByShortCode(ctx, host, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if url.OwnerID != ownerID {
+		return nil, domain.ErrURLNotFound
+	}
+
+	stats, err := s.statsRepo.GetLinkStats(ctx, host, shortCode, query)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Series = fillBuckets(buckets, stats.Series)
+	return stats, nil
+}
+
+// bucketStarts lists the start of every bucket in the query range. It
+// reports false when the range spans more than MaxStatsBuckets buckets.
+func bucketStarts(query domain.StatsQuery) ([]time.Time, bool) {
+	var starts []time.Time
+	for t := query.From; t.Before(query.To); t = query.Interval.Next(t) {
+		if len(starts) == domain.MaxStatsBuckets {
+			return nil, false
+		}
+		starts = append(starts, t)
+	}
+	return starts, true
+}
+
+// fillBuckets returns a series with one entry per bucket, using zero counts
+// for buckets that had no clicks and therefore no rollup row.
+func fillBuckets(starts []time.Time, series []domain.StatsBucket) []domain.StatsBucket {
+	byStart := make(map[int64]domain.StatsBucket, len(series))
+	for _, bucket := range series {
+		byStart[bucket.Start.Unix()] = bucket
+	}
+
+	filled := make([]domain.StatsBucket, 0, len(starts))
+	for _, start := range starts {
+		bucket, ok := byStart[start.Unix()]
+		if !ok {
+			bucket = domain.StatsBucket{Start: start}
+		}
+		bucket.Start = start
+		filled = append(filled, bucket)
+	}
+	return filled
+}
@@ -0,0 +1,184 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mikiasyonas/url-shortener/internal/app/service"
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockStatsRepository struct {
+	mock.Mock
+}
+
+func (m *MockStatsRepository) GetLinkStats(ctx context.Context, host, shortCode string, query domain.StatsQuery) (*domain.LinkStats, error) {
+	args := m.Called(ctx, host, shortCode, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.LinkStats), args.Error(1)
+}
+
+func (m *MockStatsRepository) RollupHour(ctx context.Context, hour time.Time) error {
+	args := m.Called(ctx, hour)
+	return args.Error(0)
+}
+
+func (m *MockStatsRepository) EarliestClick(ctx context.Context) (time.Time, bool, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(time.Time), args.Bool(1), args.Error(2)
+}
+
+func (m *MockStatsRepository) GetCheckpoint(ctx context.Context, name string) (time.Time, bool, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(time.Time), args.Bool(1), args.Error(2)
+}
+
+func (m *MockStatsRepository) SaveCheckpoint(ctx context.Context, name string, processedUntil time.Time) error {
+	args := m.Called(ctx, name, processedUntil)
+	return args.Error(0)
+}
+
+func TestStatsService_GetLinkStats_FillsEmptyBuckets(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockStats := new(MockStatsRepository)
+
+	statsService := service.NewStatsService(mockRepo, mockStats)
+
+	from := time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(&domain.URL{ShortCode: "abc123", OwnerID: "owner-1"}, nil)
+	mockStats.On("GetLinkStats", ctx, "", "abc123", mock.MatchedBy(func(q domain.StatsQuery) bool {
+		return q.From.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) && q.TopLimit == domain.DefaultStatsTopLimit
+	})).Return(&domain.LinkStats{
+		ShortCode:   "abc123",
+		TotalClicks: 4,
+		Series:      []domain.StatsBucket{{Start: day2, Clicks: 4, UniqueVisitors: 2}},
+	}, nil)
+
+	stats, err := statsService.GetLinkStats(ctx, "owner-1", "", "abc123", domain.StatsQuery{
+		From:     from,
+		To:       to,
+		Interval: domain.StatsIntervalDay,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, stats.Series, 3)
+	assert.Equal(t, int64(0), stats.Series[0].Clicks)
+	assert.Equal(t, int64(4), stats.Series[1].Clicks)
+	assert.Equal(t, int64(2), stats.Series[1].UniqueVisitors)
+	assert.Equal(t, int64(0), stats.Series[2].Clicks)
+
+	mockStats.AssertExpectations(t)
+}
+
+func TestStatsService_GetLinkStats_InvalidQuery(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockStats := new(MockStatsRepository)
+
+	statsService := service.NewStatsService(mockRepo, mockStats)
+
+	now := time.Now()
+	testCases := map[string]domain.StatsQuery{
+		"reversed range":   {From: now, To: now.Add(-time.Hour), Interval: domain.StatsIntervalHour},
+		"unknown interval": {From: now.Add(-time.Hour), To: now, Interval: "month"},
+		"too many buckets": {From: now.AddDate(-1, 0, 0), To: now, Interval: domain.StatsIntervalHour},
+	}
+
+	for name, query := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := statsService.GetLinkStats(ctx, "owner-1", "", "abc123", query)
+			assert.ErrorIs(t, err, domain.ErrInvalidStatsQuery)
+		})
+	}
+
+	mockStats.AssertNotCalled(t, "GetLinkStats", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestStatsService_GetLinkStats_UnknownLink(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockStats := new(MockStatsRepository)
+
+	statsService := service.NewStatsService(mockRepo, mockStats)
+
+	mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(nil, domain.ErrURLNotFound)
+
+	_, err := statsService.GetLinkStats(ctx, "owner-1", "", "abc123", domain.StatsQuery{
+		From:     time.Now().Add(-time.Hour),
+		To:       time.Now(),
+		Interval: domain.StatsIntervalHour,
+	})
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestStatsService_GetLinkStats_OtherOwner(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockStats := new(MockStatsRepository)
+
+	statsService := service.NewStatsService(mockRepo, mockStats)
+
+	mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(&domain.URL{ShortCode: "abc123", OwnerID: "owner-1"}, nil)
+
+	_, err := statsService.GetLinkStats(ctx, "owner-2", "", "abc123", domain.StatsQuery{
+		From:     time.Now().Add(-time.Hour),
+		To:       time.Now(),
+		Interval: domain.StatsIntervalHour,
+	})
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	mockStats.AssertNotCalled(t, "GetLinkStats", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestStatsAggregator_AdvancesCheckpointOnlyForSettledHours(t *testing.T) {
+	ctx := context.Background()
+	mockStats := new(MockStatsRepository)
+
+	aggregator := service.NewStatsAggregator(mockStats, time.Minute, 5*time.Minute)
+
+	now := time.Date(2026, 3, 1, 12, 3, 0, 0, time.UTC)
+	h10 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	h11 := h10.Add(time.Hour)
+	h12 := h11.Add(time.Hour)
+
+	mockStats.On("GetCheckpoint", ctx, "click_rollups").Return(time.Time{}, false, nil)
+	mockStats.On("EarliestClick", ctx).Return(h10.Add(17*time.Minute), true, nil)
+	mockStats.On("RollupHour", ctx, h10).Return(nil)
+	mockStats.On("RollupHour", ctx, h11).Return(nil)
+	mockStats.On("RollupHour", ctx, h12).Return(nil)
+	mockStats.On("SaveCheckpoint", ctx, "click_rollups", h11).Return(nil)
+
+	err := aggregator.Aggregate(ctx, now)
+
+	assert.NoError(t, err)
+	mockStats.AssertExpectations(t)
+	// 11:00-12:00 closed only three minutes ago, inside the settle delay.
+	mockStats.AssertNotCalled(t, "SaveCheckpoint", ctx, "click_rollups", h12)
+}
+
+func TestStatsAggregator_NoClicks(t *testing.T) {
+	ctx := context.Background()
+	mockStats := new(MockStatsRepository)
+
+	aggregator := service.NewStatsAggregator(mockStats, time.Minute, time.Minute)
+
+	mockStats.On("GetCheckpoint", ctx, "click_rollups").Return(time.Time{}, false, nil)
+	mockStats.On("EarliestClick", ctx).Return(time.Time{}, false, nil)
+
+	err := aggregator.Aggregate(ctx, time.Now())
+
+	assert.NoError(t, err)
+	mockStats.AssertNotCalled(t, "RollupHour", mock.Anything, mock.Anything)
+}
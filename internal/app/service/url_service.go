@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
@@ -9,75 +10,359 @@ import (
 
 	"github.com/mikiasyonas/url-shortener/internal/core/domain"
 	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+	"github.com/mikiasyonas/url-shortener/pkg/canonical"
+	"github.com/mikiasyonas/url-shortener/pkg/shortcode"
 )
 
 type urlService struct {
-	repo          ports.URLRepository
-	codeGenerator ports.ShortCodeGenerator
+	repo           ports.URLRepository
+	codeGenerator  ports.ShortCodeGenerator
+	aliasValidator ports.AliasValidator
+	// codes, when set, lets collision checks skip the database for codes
+	// that were definitely never issued.
+	codes ports.ShortCodeFilter
+	// pool, when set, supplies pre-generated codes; codes are generated on
+	// demand only when it has none to give.
+	pool ports.ShortCodePool
+	// screener, when set, vets destinations before links may point at
+	// them.
+	screener ports.URLScreener
+	// canonicalizer, when set, lets differently spelled destinations share
+	// a link; otherwise only identical strings do.
+	canonicalizer ports.URLCanonicalizer
+	maxURLLength  int
 }
 
-func NewURLService(repo ports.URLRepository, codeGenerator ports.ShortCodeGenerator) *urlService {
-	return &urlService{
-		repo:          repo,
-		codeGenerator: codeGenerator,
+const defaultMaxURLLength = 2048
+
+// URLServiceOption configures an optional part of the URL service.
+type URLServiceOption func(*urlService)
+
+// WithShortCodeFilter lets collision checks skip the database for codes the
+// filter rules out.
+func WithShortCodeFilter(codes ports.ShortCodeFilter) URLServiceOption {
+	return func(s *urlService) {
+		s.codes = codes
 	}
 }
 
-func (s *urlService) ShortenURL(ctx context.Context, originalURL string) (*domain.URL, error) {
-	if err := s.validateURL(originalURL); err != nil {
-		return nil, err
+// WithShortCodePool takes codes from a pool of pre-generated ones.
+func WithShortCodePool(pool ports.ShortCodePool) URLServiceOption {
+	return func(s *urlService) {
+		s.pool = pool
+	}
+}
+
+// WithScreener vets destinations before links may point at them.
+func WithScreener(screener ports.URLScreener) URLServiceOption {
+	return func(s *urlService) {
+		s.screener = screener
+	}
+}
+
+// WithCanonicalizer lets differently spelled destinations share a link.
+func WithCanonicalizer(canonicalizer ports.URLCanonicalizer) URLServiceOption {
+	return func(s *urlService) {
+		s.canonicalizer = canonicalizer
 	}
+}
 
-	if existing, err := s.repo.FindByOriginalURL(ctx, originalURL); err == nil {
-		return existing, nil
+// WithMaxURLLength overrides the default limit on destination length; a
+// length of zero or less keeps the default.
+func WithMaxURLLength(length int) URLServiceOption {
+	return func(s *urlService) {
+		if length > 0 {
+			s.maxURLLength = length
+		}
 	}
+}
 
-	shortCode, err := s.generateUniqueShortCode(ctx)
+func NewURLService(repo ports.URLRepository, codeGenerator ports.ShortCodeGenerator, aliasValidator ports.AliasValidator, opts ...URLServiceOption) *urlService {
+	s := &urlService{
+		repo:           repo,
+		codeGenerator:  codeGenerator,
+		aliasValidator: aliasValidator,
+		maxURLLength:   defaultMaxURLLength,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *urlService) ShortenURL(ctx context.Context, originalURL string, opts domain.ShortenOptions) (*domain.URL, error) {
+	if err := s.checkDestination(ctx, originalURL); err != nil {
+		return nil, err
+	}
+	canonicalURL, err := s.canonicalize(originalURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate unique short code: %w", err)
+		return nil, err
 	}
 
-	newURL, err := domain.NewURL(originalURL, shortCode)
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(time.Now()) {
+		return nil, domain.ErrInvalidExpiry
+	}
+
+	if opts.RedirectType == 0 {
+		opts.RedirectType = domain.DefaultRedirectType
+	}
+	if !domain.ValidRedirectType(opts.RedirectType) {
+		return nil, domain.ErrInvalidRedirectType
+	}
+
+	if opts.Alias != "" {
+		return s.shortenWithAlias(ctx, originalURL, canonicalURL, opts)
+	}
+
+	// Only permanent links are shared; a link with its own lifetime always
+	// gets a fresh code so it cannot cut another caller's link short. Links
+	// are shared per redirect type, and a shared link keeps redirecting to
+	// the spelling it was created with.
+	shared := opts.ExpiresAt == nil
+	if shared {
+		if existing, err := s.repo.FindByCanonicalURL(ctx, opts.OwnerID, opts.Domain, canonicalURL, opts.RedirectType); err == nil {
+			return existing, nil
+		}
+	}
+
+	// A code is only known to be free when it is checked or pooled, so a
+	// concurrent link or alias may claim it before it is saved; another
+	// code is tried then.
+	const maxSaveAttempts = 3
+
+	for attempt := 1; ; attempt++ {
+		shortCode, err := s.nextShortCode(ctx, opts.Domain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate unique short code: %w", err)
+		}
+
+		newURL, err := s.newURL(originalURL, canonicalURL, shortCode, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		// A shared link is saved only if no concurrent call shared one to
+		// the same destination first, in which case that one is returned.
+		var existing *domain.URL
+		if shared {
+			newURL.Share()
+			existing, err = s.repo.SaveShared(ctx, newURL)
+		} else {
+			err = s.repo.Save(ctx, newURL)
+		}
+		if err != nil {
+			if errors.Is(err, domain.ErrShortCodeTaken) && attempt < maxSaveAttempts {
+				continue
+			}
+			return nil, fmt.Errorf("failed to save URL: %w", err)
+		}
+		if existing != nil {
+			return existing, nil
+		}
+
+		s.recordIssued(ctx, shortCode)
+		return newURL, nil
+	}
+}
+
+func (s *urlService) shortenWithAlias(ctx context.Context, originalURL, canonicalURL string, opts domain.ShortenOptions) (*domain.URL, error) {
+	if err := s.aliasValidator.Validate(opts.Alias); err != nil {
+		return nil, aliasError(err)
+	}
+	// Such an alias would pass for a mistyped generated code.
+	if s.codeGenerator.Resembles(opts.Alias) && !s.codeGenerator.Validate(opts.Alias) {
+		return nil, domain.ErrInvalidAlias
+	}
+
+	newURL, err := s.newURL(originalURL, canonicalURL, opts.Alias, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	if err := s.repo.Save(ctx, newURL); err != nil {
+		if errors.Is(err, domain.ErrShortCodeTaken) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to save URL: %w", err)
 	}
 
+	s.recordIssued(ctx, opts.Alias)
 	return newURL, nil
 }
 
-func (s *urlService) Redirect(ctx context.Context, shortCode string) (string, error) {
-	if !s.codeGenerator.Validate(shortCode) {
-		return "", domain.ErrInvalidShortCode
+func (s *urlService) newURL(originalURL, canonicalURL, shortCode string, opts domain.ShortenOptions) (*domain.URL, error) {
+	newURL, err := domain.NewURL(originalURL, shortCode)
+	if err != nil {
+		return nil, err
 	}
 
-	url, err := s.repo.FindByShortCode(ctx, shortCode)
+	newURL.CanonicalURL = canonicalURL
+	newURL.OwnerID = opts.OwnerID
+	newURL.Domain = opts.Domain
+	newURL.ExpiresAt = opts.ExpiresAt
+	newURL.RedirectType = opts.RedirectType
+
+	return newURL, nil
+}
+
+func (s *urlService) Redirect(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	url, err := s.LookupURL(ctx, host, shortCode)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if url.IsExpired(time.Now()) {
+		return nil, domain.ErrURLExpired
 	}
 
 	go func() {
 		backgroundCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		if err := s.repo.IncrementClickCount(backgroundCtx, shortCode); err != nil {
+		if err := s.repo.IncrementClickCount(backgroundCtx, host, shortCode); err != nil {
 			log.Printf("Failed to increment click count: %v", err)
 		}
 	}()
 
-	return url.OriginalURL, nil
+	return url, nil
+}
+
+func (s *urlService) LookupURL(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	if !s.isValidCode(shortCode) {
+		return nil, domain.ErrInvalidShortCode
+	}
+
+	return s.repo.FindByShortCode(ctx, host, shortCode)
 }
 
-func (s *urlService) generateUniqueShortCode(ctx context.Context) (string, error) {
+func (s *urlService) GetURL(ctx context.Context, ownerID, host, shortCode string) (*domain.URL, error) {
+	if !s.isValidCode(shortCode) {
+		return nil, domain.ErrInvalidShortCode
+	}
+
+	return s.findOwned(ctx, ownerID, host, shortCode)
+}
+
+func (s *urlService) UpdateURL(ctx context.Context, ownerID, host, shortCode string, update domain.URLUpdate) (*domain.URL, error) {
+	if !s.isValidCode(shortCode) {
+		return nil, domain.ErrInvalidShortCode
+	}
+
+	url, err := s.findOwned(ctx, ownerID, host, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.OriginalURL != nil {
+		if err := s.checkDestination(ctx, *update.OriginalURL); err != nil {
+			return nil, err
+		}
+		canonicalURL, err := s.canonicalize(*update.OriginalURL)
+		if err != nil {
+			return nil, err
+		}
+		if canonicalURL != url.CanonicalURL {
+			url.Unshare()
+		}
+		url.OriginalURL = *update.OriginalURL
+		url.CanonicalURL = canonicalURL
+	}
+
+	// A link stops being shared once it is given an expiry; clearing the
+	// expiry does not share it again.
+	switch {
+	case update.ClearExpiry:
+		url.ExpiresAt = nil
+	case update.ExpiresAt != nil:
+		if !update.ExpiresAt.After(time.Now()) {
+			return nil, domain.ErrInvalidExpiry
+		}
+		url.ExpiresAt = update.ExpiresAt
+		url.Unshare()
+	}
+
+	if update.RedirectType != nil {
+		if !domain.ValidRedirectType(*update.RedirectType) {
+			return nil, domain.ErrInvalidRedirectType
+		}
+		if *update.RedirectType != url.RedirectStatus() {
+			url.Unshare()
+		}
+		url.RedirectType = *update.RedirectType
+	}
+
+	if err := s.repo.Update(ctx, url); err != nil {
+		return nil, err
+	}
+
+	return url, nil
+}
+
+func (s *urlService) DeleteURL(ctx context.Context, ownerID, host, shortCode string) error {
+	if !s.isValidCode(shortCode) {
+		return domain.ErrInvalidShortCode
+	}
+
+	return s.repo.Delete(ctx, ownerID, host, shortCode)
+}
+
+// findOwned loads a link and reports links owned by someone else as not
+// found, so callers cannot probe for other owners' codes.
+func (s *urlService) findOwned(ctx context.Context, ownerID, host, shortCode string) (*domain.URL, error) {
+	url, err := s.repo.FindByShortCode(ctx, host, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if url.OwnerID != ownerID {
+		return nil, domain.ErrURLNotFound
+	}
+	return url, nil
+}
+
+func (s *urlService) ListURLs(ctx context.Context, filter domain.ListFilter) (*domain.URLPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = domain.DefaultListLimit
+	}
+	if filter.Limit > domain.MaxListLimit {
+		filter.Limit = domain.MaxListLimit
+	}
+
+	return s.repo.List(ctx, filter)
+}
+
+// nextShortCode takes a code from the pool when there is one, and otherwise
+// generates one that is free on host. Pooled codes were free on every
+// domain when they were generated.
+func (s *urlService) nextShortCode(ctx context.Context, host string) (string, error) {
+	if s.pool != nil {
+		shortCode, err := s.pool.Take(ctx)
+		if err == nil {
+			return shortCode, nil
+		}
+		if !errors.Is(err, ports.ErrPoolEmpty) {
+			log.Printf("Failed to take short code from pool: %v", err)
+		}
+	}
+
+	return s.generateUniqueShortCode(ctx, host)
+}
+
+func (s *urlService) generateUniqueShortCode(ctx context.Context, host string) (string, error) {
 	const maxAttempts = 10
 
 	for i := 0; i < maxAttempts; i++ {
-		shortCode := s.codeGenerator.Generate()
+		shortCode, err := s.codeGenerator.Generate(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate short code: %w", err)
+		}
+
+		if s.codes != nil {
+			if maybe, err := s.codes.MayContain(ctx, shortCode); err == nil && !maybe {
+				return shortCode, nil
+			}
+		}
 
-		exists, err := s.repo.Exists(ctx, shortCode)
+		exists, err := s.repo.Exists(ctx, host, shortCode)
 		if err != nil {
 			return "", err
 		}
@@ -90,19 +375,86 @@ func (s *urlService) generateUniqueShortCode(ctx context.Context) (string, error
 	return "", fmt.Errorf("failed to generate unique short code after %d attempts", maxAttempts)
 }
 
-func (s *urlService) validateURL(rawURL string) error {
+func (s *urlService) recordIssued(ctx context.Context, shortCode string) {
+	if s.codes == nil {
+		return
+	}
+
+	if err := s.codes.Add(ctx, shortCode); err != nil {
+		log.Printf("Failed to add short code %s to filter: %v", shortCode, err)
+	}
+}
+
+// isValidCode accepts anything shaped like a generated code or a custom
+// alias. The generator's rules are not applied: links created before the
+// blocklist or check character changed must keep resolving, and a code
+// failing them was never issued, so its lookup finds nothing.
+func (s *urlService) isValidCode(code string) bool {
+	return s.codeGenerator.Resembles(code) || s.aliasValidator.Validate(code) == nil
+}
+
+// checkDestination validates a link's destination and passes it through
+// the screener. Rejections are returned as is, for the caller to report
+// their reason.
+func (s *urlService) checkDestination(ctx context.Context, rawURL string) error {
+	parsed, err := s.validateURL(rawURL)
+	if err != nil {
+		return err
+	}
+	if s.screener == nil {
+		return nil
+	}
+
+	if err := s.screener.Screen(ctx, parsed); err != nil {
+		if errors.Is(err, domain.ErrURLRejected) {
+			return err
+		}
+		return fmt.Errorf("failed to screen URL: %w", err)
+	}
+	return nil
+}
+
+// canonicalize returns the form a destination is deduplicated on, which is
+// the destination itself without a canonicalizer.
+func (s *urlService) canonicalize(rawURL string) (string, error) {
+	if s.canonicalizer == nil {
+		return rawURL, nil
+	}
+	canonicalURL, err := s.canonicalizer.Canonicalize(rawURL)
+	if errors.Is(err, canonical.ErrInvalidURL) {
+		return "", domain.ErrInvalidURL
+	}
+	return canonicalURL, err
+}
+
+// aliasError maps the alias validator's errors to the domain's.
+func aliasError(err error) error {
+	switch {
+	case errors.Is(err, shortcode.ErrInvalidAlias):
+		return domain.ErrInvalidAlias
+	case errors.Is(err, shortcode.ErrReservedAlias):
+		return domain.ErrReservedAlias
+	}
+	return err
+}
+
+func (s *urlService) validateURL(rawURL string) (*url.URL, error) {
+	if len(rawURL) > s.maxURLLength {
+		return nil, domain.ErrURLTooLong
+	}
+
 	parsed, err := url.Parse(rawURL)
 	if err != nil {
-		return domain.ErrInvalidURL
+		return nil, domain.ErrInvalidURL
 	}
 
 	if parsed.Scheme == "" || parsed.Host == "" {
-		return domain.ErrInvalidURL
+		return nil, domain.ErrInvalidURL
 	}
 
 	if parsed.Scheme != "http" && parsed.Scheme != "https" {
-		return domain.ErrInvalidURL
+		return nil, domain.ErrInvalidURL
 	}
 
-	return nil
+	return parsed, nil
 }
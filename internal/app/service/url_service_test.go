@@ -2,14 +2,21 @@ package service_test
 
 import (
 	"context"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/mikiasyonas/url-shortener/internal/app/service"
 	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+	"github.com/mikiasyonas/url-shortener/internal/core/ports"
+	"github.com/mikiasyonas/url-shortener/pkg/canonical"
+	"github.com/mikiasyonas/url-shortener/pkg/shortcode"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockRepository struct {
@@ -25,29 +32,86 @@ func (m *MockRepository) Save(ctx context.Context, url *domain.URL) error {
 	return args.Error(0)
 }
 
-func (m *MockRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
-	args := m.Called(ctx, shortCode)
+func (m *MockRepository) SaveShared(ctx context.Context, url *domain.URL) (*domain.URL, error) {
+	args := m.Called(ctx, url)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.URL), args.Error(1)
 }
 
-func (m *MockRepository) FindByOriginalURL(ctx context.Context, originalURL string) (*domain.URL, error) {
-	args := m.Called(ctx, originalURL)
+func (m *MockRepository) FindByShortCode(ctx context.Context, host, shortCode string) (*domain.URL, error) {
+	args := m.Called(ctx, host, shortCode)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.URL), args.Error(1)
 }
 
-func (m *MockRepository) Exists(ctx context.Context, shortCode string) (bool, error) {
-	args := m.Called(ctx, shortCode)
+func (m *MockRepository) FindByCanonicalURL(ctx context.Context, ownerID, host, canonicalURL string, redirectType int) (*domain.URL, error) {
+	args := m.Called(ctx, ownerID, host, canonicalURL, redirectType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
+func (m *MockRepository) Exists(ctx context.Context, host, shortCode string) (bool, error) {
+	args := m.Called(ctx, host, shortCode)
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockRepository) IncrementClickCount(ctx context.Context, shortCode string) error {
+func (m *MockRepository) ExistsOnAnyDomain(ctx context.Context, shortCode string) (bool, error) {
 	args := m.Called(ctx, shortCode)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) IncrementClickCount(ctx context.Context, host, shortCode string) error {
+	args := m.Called(ctx, host, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockRepository) AddClickCounts(ctx context.Context, counts map[domain.LinkRef]int64) error {
+	args := m.Called(ctx, counts)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Update(ctx context.Context, url *domain.URL) error {
+	args := m.Called(ctx, url)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Delete(ctx context.Context, ownerID, host, shortCode string) error {
+	args := m.Called(ctx, ownerID, host, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockRepository) List(ctx context.Context, filter domain.ListFilter) (*domain.URLPage, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URLPage), args.Error(1)
+}
+
+func (m *MockRepository) ShortCodes(ctx context.Context, createdSince time.Time, after string, limit int) ([]string, error) {
+	args := m.Called(ctx, createdSince, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRepository) ClaimLinksToCheck(ctx context.Context, now, nextCheckAt time.Time, limit int) ([]*domain.URL, error) {
+	args := m.Called(ctx, now, nextCheckAt, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.URL), args.Error(1)
+}
+
+func (m *MockRepository) RecordLinkCheck(ctx context.Context, id string, status int, checkedAt time.Time) error {
+	args := m.Called(ctx, id, status, checkedAt)
 	return args.Error(0)
 }
 
@@ -55,9 +119,9 @@ type MockShortCodeGenerator struct {
 	mock.Mock
 }
 
-func (m *MockShortCodeGenerator) Generate() string {
-	args := m.Called()
-	return args.String(0)
+func (m *MockShortCodeGenerator) Generate(ctx context.Context) (string, error) {
+	args := m.Called(ctx)
+	return args.String(0), args.Error(1)
 }
 
 func (m *MockShortCodeGenerator) Validate(code string) bool {
@@ -65,24 +129,34 @@ func (m *MockShortCodeGenerator) Validate(code string) bool {
 	return args.Bool(0)
 }
 
+func (m *MockShortCodeGenerator) Resembles(code string) bool {
+	args := m.Called(code)
+	return args.Bool(0)
+}
+
+func newAliasValidator() *shortcode.AliasValidator {
+	return shortcode.NewAliasValidator(3, 32, []string{"api", "health"})
+}
+
 func TestURLService_ShortenURL_Success(t *testing.T) {
 	ctx := context.Background()
 	mockRepo := new(MockRepository)
 	mockGenerator := new(MockShortCodeGenerator)
 
-	service := service.NewURLService(mockRepo, mockGenerator)
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
 
-	mockRepo.On("FindByOriginalURL", ctx, "https://example.com").Return((*domain.URL)(nil), domain.ErrURLNotFound)
-	mockGenerator.On("Generate").Return("abc123")
-	mockRepo.On("Exists", ctx, "abc123").Return(false, nil)
-	mockRepo.On("Save", ctx, mock.AnythingOfType("*domain.URL")).Return(nil)
+	mockRepo.On("FindByCanonicalURL", ctx, "", "", "https://example.com", domain.RedirectFound).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	mockGenerator.On("Generate", mock.Anything).Return("abc123", nil)
+	mockRepo.On("Exists", ctx, "", "abc123").Return(false, nil)
+	mockRepo.On("SaveShared", ctx, mock.AnythingOfType("*domain.URL")).Return((*domain.URL)(nil), nil)
 
-	result, err := service.ShortenURL(ctx, "https://example.com")
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{})
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.Equal(t, "https://example.com", result.OriginalURL)
 	assert.Equal(t, "abc123", result.ShortCode)
+	assert.True(t, result.IsShared())
 
 	mockRepo.AssertExpectations(t)
 	mockGenerator.AssertExpectations(t)
@@ -93,16 +167,16 @@ func TestURLService_ShortenURL_ExistingURL(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockGenerator := new(MockShortCodeGenerator)
 
-	service := service.NewURLService(mockRepo, mockGenerator)
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
 
 	existingURL := &domain.URL{
 		OriginalURL: "https://example.com",
 		ShortCode:   "existing",
 	}
 
-	mockRepo.On("FindByOriginalURL", ctx, "https://example.com").Return(existingURL, nil)
+	mockRepo.On("FindByCanonicalURL", ctx, "", "", "https://example.com", domain.RedirectFound).Return(existingURL, nil)
 
-	result, err := service.ShortenURL(ctx, "https://example.com")
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, existingURL, result)
@@ -116,7 +190,7 @@ func TestURLService_ShortenURL_InvalidURL(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockGenerator := new(MockShortCodeGenerator)
 
-	service := service.NewURLService(mockRepo, mockGenerator)
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
 
 	invalidURLs := []string{
 		"",
@@ -127,7 +201,7 @@ func TestURLService_ShortenURL_InvalidURL(t *testing.T) {
 
 	for _, invalidURL := range invalidURLs {
 		t.Run(invalidURL, func(t *testing.T) {
-			result, err := service.ShortenURL(ctx, invalidURL)
+			result, err := service.ShortenURL(ctx, invalidURL, domain.ShortenOptions{})
 			assert.ErrorIs(t, err, domain.ErrInvalidURL)
 			assert.Nil(t, result)
 		})
@@ -139,7 +213,7 @@ func TestURLService_Redirect_Success(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockGenerator := new(MockShortCodeGenerator)
 
-	service := service.NewURLService(mockRepo, mockGenerator)
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
 
 	expectedURL := &domain.URL{
 		OriginalURL: "https://example.com",
@@ -147,16 +221,16 @@ func TestURLService_Redirect_Success(t *testing.T) {
 	}
 
 	incrementCalled := make(chan bool, 1)
-	mockGenerator.On("Validate", "abc123").Return(true)
-	mockRepo.On("FindByShortCode", ctx, "abc123").Return(expectedURL, nil)
-	mockRepo.On("IncrementClickCount", mock.Anything, "abc123").Return(nil).Run(func(args mock.Arguments) {
+	mockGenerator.On("Resembles", "abc123").Return(true)
+	mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(expectedURL, nil)
+	mockRepo.On("IncrementClickCount", mock.Anything, "", "abc123").Return(nil).Run(func(args mock.Arguments) {
 		incrementCalled <- true
 	})
 
-	originalURL, err := service.Redirect(ctx, "abc123")
+	result, err := service.Redirect(ctx, "", "abc123")
 
 	assert.NoError(t, err)
-	assert.Equal(t, "https://example.com", originalURL)
+	assert.Equal(t, "https://example.com", result.OriginalURL)
 
 	select {
 	case <-incrementCalled:
@@ -174,14 +248,14 @@ func TestURLService_Redirect_InvalidShortCode(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockGenerator := new(MockShortCodeGenerator)
 
-	service := service.NewURLService(mockRepo, mockGenerator)
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
 
-	mockGenerator.On("Validate", "invalid").Return(false)
+	mockGenerator.On("Resembles", "in valid!").Return(false)
 
-	originalURL, err := service.Redirect(ctx, "invalid")
+	result, err := service.Redirect(ctx, "", "in valid!")
 
 	assert.ErrorIs(t, err, domain.ErrInvalidShortCode)
-	assert.Equal(t, "", originalURL)
+	assert.Nil(t, result)
 
 	mockRepo.AssertExpectations(t)
 	mockGenerator.AssertExpectations(t)
@@ -192,16 +266,913 @@ func TestURLService_Redirect_NotFound(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockGenerator := new(MockShortCodeGenerator)
 
-	service := service.NewURLService(mockRepo, mockGenerator)
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
 
-	mockGenerator.On("Validate", "notfound").Return(true)
-	mockRepo.On("FindByShortCode", ctx, "notfound").Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	mockGenerator.On("Resembles", "notfound").Return(true)
+	mockRepo.On("FindByShortCode", ctx, "", "notfound").Return((*domain.URL)(nil), domain.ErrURLNotFound)
 
-	originalURL, err := service.Redirect(ctx, "notfound")
+	result, err := service.Redirect(ctx, "", "notfound")
 
 	assert.ErrorIs(t, err, domain.ErrURLNotFound)
-	assert.Equal(t, "", originalURL)
+	assert.Nil(t, result)
+
+	mockRepo.AssertExpectations(t)
+	mockGenerator.AssertExpectations(t)
+}
+
+func TestURLService_ShortenURL_WithAlias(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	mockGenerator.On("Resembles", "spring-sale").Return(false)
+	mockRepo.On("Save", ctx, mock.AnythingOfType("*domain.URL")).Return(nil)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{Alias: "spring-sale"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "spring-sale", result.ShortCode)
+	assert.Equal(t, "https://example.com", result.OriginalURL)
+	assert.False(t, result.IsShared())
 
+	mockRepo.AssertNotCalled(t, "FindByCanonicalURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 	mockRepo.AssertExpectations(t)
 	mockGenerator.AssertExpectations(t)
 }
+
+func TestURLService_ShortenURL_AliasTaken(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	mockGenerator.On("Resembles", "spring-sale").Return(false)
+	mockRepo.On("Save", ctx, mock.AnythingOfType("*domain.URL")).Return(domain.ErrShortCodeTaken)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{Alias: "spring-sale"})
+
+	assert.ErrorIs(t, err, domain.ErrShortCodeTaken)
+	assert.Nil(t, result)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestURLService_ShortenURL_InvalidAlias(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	testCases := map[string]error{
+		"ab":          domain.ErrInvalidAlias,
+		"-spring":     domain.ErrInvalidAlias,
+		"spring sale": domain.ErrInvalidAlias,
+		"spring/sale": domain.ErrInvalidAlias,
+		"api":         domain.ErrReservedAlias,
+		"Health":      domain.ErrReservedAlias,
+		"this-alias-is-way-too-long-to-be-accepted": domain.ErrInvalidAlias,
+	}
+
+	for alias, expectedErr := range testCases {
+		t.Run(alias, func(t *testing.T) {
+			result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{Alias: alias})
+			assert.ErrorIs(t, err, expectedErr)
+			assert.Nil(t, result)
+		})
+	}
+
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestURLService_Redirect_Alias(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	expectedURL := &domain.URL{
+		OriginalURL: "https://example.com/spring",
+		ShortCode:   "spring-sale",
+	}
+
+	mockGenerator.On("Resembles", "spring-sale").Return(false)
+	mockRepo.On("FindByShortCode", ctx, "", "spring-sale").Return(expectedURL, nil)
+	mockRepo.On("IncrementClickCount", mock.Anything, "", "spring-sale").Return(nil).Maybe()
+
+	result, err := service.Redirect(ctx, "", "spring-sale")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/spring", result.OriginalURL)
+}
+
+func TestURLService_CheckCharacter(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	generator := shortcode.NewGenerator(6, shortcode.WithCheckCharacter(), shortcode.WithBlocklist([]string{"abc"}))
+
+	service := service.NewURLService(mockRepo, generator, newAliasValidator())
+
+	code, err := generator.Generate(ctx)
+	require.NoError(t, err)
+	typo := "x" + code[1:]
+	if typo == code {
+		typo = "y" + code[1:]
+	}
+
+	_, err = service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{Alias: typo})
+	assert.ErrorIs(t, err, domain.ErrInvalidAlias, "an alias that passes for a mistyped code is refused")
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+
+	// A mistyped code was never issued, so its lookup finds nothing.
+	mockRepo.On("FindByShortCode", ctx, "", typo).Return(nil, domain.ErrURLNotFound)
+	_, err = service.Redirect(ctx, "", typo)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	// Links created before the check character or blocklist keep resolving.
+	legacy := &domain.URL{OriginalURL: "https://example.com/old", ShortCode: "abc123"}
+	mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(legacy, nil)
+	mockRepo.On("IncrementClickCount", mock.Anything, "", "abc123").Return(nil).Maybe()
+	result, err := service.Redirect(ctx, "", "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/old", result.OriginalURL)
+}
+
+func TestURLService_ShortenURL_WithExpiry(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	mockGenerator.On("Generate", mock.Anything).Return("abc123", nil)
+	mockRepo.On("Exists", ctx, "", "abc123").Return(false, nil)
+	mockRepo.On("Save", ctx, mock.AnythingOfType("*domain.URL")).Return(nil)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{ExpiresAt: &expiresAt})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &expiresAt, result.ExpiresAt)
+	assert.False(t, result.IsShared())
+
+	mockRepo.AssertNotCalled(t, "FindByCanonicalURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+	mockGenerator.AssertExpectations(t)
+}
+
+func TestURLService_ShortenURL_ExpiryInPast(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	expiresAt := time.Now().Add(-time.Minute)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{ExpiresAt: &expiresAt})
+
+	assert.ErrorIs(t, err, domain.ErrInvalidExpiry)
+	assert.Nil(t, result)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestURLService_Redirect_Expired(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	expiresAt := time.Now().Add(-time.Hour)
+	expiredURL := &domain.URL{
+		OriginalURL: "https://example.com",
+		ShortCode:   "abc123",
+		ExpiresAt:   &expiresAt,
+	}
+
+	mockGenerator.On("Resembles", "abc123").Return(true)
+	mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(expiredURL, nil)
+
+	result, err := service.Redirect(ctx, "", "abc123")
+
+	assert.ErrorIs(t, err, domain.ErrURLExpired)
+	assert.Nil(t, result)
+
+	mockRepo.AssertNotCalled(t, "IncrementClickCount", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestURLService_UpdateURL_Success(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	expiresAt := time.Now().Add(time.Hour)
+	existing := &domain.URL{
+		OriginalURL: "https://example.com",
+		ShortCode:   "abc123",
+		ExpiresAt:   &expiresAt,
+	}
+	newTarget := "https://example.org/new"
+
+	mockGenerator.On("Resembles", "abc123").Return(true)
+	mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(existing, nil)
+	mockRepo.On("Update", ctx, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.OriginalURL == newTarget && url.ExpiresAt == nil
+	})).Return(nil)
+
+	result, err := service.UpdateURL(ctx, "", "", "abc123", domain.URLUpdate{
+		OriginalURL: &newTarget,
+		ClearExpiry: true,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, newTarget, result.OriginalURL)
+	assert.Nil(t, result.ExpiresAt)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestURLService_UpdateURL_InvalidURL(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	existing := &domain.URL{OriginalURL: "https://example.com", ShortCode: "abc123"}
+	newTarget := "ftp://example.com"
+
+	mockGenerator.On("Resembles", "abc123").Return(true)
+	mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(existing, nil)
+
+	result, err := service.UpdateURL(ctx, "", "", "abc123", domain.URLUpdate{OriginalURL: &newTarget})
+
+	assert.ErrorIs(t, err, domain.ErrInvalidURL)
+	assert.Nil(t, result)
+
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestURLService_DeleteURL_NotFound(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	mockGenerator.On("Resembles", "abc123").Return(true)
+	mockRepo.On("Delete", ctx, "", "", "abc123").Return(domain.ErrURLNotFound)
+
+	err := service.DeleteURL(ctx, "", "", "abc123")
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestURLService_ListURLs_ClampsLimit(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	page := &domain.URLPage{}
+	mockRepo.On("List", ctx, domain.ListFilter{Limit: domain.MaxListLimit}).Return(page, nil)
+	mockRepo.On("List", ctx, domain.ListFilter{Limit: domain.DefaultListLimit}).Return(page, nil)
+
+	_, err := service.ListURLs(ctx, domain.ListFilter{Limit: 10000})
+	assert.NoError(t, err)
+
+	_, err = service.ListURLs(ctx, domain.ListFilter{})
+	assert.NoError(t, err)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestURLService_GetURL_OtherOwner(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	existing := &domain.URL{OriginalURL: "https://example.com", ShortCode: "abc123", OwnerID: "owner-1"}
+
+	mockGenerator.On("Resembles", "abc123").Return(true)
+	mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(existing, nil)
+
+	result, err := service.GetURL(ctx, "owner-2", "", "abc123")
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	assert.Nil(t, result)
+
+	result, err = service.GetURL(ctx, "owner-1", "", "abc123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, existing, result)
+}
+
+func TestURLService_UpdateURL_OtherOwner(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	existing := &domain.URL{OriginalURL: "https://example.com", ShortCode: "abc123", OwnerID: "owner-1"}
+	newTarget := "https://example.org"
+
+	mockGenerator.On("Resembles", "abc123").Return(true)
+	mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(existing, nil)
+
+	_, err := service.UpdateURL(ctx, "owner-2", "", "abc123", domain.URLUpdate{OriginalURL: &newTarget})
+
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestURLService_ShortenURL_DeduplicatesPerOwner(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	mockRepo.On("FindByCanonicalURL", ctx, "owner-1", "", "https://example.com", domain.RedirectFound).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	mockGenerator.On("Generate", mock.Anything).Return("abc123", nil)
+	mockRepo.On("Exists", ctx, "", "abc123").Return(false, nil)
+	mockRepo.On("SaveShared", ctx, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.OwnerID == "owner-1"
+	})).Return((*domain.URL)(nil), nil)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{OwnerID: "owner-1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "owner-1", result.OwnerID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestURLService_ShortenURL_Domain(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	mockRepo.On("FindByCanonicalURL", ctx, "", "go.example.com", "https://example.com", domain.RedirectFound).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	mockGenerator.On("Generate", mock.Anything).Return("abc123", nil)
+	mockRepo.On("Exists", ctx, "go.example.com", "abc123").Return(false, nil)
+	mockRepo.On("SaveShared", ctx, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.Domain == "go.example.com" && url.ShortCode == "abc123"
+	})).Return((*domain.URL)(nil), nil)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{Domain: "go.example.com"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "go.example.com", result.Domain)
+	mockRepo.AssertExpectations(t)
+}
+
+type MockShortCodeFilter struct {
+	mock.Mock
+}
+
+func (m *MockShortCodeFilter) Add(ctx context.Context, shortCode string) error {
+	args := m.Called(ctx, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockShortCodeFilter) MayContain(ctx context.Context, shortCode string) (bool, error) {
+	args := m.Called(ctx, shortCode)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestURLService_ShortenURL_RedirectType(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	// Links are shared per redirect type, so a permanent link to a URL that
+	// already has a 302 link needs a code of its own.
+	mockRepo.On("FindByCanonicalURL", ctx, "", "", "https://example.com", domain.RedirectMovedPermanently).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	mockGenerator.On("Generate", mock.Anything).Return("abc123", nil)
+	mockRepo.On("Exists", ctx, "", "abc123").Return(false, nil)
+	mockRepo.On("SaveShared", ctx, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.ShortCode == "abc123" && url.RedirectType == domain.RedirectMovedPermanently
+	})).Return((*domain.URL)(nil), nil)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{RedirectType: domain.RedirectMovedPermanently})
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.RedirectMovedPermanently, result.RedirectType)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestURLService_ShortenURL_InvalidRedirectType(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{RedirectType: 303})
+
+	assert.ErrorIs(t, err, domain.ErrInvalidRedirectType)
+	assert.Nil(t, result)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestURLService_UpdateURL_RedirectType(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	mockGenerator.On("Resembles", "abc123").Return(true)
+	mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(&domain.URL{OriginalURL: "https://example.com", ShortCode: "abc123"}, nil)
+	mockRepo.On("Update", ctx, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.RedirectType == domain.RedirectPermanent
+	})).Return(nil).Once()
+
+	permanent, invalid := domain.RedirectPermanent, 304
+
+	result, err := service.UpdateURL(ctx, "", "", "abc123", domain.URLUpdate{RedirectType: &permanent})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.RedirectPermanent, result.RedirectType)
+
+	_, err = service.UpdateURL(ctx, "", "", "abc123", domain.URLUpdate{RedirectType: &invalid})
+	assert.ErrorIs(t, err, domain.ErrInvalidRedirectType)
+
+	mockRepo.AssertExpectations(t)
+}
+
+type MockURLScreener struct {
+	mock.Mock
+}
+
+func (m *MockURLScreener) Screen(ctx context.Context, target *url.URL) error {
+	args := m.Called(ctx, target.String())
+	return args.Error(0)
+}
+
+func TestURLService_ShortenURL_Rejected(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+	mockScreener := new(MockURLScreener)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithScreener(mockScreener))
+
+	mockScreener.On("Screen", ctx, "https://evil.example/login").Return(domain.RejectURL("destination domain is blocklisted"))
+
+	result, err := service.ShortenURL(ctx, "https://evil.example/login", domain.ShortenOptions{})
+
+	var rejection *domain.URLRejectedError
+	require.ErrorAs(t, err, &rejection)
+	assert.ErrorIs(t, err, domain.ErrURLRejected)
+	assert.Equal(t, "destination domain is blocklisted", rejection.Reason)
+	assert.Nil(t, result)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestURLService_ShortenURL_ScreenerFailure(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+	mockScreener := new(MockURLScreener)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithScreener(mockScreener))
+
+	mockScreener.On("Screen", ctx, "https://example.com").Return(assert.AnError)
+
+	_, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{})
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NotErrorIs(t, err, domain.ErrURLRejected)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestURLService_UpdateURL_Rejected(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+	mockScreener := new(MockURLScreener)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithScreener(mockScreener))
+
+	newTarget := "http://127.0.0.1/admin"
+	mockGenerator.On("Resembles", "abc123").Return(true)
+	mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(&domain.URL{OriginalURL: "https://example.com", ShortCode: "abc123"}, nil)
+	mockScreener.On("Screen", ctx, newTarget).Return(domain.RejectURL("destination is an IP address"))
+
+	_, err := service.UpdateURL(ctx, "", "", "abc123", domain.URLUpdate{OriginalURL: &newTarget})
+
+	assert.ErrorIs(t, err, domain.ErrURLRejected)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestURLService_ShortenURL_DeduplicatesCanonicalURL(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithCanonicalizer(canonical.NewCanonicalizer(canonical.WithStrippedParams([]string{"utm_*"}))))
+
+	existing := &domain.URL{
+		OriginalURL:  "https://example.com/?utm_source=mail",
+		CanonicalURL: "https://example.com/",
+		ShortCode:    "existing",
+	}
+	mockRepo.On("FindByCanonicalURL", ctx, "", "", "https://example.com/", domain.RedirectFound).Return(existing, nil)
+
+	result, err := service.ShortenURL(ctx, "HTTPS://Example.com:443?utm_campaign=launch", domain.ShortenOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, existing, result)
+	mockGenerator.AssertNotCalled(t, "Generate", mock.Anything)
+}
+
+func TestURLService_ShortenURL_KeepsOriginalSpelling(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithCanonicalizer(canonical.NewCanonicalizer()))
+
+	mockRepo.On("FindByCanonicalURL", ctx, "", "", "https://example.com/a%2Fb", domain.RedirectFound).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	mockGenerator.On("Generate", mock.Anything).Return("abc123", nil)
+	mockRepo.On("Exists", ctx, "", "abc123").Return(false, nil)
+	mockRepo.On("SaveShared", ctx, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.OriginalURL == "https://Example.com/a%2fb" && url.CanonicalURL == "https://example.com/a%2Fb"
+	})).Return((*domain.URL)(nil), nil)
+
+	result, err := service.ShortenURL(ctx, "https://Example.com/a%2fb", domain.ShortenOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://Example.com/a%2fb", result.OriginalURL)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestURLService_UpdateURL_RecanonicalizesTarget(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithCanonicalizer(canonical.NewCanonicalizer()))
+
+	newTarget := "HTTPS://Example.org"
+	mockGenerator.On("Resembles", "abc123").Return(true)
+	mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(&domain.URL{
+		OriginalURL:  "https://example.com",
+		CanonicalURL: "https://example.com/",
+		ShortCode:    "abc123",
+	}, nil)
+	mockRepo.On("Update", ctx, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.OriginalURL == newTarget && url.CanonicalURL == "https://example.org/"
+	})).Return(nil)
+
+	_, err := service.UpdateURL(ctx, "", "", "abc123", domain.URLUpdate{OriginalURL: &newTarget})
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestURLService_ShortenURL_TooLong(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithMaxURLLength(32))
+
+	_, err := service.ShortenURL(ctx, "https://example.com/"+strings.Repeat("a", 13), domain.ShortenOptions{})
+	assert.ErrorIs(t, err, domain.ErrURLTooLong)
+
+	mockGenerator.On("Resembles", "abc123").Return(true)
+	mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(&domain.URL{OriginalURL: "https://example.com", ShortCode: "abc123"}, nil)
+	newTarget := "https://example.org/" + strings.Repeat("a", 13)
+
+	_, err = service.UpdateURL(ctx, "", "", "abc123", domain.URLUpdate{OriginalURL: &newTarget})
+	assert.ErrorIs(t, err, domain.ErrURLTooLong)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestURLService_UpdateURL_Unshares(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	permanent := domain.RedirectPermanent
+	retarget := "https://example.org"
+	respell := "HTTPS://Example.com"
+
+	tests := []struct {
+		name   string
+		update domain.URLUpdate
+		shared bool
+	}{
+		{"new expiry", domain.URLUpdate{ExpiresAt: &expiresAt}, false},
+		{"new redirect type", domain.URLUpdate{RedirectType: &permanent}, false},
+		{"new destination", domain.URLUpdate{OriginalURL: &retarget}, false},
+		{"same destination respelled", domain.URLUpdate{OriginalURL: &respell}, true},
+		{"expiry cleared", domain.URLUpdate{ClearExpiry: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockRepo := new(MockRepository)
+			mockGenerator := new(MockShortCodeGenerator)
+
+			service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithCanonicalizer(canonical.NewCanonicalizer()))
+
+			existing := &domain.URL{OriginalURL: "https://example.com", CanonicalURL: "https://example.com/", ShortCode: "abc123"}
+			existing.Share()
+			mockGenerator.On("Resembles", "abc123").Return(true)
+			mockRepo.On("FindByShortCode", ctx, "", "abc123").Return(existing, nil)
+			mockRepo.On("Update", ctx, mock.AnythingOfType("*domain.URL")).Return(nil)
+
+			result, err := service.UpdateURL(ctx, "", "", "abc123", tt.update)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.shared, result.IsShared())
+		})
+	}
+}
+
+func TestURLService_ShortenURL_RetriesTakenCode(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator())
+
+	// abc123 is claimed between the collision check and the insert.
+	mockRepo.On("FindByCanonicalURL", ctx, "", "", "https://example.com", domain.RedirectFound).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	mockGenerator.On("Generate", mock.Anything).Return("abc123", nil).Once()
+	mockGenerator.On("Generate", mock.Anything).Return("def456", nil).Once()
+	mockRepo.On("Exists", ctx, "", mock.Anything).Return(false, nil)
+	mockRepo.On("SaveShared", ctx, mock.MatchedBy(func(u *domain.URL) bool { return u.ShortCode == "abc123" })).Return((*domain.URL)(nil), domain.ErrShortCodeTaken)
+	mockRepo.On("SaveShared", ctx, mock.MatchedBy(func(u *domain.URL) bool { return u.ShortCode == "def456" })).Return((*domain.URL)(nil), nil)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, "def456", result.ShortCode)
+	mockGenerator.AssertExpectations(t)
+}
+
+func TestURLService_ShortenURL_ReturnsLinkSharedConcurrently(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+	mockFilter := new(MockShortCodeFilter)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithShortCodeFilter(mockFilter))
+
+	winner := &domain.URL{OriginalURL: "https://example.com", ShortCode: "first1"}
+	mockRepo.On("FindByCanonicalURL", ctx, "", "", "https://example.com", domain.RedirectFound).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	mockGenerator.On("Generate", mock.Anything).Return("abc123", nil)
+	mockFilter.On("MayContain", ctx, "abc123").Return(false, nil)
+	mockRepo.On("SaveShared", ctx, mock.AnythingOfType("*domain.URL")).Return(winner, nil)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, winner, result)
+	mockFilter.AssertNotCalled(t, "Add", mock.Anything, mock.Anything)
+}
+
+// sharingRepository shares links the way the unique index on shared links
+// does: the first link saved for a destination wins and later callers get it
+// back.
+type sharingRepository struct {
+	*MockRepository
+	mu     sync.Mutex
+	shared map[string]*domain.URL
+	saved  int
+}
+
+func (r *sharingRepository) SaveShared(ctx context.Context, url *domain.URL) (*domain.URL, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := url.OwnerID + "|" + url.Domain + "|" + string(url.CanonicalURLHash)
+	if existing, ok := r.shared[key]; ok {
+		return existing, nil
+	}
+	r.shared[key] = url
+	r.saved++
+	return nil, nil
+}
+
+func TestURLService_ShortenURL_ConcurrentCallsShareOneLink(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	repo := &sharingRepository{MockRepository: mockRepo, shared: make(map[string]*domain.URL)}
+
+	service := service.NewURLService(repo, shortcode.NewGenerator(6), newAliasValidator(), service.WithCanonicalizer(canonical.NewCanonicalizer()))
+
+	// Every caller misses the lookup, as when they all arrive before the
+	// first link is saved.
+	mockRepo.On("FindByCanonicalURL", ctx, "", "", "https://example.com/", domain.RedirectFound).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	mockRepo.On("Exists", ctx, "", mock.Anything).Return(false, nil)
+
+	const callers = 20
+	results := make([]*domain.URL, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			spelling := "https://example.com"
+			if i%2 == 1 {
+				spelling = "HTTPS://EXAMPLE.COM:443/"
+			}
+			results[i], errs[i] = service.ShortenURL(ctx, spelling, domain.ShortenOptions{})
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < callers; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, results[0].ShortCode, results[i].ShortCode)
+	}
+	assert.Equal(t, 1, repo.saved)
+}
+
+func TestURLService_ShortenURL_FilterSkipsCollisionQuery(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+	mockFilter := new(MockShortCodeFilter)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithShortCodeFilter(mockFilter))
+
+	mockRepo.On("FindByCanonicalURL", ctx, "", "", "https://example.com", domain.RedirectFound).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	mockGenerator.On("Generate", mock.Anything).Return("abc123", nil)
+	mockFilter.On("MayContain", ctx, "abc123").Return(false, nil)
+	mockRepo.On("SaveShared", ctx, mock.AnythingOfType("*domain.URL")).Return((*domain.URL)(nil), nil)
+	mockFilter.On("Add", ctx, "abc123").Return(nil)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", result.ShortCode)
+	mockRepo.AssertNotCalled(t, "Exists", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+	mockFilter.AssertExpectations(t)
+}
+
+func TestURLService_ShortenURL_FilterMaybeQueriesDatabase(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+	mockFilter := new(MockShortCodeFilter)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithShortCodeFilter(mockFilter))
+
+	mockRepo.On("FindByCanonicalURL", ctx, "", "", "https://example.com", domain.RedirectFound).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	mockGenerator.On("Generate", mock.Anything).Return("abc123", nil).Once()
+	mockGenerator.On("Generate", mock.Anything).Return("def456", nil).Once()
+	mockFilter.On("MayContain", ctx, "abc123").Return(true, nil)
+	mockRepo.On("Exists", ctx, "", "abc123").Return(true, nil)
+	mockFilter.On("MayContain", ctx, "def456").Return(true, nil)
+	mockRepo.On("Exists", ctx, "", "def456").Return(false, nil)
+	mockRepo.On("SaveShared", ctx, mock.AnythingOfType("*domain.URL")).Return((*domain.URL)(nil), nil)
+	mockFilter.On("Add", ctx, "def456").Return(nil)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "def456", result.ShortCode)
+	mockRepo.AssertExpectations(t)
+	mockFilter.AssertExpectations(t)
+}
+
+func TestURLService_ShortenURL_AliasAddedToFilter(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+	mockFilter := new(MockShortCodeFilter)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithShortCodeFilter(mockFilter))
+
+	mockGenerator.On("Resembles", "promo").Return(false)
+	mockRepo.On("Save", ctx, mock.AnythingOfType("*domain.URL")).Return(nil)
+	mockFilter.On("Add", ctx, "promo").Return(assert.AnError)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{Alias: "promo"})
+
+	assert.NoError(t, err, "a filter failure must not fail the request")
+	assert.Equal(t, "promo", result.ShortCode)
+	mockFilter.AssertExpectations(t)
+}
+
+type MockShortCodePool struct {
+	mock.Mock
+}
+
+func (m *MockShortCodePool) Take(ctx context.Context) (string, error) {
+	args := m.Called(ctx)
+	return args.String(0), args.Error(1)
+}
+
+func TestURLService_ShortenURL_UsesPooledCode(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+	mockPool := new(MockShortCodePool)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithShortCodePool(mockPool))
+
+	mockRepo.On("FindByCanonicalURL", ctx, "", "", "https://example.com", domain.RedirectFound).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	mockPool.On("Take", ctx).Return("pool01", nil)
+	mockRepo.On("SaveShared", ctx, mock.AnythingOfType("*domain.URL")).Return((*domain.URL)(nil), nil)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "pool01", result.ShortCode)
+	mockGenerator.AssertNotCalled(t, "Generate", mock.Anything)
+	mockRepo.AssertNotCalled(t, "Exists", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestURLService_ShortenURL_SkipsPooledCodeClaimedByAlias(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+	mockPool := new(MockShortCodePool)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithShortCodePool(mockPool))
+
+	mockRepo.On("FindByCanonicalURL", ctx, "", "", "https://example.com", domain.RedirectFound).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	mockPool.On("Take", ctx).Return("pool01", nil).Once()
+	mockPool.On("Take", ctx).Return("pool02", nil).Once()
+	mockRepo.On("SaveShared", ctx, mock.MatchedBy(func(u *domain.URL) bool { return u.ShortCode == "pool01" })).Return((*domain.URL)(nil), domain.ErrShortCodeTaken)
+	mockRepo.On("SaveShared", ctx, mock.MatchedBy(func(u *domain.URL) bool { return u.ShortCode == "pool02" })).Return((*domain.URL)(nil), nil)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "pool02", result.ShortCode)
+	mockPool.AssertExpectations(t)
+}
+
+func TestURLService_ShortenURL_EmptyPoolFallsBackToGenerator(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	mockGenerator := new(MockShortCodeGenerator)
+	mockPool := new(MockShortCodePool)
+
+	service := service.NewURLService(mockRepo, mockGenerator, newAliasValidator(), service.WithShortCodePool(mockPool))
+
+	mockRepo.On("FindByCanonicalURL", ctx, "", "", "https://example.com", domain.RedirectFound).Return((*domain.URL)(nil), domain.ErrURLNotFound)
+	mockPool.On("Take", ctx).Return("", ports.ErrPoolEmpty)
+	mockGenerator.On("Generate", mock.Anything).Return("abc123", nil)
+	mockRepo.On("Exists", ctx, "", "abc123").Return(false, nil)
+	mockRepo.On("SaveShared", ctx, mock.AnythingOfType("*domain.URL")).Return((*domain.URL)(nil), nil)
+
+	result, err := service.ShortenURL(ctx, "https://example.com", domain.ShortenOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", result.ShortCode)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGuardedURLService_Redirect(t *testing.T) {
+	ctx := context.Background()
+	mockService := new(MockURLService)
+	mockFilter := new(MockShortCodeFilter)
+
+	guarded := service.NewGuardedURLService(mockService, mockFilter)
+
+	mockFilter.On("MayContain", ctx, "nope42").Return(false, nil)
+	mockFilter.On("MayContain", ctx, "abc123").Return(true, nil)
+	mockFilter.On("MayContain", ctx, "down01").Return(false, assert.AnError)
+	mockService.On("Redirect", ctx, "", "abc123").Return(&domain.URL{OriginalURL: "https://example.com"}, nil)
+	mockService.On("Redirect", ctx, "", "down01").Return(&domain.URL{OriginalURL: "https://example.com/down"}, nil)
+
+	_, err := guarded.Redirect(ctx, "", "nope42")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	mockService.AssertNotCalled(t, "Redirect", ctx, "", "nope42")
+
+	result, err := guarded.Redirect(ctx, "", "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", result.OriginalURL)
+
+	result, err = guarded.Redirect(ctx, "", "down01")
+	assert.NoError(t, err, "an unavailable filter lets requests through")
+	assert.Equal(t, "https://example.com/down", result.OriginalURL)
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// APIKey authenticates API callers. Only a SHA-256 hash of the secret is
+// stored; the plaintext key is shown once, when it is minted.
+type APIKey struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	OwnerID   string     `json:"owner_id" gorm:"not null;size:64;index"`
+	Name      string     `json:"name" gorm:"not null;size:100"`
+	Prefix    string     `json:"prefix" gorm:"not null;size:16"`
+	KeyHash   string     `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	CreatedAt time.Time  `json:"created_at" gorm:"not null;default:now()"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+const (
+	maxOwnerIDLength   = 64
+	maxAPIKeyNameBytes = 100
+)
+
+func NewAPIKey(ownerID, name string) (*APIKey, error) {
+	if ownerID == "" || len(ownerID) > maxOwnerIDLength {
+		return nil, ErrInvalidOwner
+	}
+
+	return &APIKey{
+		OwnerID:   ownerID,
+		Name:      truncate(name, maxAPIKeyNameBytes),
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Click is a single redirect event recorded for analytics.
+type Click struct {
+	ID             int64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	Domain         string    `json:"domain,omitempty" gorm:"not null;default:'';size:253;index:idx_clicks_domain_short_code_clicked_at,priority:1"`
+	ShortCode      string    `json:"short_code" gorm:"not null;size:32;index:idx_clicks_domain_short_code_clicked_at,priority:2"`
+	ClickedAt      time.Time `json:"clicked_at" gorm:"not null;default:now();index:idx_clicks_domain_short_code_clicked_at,priority:3;index"`
+	Referrer       string    `json:"referrer" gorm:"type:text"`
+	UserAgent      string    `json:"user_agent" gorm:"type:text"`
+	IPHash         string    `json:"ip_hash" gorm:"size:64"`
+	AcceptLanguage string    `json:"accept_language" gorm:"size:255"`
+}
+
+const (
+	maxReferrerLength       = 2048
+	maxUserAgentLength      = 512
+	maxAcceptLanguageLength = 255
+)
+
+func NewClick(link LinkRef, referrer, userAgent, acceptLanguage string, clickedAt time.Time) *Click {
+	return &Click{
+		Domain:         link.Domain,
+		ShortCode:      link.ShortCode,
+		ClickedAt:      clickedAt,
+		Referrer:       truncate(referrer, maxReferrerLength),
+		UserAgent:      truncate(userAgent, maxUserAgentLength),
+		AcceptLanguage: truncate(acceptLanguage, maxAcceptLanguageLength),
+	}
+}
+
+// truncate cuts s to at most max bytes without leaving a partial rune.
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return strings.ToValidUTF8(s[:max], "")
+	}
+	return s
+}
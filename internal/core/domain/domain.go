@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultDomain is the Domain of links on the deployment's own host,
+// configured by APP_BASE_URL.
+const DefaultDomain = ""
+
+// Domain is a branded host links can be created on. Short codes are unique
+// per domain, so the same code may lead to different links on different
+// domains.
+type Domain struct {
+	Host string `json:"host" gorm:"primaryKey;size:253"`
+	// OwnerID is the only owner who may create links on the domain; an
+	// empty OwnerID shares the domain with every owner.
+	OwnerID   string    `json:"owner_id,omitempty" gorm:"not null;default:'';size:64;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null;default:now()"`
+}
+
+const maxHostLength = 253
+
+// NewDomain registers host for ownerID, or for every owner when ownerID is
+// empty. The host is normalized and must be a plain DNS name.
+func NewDomain(host, ownerID string) (*Domain, error) {
+	host = NormalizeHost(host)
+	if !validHost(host) {
+		return nil, ErrInvalidDomain
+	}
+	if len(ownerID) > maxOwnerIDLength {
+		return nil, ErrInvalidOwner
+	}
+
+	return &Domain{
+		Host:      host,
+		OwnerID:   ownerID,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (d *Domain) UsableBy(ownerID string) bool {
+	return d.OwnerID == "" || d.OwnerID == ownerID
+}
+
+// LinkRef identifies a link by its domain and short code.
+type LinkRef struct {
+	Domain    string
+	ShortCode string
+}
+
+// NormalizeHost lowercases host and strips any port and trailing dot, so
+// that a Host header can be compared with registered domains.
+func NormalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+func validHost(host string) bool {
+	if host == "" || len(host) > maxHostLength || !strings.Contains(host, ".") {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -3,8 +3,25 @@ package domain
 import "errors"
 
 var (
-	ErrURLNotFound      = errors.New("url not found")
-	ErrInvalidURL       = errors.New("invalid URL")
-	ErrInvalidShortCode = errors.New("invalid short code")
-	ErrShortCodeTaken   = errors.New("short code already taken")
+	ErrURLNotFound         = errors.New("url not found")
+	ErrInvalidURL          = errors.New("invalid URL")
+	ErrInvalidShortCode    = errors.New("invalid short code")
+	ErrShortCodeTaken      = errors.New("short code already taken")
+	ErrInvalidAlias        = errors.New("invalid alias")
+	ErrReservedAlias       = errors.New("alias is reserved")
+	ErrURLExpired          = errors.New("url has expired")
+	ErrInvalidExpiry       = errors.New("expiry must be in the future")
+	ErrInvalidCursor       = errors.New("invalid pagination cursor")
+	ErrInvalidStatsQuery   = errors.New("invalid stats query")
+	ErrInvalidAPIKey       = errors.New("invalid API key")
+	ErrAPIKeyNotFound      = errors.New("API key not found")
+	ErrInvalidOwner        = errors.New("invalid owner")
+	ErrInvalidDomain       = errors.New("invalid domain")
+	ErrDomainNotFound      = errors.New("domain not found")
+	ErrDomainExists        = errors.New("domain already registered")
+	ErrDomainNotAllowed    = errors.New("domain not allowed")
+	ErrDomainPending       = errors.New("domain not active yet")
+	ErrInvalidRedirectType = errors.New("invalid redirect type")
+	ErrURLRejected         = errors.New("url rejected")
+	ErrURLTooLong          = errors.New("url too long")
 )
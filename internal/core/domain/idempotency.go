@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// IdempotencyKey remembers a request made with an Idempotency-Key header:
+// the fingerprint of the request that first used the key and, once it has
+// completed, its response, which is replayed to retries.
+type IdempotencyKey struct {
+	// Key is derived from the header and the caller it is scoped to.
+	Key         string `gorm:"primaryKey;size:64"`
+	Fingerprint string `gorm:"not null;size:64"`
+	// Status is the response status, or 0 while the request is in progress.
+	Status      int       `gorm:"not null;default:0"`
+	ContentType string    `gorm:"not null;default:'';size:255"`
+	Body        []byte    `gorm:"type:bytea"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
+
+// Completed reports whether the request holding the key has a response to
+// replay.
+func (k *IdempotencyKey) Completed() bool {
+	return k.Status != 0
+}
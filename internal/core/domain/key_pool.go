@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// PooledShortCode is a short code generated ahead of time and waiting in the
+// key pool to be handed out.
+type PooledShortCode struct {
+	ShortCode string    `gorm:"primaryKey;size:32"`
+	CreatedAt time.Time `gorm:"not null;default:now()"`
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+type LinkStatus string
+
+const (
+	LinkStatusActive  LinkStatus = "active"
+	LinkStatusExpired LinkStatus = "expired"
+	// LinkStatusBroken selects links whose last dead-link check failed.
+	LinkStatusBroken LinkStatus = "broken"
+)
+
+// URLUpdate describes a partial update of a link. Nil fields are left as is.
+type URLUpdate struct {
+	OriginalURL  *string
+	ExpiresAt    *time.Time
+	ClearExpiry  bool
+	RedirectType *int
+}
+
+// ListFilter narrows and paginates link listings. Links are ordered newest
+// first and paginated with a keyset cursor on (created_at, id).
+type ListFilter struct {
+	OwnerID       string
+	Query         string
+	Status        LinkStatus
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Cursor        *Cursor
+	Limit         int
+}
+
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+type URLPage struct {
+	URLs       []*URL
+	NextCursor *Cursor
+}
+
+func (c *Cursor) Encode() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(encoded string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{
+		CreatedAt: time.Unix(0, nanos).UTC(),
+		ID:        parts[1],
+	}, nil
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// RateLimitResult is the outcome of a single rate limit check.
+type RateLimitResult struct {
+	Allowed bool
+
+	// Limit is the number of requests that may be made in a burst.
+	Limit int
+	// Remaining is how many more requests would be allowed right now.
+	Remaining int
+	// ResetAfter is how long until the full burst is available again.
+	ResetAfter time.Duration
+	// RetryAfter is how long a rejected caller should wait. It is zero
+	// when the request was allowed.
+	RetryAfter time.Duration
+}
@@ -0,0 +1,19 @@
+package domain
+
+// URLRejectedError reports why URL screening refused a destination. It
+// matches ErrURLRejected with errors.Is.
+type URLRejectedError struct {
+	Reason string
+}
+
+func RejectURL(reason string) *URLRejectedError {
+	return &URLRejectedError{Reason: reason}
+}
+
+func (e *URLRejectedError) Error() string {
+	return ErrURLRejected.Error() + ": " + e.Reason
+}
+
+func (e *URLRejectedError) Is(target error) bool {
+	return target == ErrURLRejected
+}
@@ -0,0 +1,124 @@
+package domain
+
+import "time"
+
+type StatsInterval string
+
+const (
+	StatsIntervalHour StatsInterval = "hour"
+	StatsIntervalDay  StatsInterval = "day"
+	StatsIntervalWeek StatsInterval = "week"
+)
+
+const (
+	StatsDimensionReferrer  = "referrer"
+	StatsDimensionUserAgent = "user_agent"
+
+	DefaultStatsTopLimit = 10
+	MaxStatsTopLimit     = 100
+	MaxStatsBuckets      = 1000
+)
+
+// Truncate returns the start of the bucket containing t. Weeks start on
+// Monday, and all buckets are aligned in UTC.
+func (i StatsInterval) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+	switch i {
+	case StatsIntervalHour:
+		return t.Truncate(time.Hour)
+	case StatsIntervalDay:
+		return t.Truncate(24 * time.Hour)
+	case StatsIntervalWeek:
+		day := t.Truncate(24 * time.Hour)
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	}
+	return t
+}
+
+// Next returns the start of the bucket that follows the one starting at t.
+func (i StatsInterval) Next(t time.Time) time.Time {
+	switch i {
+	case StatsIntervalHour:
+		return t.Add(time.Hour)
+	case StatsIntervalDay:
+		return t.AddDate(0, 0, 1)
+	case StatsIntervalWeek:
+		return t.AddDate(0, 0, 7)
+	}
+	return t
+}
+
+func (i StatsInterval) IsValid() bool {
+	switch i {
+	case StatsIntervalHour, StatsIntervalDay, StatsIntervalWeek:
+		return true
+	}
+	return false
+}
+
+type StatsQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval StatsInterval
+	TopLimit int
+}
+
+type StatsBucket struct {
+	Start          time.Time `json:"start"`
+	Clicks         int64     `json:"clicks"`
+	UniqueVisitors int64     `json:"unique_visitors"`
+}
+
+type TopValue struct {
+	Value  string `json:"value"`
+	Clicks int64  `json:"clicks"`
+}
+
+type LinkStats struct {
+	Domain         string        `json:"domain,omitempty"`
+	ShortCode      string        `json:"short_code"`
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	Interval       StatsInterval `json:"interval"`
+	TotalClicks    int64         `json:"total_clicks"`
+	UniqueVisitors int64         `json:"unique_visitors"`
+	Series         []StatsBucket `json:"series"`
+	TopReferrers   []TopValue    `json:"top_referrers"`
+	TopUserAgents  []TopValue    `json:"top_user_agents"`
+}
+
+// ClickRollup holds pre-aggregated click counts for one link and bucket.
+type ClickRollup struct {
+	Domain         string    `gorm:"primaryKey;default:'';size:253"`
+	ShortCode      string    `gorm:"primaryKey;size:32"`
+	Granularity    string    `gorm:"primaryKey;size:8"`
+	BucketStart    time.Time `gorm:"primaryKey"`
+	Clicks         int64     `gorm:"not null;default:0"`
+	UniqueVisitors int64     `gorm:"not null;default:0"`
+}
+
+// ClickDimensionRollup counts hourly clicks per referrer host or user agent.
+type ClickDimensionRollup struct {
+	Domain      string    `gorm:"primaryKey;default:'';size:253"`
+	ShortCode   string    `gorm:"primaryKey;size:32"`
+	Dimension   string    `gorm:"primaryKey;size:16"`
+	BucketStart time.Time `gorm:"primaryKey"`
+	Value       string    `gorm:"primaryKey;size:512"`
+	Clicks      int64     `gorm:"not null;default:0"`
+}
+
+// ClickDailyVisitor is the set of distinct visitors per link and day, used to
+// count unique visitors over arbitrary ranges without scanning raw clicks.
+type ClickDailyVisitor struct {
+	Domain    string    `gorm:"primaryKey;default:'';size:253"`
+	ShortCode string    `gorm:"primaryKey;size:32"`
+	Day       time.Time `gorm:"primaryKey"`
+	IPHash    string    `gorm:"primaryKey;size:64"`
+}
+
+// RollupCheckpoint records how far the aggregator has fully processed.
+type RollupCheckpoint struct {
+	Name           string    `gorm:"primaryKey;size:64"`
+	ProcessedUntil time.Time `gorm:"not null"`
+}
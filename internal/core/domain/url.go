@@ -1,15 +1,86 @@
 package domain
 
 import (
+	"crypto/sha256"
 	"time"
 )
 
 type URL struct {
-	ID          string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	OriginalURL string    `json:"original_url" gorm:"not null;type:text"`
-	ShortCode   string    `json:"short_code" gorm:"not null;uniqueIndex;size:10"`
-	CreatedAt   time.Time `json:"created_at" gorm:"not null;default:now()"`
-	ClickCount  int64     `json:"click_count" gorm:"not null;default:0"`
+	ID          string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	OwnerID     string     `json:"owner_id" gorm:"not null;default:'';size:64;index:idx_urls_owner_id_created_at,priority:1;uniqueIndex:idx_urls_shared_canonical_url,priority:1"`
+	OriginalURL string     `json:"original_url" gorm:"not null;type:text"`
+	Domain      string     `json:"domain,omitempty" gorm:"not null;default:'';size:253;uniqueIndex:idx_urls_domain_short_code,priority:1;uniqueIndex:idx_urls_shared_canonical_url,priority:2"`
+	ShortCode   string     `json:"short_code" gorm:"not null;size:32;uniqueIndex:idx_urls_domain_short_code,priority:2;index"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"not null;default:now();index:idx_urls_owner_id_created_at,priority:2"`
+	ClickCount  int64      `json:"click_count" gorm:"not null;default:0"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	// RedirectType is the HTTP status redirects are answered with; see
+	// RedirectStatus.
+	RedirectType int `json:"redirect_type" gorm:"not null;type:smallint;default:302;uniqueIndex:idx_urls_shared_canonical_url,priority:3"`
+	// CanonicalURL is OriginalURL in canonical form, which links are
+	// deduplicated on.
+	CanonicalURL string `json:"-" gorm:"not null;default:'';type:text"`
+	// CanonicalURLHash is set on the link handed out again to its owner
+	// for the same destination, and nil on links that are not shared, so
+	// that an owner shares at most one link per domain, redirect type and
+	// destination; see Share.
+	CanonicalURLHash []byte `json:"-" gorm:"type:bytea;uniqueIndex:idx_urls_shared_canonical_url,priority:4"`
+
+	// LastCheckStatus is the status the destination answered the last
+	// dead-link check with, or 0 if it could not be reached. LastCheckedAt
+	// is nil until the link has been checked.
+	LastCheckStatus int        `json:"last_check_status" gorm:"not null;type:smallint;default:0"`
+	LastCheckedAt   *time.Time `json:"last_checked_at,omitempty"`
+	// NextCheckAt is when the link is next due for a check; nil if it is
+	// due now.
+	NextCheckAt *time.Time `json:"-" gorm:"index"`
+}
+
+// Redirect types a link may use. Permanent redirects tell browsers and
+// search engines the short URL has moved for good, temporary ones keep them
+// coming back to it. 307 and 308 also preserve the request method.
+const (
+	RedirectMovedPermanently = 301
+	RedirectFound            = 302
+	RedirectTemporary        = 307
+	RedirectPermanent        = 308
+	DefaultRedirectType      = RedirectFound
+)
+
+// ValidRedirectType reports whether status is a redirect type a link may use.
+func ValidRedirectType(status int) bool {
+	switch status {
+	case RedirectMovedPermanently, RedirectFound, RedirectTemporary, RedirectPermanent:
+		return true
+	}
+	return false
+}
+
+// IsPermanentRedirect reports whether status is a permanent redirect type.
+func IsPermanentRedirect(status int) bool {
+	return status == RedirectMovedPermanently || status == RedirectPermanent
+}
+
+// ShortenOptions carries the optional, caller-supplied settings for a new link.
+type ShortenOptions struct {
+	// Alias is a custom short code chosen by the caller. When empty a code
+	// is generated.
+	Alias string
+
+	// ExpiresAt is the absolute time after which the link stops redirecting.
+	// A nil value means the link never expires.
+	ExpiresAt *time.Time
+
+	// OwnerID identifies the caller creating the link. Only the owner can
+	// see, change or delete it through the API.
+	OwnerID string
+
+	// Domain is the host to create the link on; empty for DefaultDomain.
+	Domain string
+
+	// RedirectType is the status redirects are answered with; zero means
+	// DefaultRedirectType.
+	RedirectType int
 }
 
 func NewURL(originalURL, shortCode string) (*URL, error) {
@@ -21,13 +92,58 @@ func NewURL(originalURL, shortCode string) (*URL, error) {
 	}
 
 	return &URL{
-		OriginalURL: originalURL,
-		ShortCode:   shortCode,
-		CreatedAt:   time.Now(),
-		ClickCount:  0,
+		OriginalURL:  originalURL,
+		ShortCode:    shortCode,
+		CreatedAt:    time.Now(),
+		ClickCount:   0,
+		RedirectType: DefaultRedirectType,
 	}, nil
 }
 
+// HashURL returns the hash shared links are looked up by.
+func HashURL(canonicalURL string) []byte {
+	sum := sha256.Sum256([]byte(canonicalURL))
+	return sum[:]
+}
+
+// Share makes the link the one its owner gets back when shortening the
+// same destination again, with no expiry and the same redirect type.
+func (u *URL) Share() {
+	u.CanonicalURLHash = HashURL(u.CanonicalURL)
+}
+
+// Unshare stops the link from being handed out again, as when it no longer
+// points at the destination or lasts as long as it was shared for.
+func (u *URL) Unshare() {
+	u.CanonicalURLHash = nil
+}
+
+// IsShared reports whether the link is handed out again for its
+// destination.
+func (u *URL) IsShared() bool {
+	return u.CanonicalURLHash != nil
+}
+
 func (u *URL) IncrementClickCount() {
 	u.ClickCount++
 }
+
+// RedirectStatus returns the status to redirect with. Links cached before
+// they carried a redirect type use the default.
+func (u *URL) RedirectStatus() int {
+	if u.RedirectType == 0 {
+		return DefaultRedirectType
+	}
+	return u.RedirectType
+}
+
+// IsBroken reports whether the last check found the destination
+// unreachable or answering with an error status.
+func (u *URL) IsBroken() bool {
+	return u.LastCheckedAt != nil && (u.LastCheckStatus == 0 || u.LastCheckStatus >= 400)
+}
+
+// IsExpired reports whether the link has passed its expiry at the given time.
+func (u *URL) IsExpired(now time.Time) bool {
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
+}
@@ -2,15 +2,36 @@ package ports
 
 import (
 	"context"
+	"errors"
 
 	"github.com/mikiasyonas/url-shortener/internal/core/domain"
 )
 
+// ErrCacheMiss is returned by Cache.GetURL when the cache knows nothing about
+// a link. A cached "not found", stored with SetNotFound, is reported as
+// domain.ErrURLNotFound instead.
+var ErrCacheMiss = errors.New("cache miss")
+
 type Cache interface {
-	GetURL(ctx context.Context, shortCode string) (*domain.URL, error)
+	// Links are keyed by domain and short code; SetURL uses url.Domain.
+	GetURL(ctx context.Context, host, shortCode string) (*domain.URL, error)
 	SetURL(ctx context.Context, url *domain.URL, ttl int) error
-	DeleteURL(ctx context.Context, shortCode string) error
+	// SetNotFound remembers for ttl seconds that shortCode does not exist on
+	// host. SetURL and DeleteURL replace or clear the entry.
+	SetNotFound(ctx context.Context, host, shortCode string, ttl int) error
+	DeleteURL(ctx context.Context, host, shortCode string) error
+
+	IncrementClickCount(ctx context.Context, host, shortCode string) error
+	GetClickCount(ctx context.Context, host, shortCode string) (int64, error)
+	// DrainClickCounts atomically reads and resets every pending click
+	// counter, returning the drained deltas keyed by link.
+	DrainClickCounts(ctx context.Context) (map[domain.LinkRef]int64, error)
+}
 
-	IncrementClickCount(ctx context.Context, shortCode string) error
-	GetClickCount(ctx context.Context, shortCode string) (int64, error)
+// CacheMetrics receives the outcome of cache lookups. The cache argument
+// names the tier that was consulted, e.g. "redis".
+type CacheMetrics interface {
+	RecordCacheHit(cache string)
+	RecordCacheMiss(cache string)
+	RecordCacheNegativeHit(cache string)
 }
@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+)
+
+// IdempotencyStore holds Idempotency-Keys for as long as their responses
+// are replayed. Reserve claims a key for a request and returns nil; if the
+// key is already held, it returns the key as held and claims nothing. The
+// claim ends with Complete, which stores the response, or with Release,
+// which lets the key be used again. A claim that is never ended lapses on
+// its own; once it has been taken over by another request, Complete and
+// Release leave the key alone.
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, key, fingerprint string) (*domain.IdempotencyKey, error)
+	Complete(ctx context.Context, key *domain.IdempotencyKey) error
+	Release(ctx context.Context, key, fingerprint string) error
+}
@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/mikiasyonas/url-shortener/internal/core/domain"
+)
+
+// RateLimiter decides whether the caller identified by key may make another
+// request, consuming one unit of its budget when it may.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (domain.RateLimitResult, error)
+}
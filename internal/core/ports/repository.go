@@ -2,19 +2,80 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/mikiasyonas/url-shortener/internal/core/domain"
 )
 
 type URLRepository interface {
 	Save(ctx context.Context, url *domain.URL) error
-	FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error)
-	FindByOriginalURL(ctx context.Context, originalURL string) (*domain.URL, error)
-	Exists(ctx context.Context, shortCode string) (bool, error)
-	IncrementClickCount(ctx context.Context, shortCode string) error
+	// SaveShared saves a shared link unless its owner already shares one to
+	// the same destination on the same domain with the same redirect type;
+	// that link is then returned and nothing is saved. Concurrent calls
+	// for one destination save at most one link.
+	SaveShared(ctx context.Context, url *domain.URL) (*domain.URL, error)
+	FindByShortCode(ctx context.Context, host, shortCode string) (*domain.URL, error)
+	// FindByCanonicalURL returns the owner's shared link to a destination
+	// on host with the given redirect type.
+	FindByCanonicalURL(ctx context.Context, ownerID, host, canonicalURL string, redirectType int) (*domain.URL, error)
+	Exists(ctx context.Context, host, shortCode string) (bool, error)
+	// ExistsOnAnyDomain reports whether a link on any domain uses the code.
+	ExistsOnAnyDomain(ctx context.Context, shortCode string) (bool, error)
+	IncrementClickCount(ctx context.Context, host, shortCode string) error
+	AddClickCounts(ctx context.Context, counts map[domain.LinkRef]int64) error
+	// Update and Delete only affect a link owned by the given owner.
+	Update(ctx context.Context, url *domain.URL) error
+	Delete(ctx context.Context, ownerID, host, shortCode string) error
+	List(ctx context.Context, filter domain.ListFilter) (*domain.URLPage, error)
+	// ShortCodes pages through the codes of links on every domain created
+	// at or after createdSince (all links when zero), in order, starting
+	// after the given code.
+	ShortCodes(ctx context.Context, createdSince time.Time, after string, limit int) ([]string, error)
+	// ClaimLinksToCheck returns up to limit unexpired links due for a
+	// dead-link check at now, never-checked and popular links first, and
+	// defers their next check to nextCheckAt so that no other instance
+	// claims them meanwhile.
+	ClaimLinksToCheck(ctx context.Context, now, nextCheckAt time.Time, limit int) ([]*domain.URL, error)
+	RecordLinkCheck(ctx context.Context, id string, status int, checkedAt time.Time) error
+}
+
+type APIKeyRepository interface {
+	Save(ctx context.Context, key *domain.APIKey) error
+	FindByHash(ctx context.Context, keyHash string) (*domain.APIKey, error)
+	List(ctx context.Context, ownerID string) ([]*domain.APIKey, error)
+	Revoke(ctx context.Context, id string, revokedAt time.Time) error
+}
+
+type DomainRepository interface {
+	Save(ctx context.Context, d *domain.Domain) error
+	FindByHost(ctx context.Context, host string) (*domain.Domain, error)
+	List(ctx context.Context) ([]*domain.Domain, error)
+}
+
+type ClickRepository interface {
+	SaveBatch(ctx context.Context, clicks []*domain.Click) error
+}
+
+type StatsRepository interface {
+	GetLinkStats(ctx context.Context, host, shortCode string, query domain.StatsQuery) (*domain.LinkStats, error)
+
+	// RollupHour recomputes every rollup touched by clicks in the hour
+	// starting at hour. It is idempotent.
+	RollupHour(ctx context.Context, hour time.Time) error
+	EarliestClick(ctx context.Context) (time.Time, bool, error)
+	GetCheckpoint(ctx context.Context, name string) (time.Time, bool, error)
+	SaveCheckpoint(ctx context.Context, name string, processedUntil time.Time) error
 }
 
 type ShortCodeGenerator interface {
-	Generate() string
+	Generate(ctx context.Context) (string, error)
+	// Validate reports whether code follows the generator's current rules.
 	Validate(code string) bool
+	// Resembles reports whether code has the length and characters of the
+	// codes the generator issues, whether or not Validate accepts it.
+	Resembles(code string) bool
+}
+
+type AliasValidator interface {
+	Validate(alias string) error
 }
@@ -7,6 +7,55 @@ import (
 )
 
 type URLService interface {
-	ShortenURL(ctx context.Context, originalURL string) (*domain.URL, error)
-	Redirect(ctx context.Context, shortCode string) (string, error)
+	ShortenURL(ctx context.Context, originalURL string, opts domain.ShortenOptions) (*domain.URL, error)
+	// Redirect and LookupURL resolve a short code on the domain host, which
+	// is domain.DefaultDomain for the deployment's own host. Redirect
+	// returns the link to redirect to and counts a click.
+	Redirect(ctx context.Context, host, shortCode string) (*domain.URL, error)
+	// LookupURL resolves a short code for the redirect path without counting
+	// a click. Expiry is left to the caller.
+	LookupURL(ctx context.Context, host, shortCode string) (*domain.URL, error)
+
+	// The management operations below only see links owned by ownerID;
+	// other links are reported as not found.
+	GetURL(ctx context.Context, ownerID, host, shortCode string) (*domain.URL, error)
+	UpdateURL(ctx context.Context, ownerID, host, shortCode string, update domain.URLUpdate) (*domain.URL, error)
+	DeleteURL(ctx context.Context, ownerID, host, shortCode string) error
+	ListURLs(ctx context.Context, filter domain.ListFilter) (*domain.URLPage, error)
+}
+
+type StatsService interface {
+	GetLinkStats(ctx context.Context, ownerID, host, shortCode string, query domain.StatsQuery) (*domain.LinkStats, error)
+}
+
+type DomainService interface {
+	// Resolve maps a request's Host header to the domain its links live
+	// on: a registered domain, or domain.DefaultDomain for any other host.
+	// It must be cheap enough to call on every redirect.
+	Resolve(host string) string
+	// Authorize checks that ownerID may create links on host and returns
+	// the normalized domain, domain.DefaultDomain for an empty host. A
+	// domain registered too recently for every instance to serve it yet
+	// gives domain.ErrDomainPending.
+	Authorize(ctx context.Context, ownerID, host string) (string, error)
+	// Register adds host as a domain usable by ownerID, or by every owner
+	// when ownerID is empty.
+	Register(ctx context.Context, host, ownerID string) (*domain.Domain, error)
+	// List returns the domains ownerID may create links on.
+	List(ctx context.Context, ownerID string) ([]*domain.Domain, error)
+}
+
+type APIKeyService interface {
+	// Create mints a key for ownerID and returns it together with the
+	// plaintext secret, which is not stored and cannot be recovered.
+	Create(ctx context.Context, ownerID, name string) (*domain.APIKey, string, error)
+	Authenticate(ctx context.Context, secret string) (*domain.APIKey, error)
+	Revoke(ctx context.Context, id string) error
+	List(ctx context.Context, ownerID string) ([]*domain.APIKey, error)
+}
+
+// ClickRecorder accepts click events from the redirect path. Implementations
+// must not block the caller.
+type ClickRecorder interface {
+	Record(click *domain.Click, clientIP string)
 }
@@ -0,0 +1,12 @@
+package ports
+
+import "context"
+
+// ShortCodeFilter is a probabilistic set of every short code ever issued.
+// MayContain never reports false for a code that was added, but may report
+// true for one that was not; until the filter has been loaded it reports
+// true for every code.
+type ShortCodeFilter interface {
+	Add(ctx context.Context, shortCode string) error
+	MayContain(ctx context.Context, shortCode string) (bool, error)
+}
@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPoolEmpty is returned by ShortCodePool.Take when no code is ready.
+var ErrPoolEmpty = errors.New("short code pool is empty")
+
+// ShortCodePool hands out short codes that were generated ahead of time and
+// found unused, so creating a link need not search for a free code. A code
+// is handed out at most once, but an alias may still claim it in between.
+type ShortCodePool interface {
+	Take(ctx context.Context) (string, error)
+}
+
+type KeyPoolMetrics interface {
+	RecordKeyPoolDepth(depth int64)
+}
@@ -0,0 +1,8 @@
+package ports
+
+// URLCanonicalizer rewrites a destination into the canonical form links are
+// deduplicated on. Spellings of the same destination share a canonical
+// form; the link still redirects to the spelling it was created with.
+type URLCanonicalizer interface {
+	Canonicalize(rawURL string) (string, error)
+}
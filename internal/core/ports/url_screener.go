@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+	"net/url"
+)
+
+// URLScreener decides whether links may point at a destination. Screen
+// returns a *domain.URLRejectedError for a destination that must not be
+// shortened; any other error means the screener could not decide.
+type URLScreener interface {
+	Screen(ctx context.Context, target *url.URL) error
+}
@@ -6,10 +6,17 @@ echo "===================================="
 # Base URL
 BASE_URL="http://localhost"
 
+# API key used for /api requests (mint one with: go run ./cmd/apikey create -owner loadtest)
+if [ -z "$API_KEY" ]; then
+    echo "API_KEY is not set"
+    exit 1
+fi
+
 echo "1. 🔄 Creating test URLs for load testing..."
 SHORT_CODES=()
 for i in {1..50}; do
     response=$(curl -s -X POST "$BASE_URL/api/shorten" \
+        -H "Authorization: Bearer $API_KEY" \
         -H "Content-Type: application/json" \
         -d "{\"url\":\"https://example.com/test/$i?loadtest=true&timestamp=$(date +%s)\"}")
     
@@ -42,6 +49,7 @@ time {
             curl -s "$BASE_URL/$short_code" > /dev/null
         else
             curl -s -X POST "$BASE_URL/api/shorten" \
+                -H "Authorization: Bearer $API_KEY" \
                 -H "Content-Type: application/json" \
                 -d "{\"url\":\"https://example.com/new/$(date +%s)/$RANDOM\"}" > /dev/null
         fi
@@ -64,6 +72,7 @@ echo "Testing 50 URL shortening requests..."
 time {
     for i in {1..5000}; do
         curl -s -X POST "$BASE_URL/api/shorten" \
+            -H "Authorization: Bearer $API_KEY" \
             -H "Content-Type: application/json" \
             -d "{\"url\":\"https://example.com/bulk/$i/$(date +%s)\"}" > /dev/null
     done
@@ -72,11 +81,7 @@ time {
 echo ""
 echo "5. 📊 Checking Application Metrics..."
 echo "Current performance metrics:"
-if command -v jq &> /dev/null; then
-    curl -s "$BASE_URL/metrics" | jq '.'
-else
-    curl -s "$BASE_URL/metrics"
-fi
+curl -s "$BASE_URL/metrics" | grep -v '^#' | grep -E 'http_requests_total|cache_(hits|misses)_total|urls_(shortened|redirected)_total' 
 
 echo ""
 echo "6. ✅ Health Check..."
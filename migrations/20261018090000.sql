@@ -0,0 +1,2 @@
+-- Modify "urls" table
+ALTER TABLE "urls" ALTER COLUMN "short_code" TYPE character varying(32);
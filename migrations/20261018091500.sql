@@ -0,0 +1,4 @@
+-- Modify "urls" table
+ALTER TABLE "urls" ADD COLUMN "expires_at" timestamptz NULL;
+-- Create index "idx_urls_expires_at" to table: "urls"
+CREATE INDEX "idx_urls_expires_at" ON "urls" ("expires_at");
@@ -0,0 +1,15 @@
+-- Create "clicks" table
+CREATE TABLE "clicks" (
+  "id" bigserial NOT NULL,
+  "short_code" character varying(32) NOT NULL,
+  "clicked_at" timestamptz NOT NULL DEFAULT now(),
+  "referrer" text NULL,
+  "user_agent" text NULL,
+  "ip_hash" character varying(64) NULL,
+  "accept_language" character varying(255) NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_clicks_clicked_at" to table: "clicks"
+CREATE INDEX "idx_clicks_clicked_at" ON "clicks" ("clicked_at");
+-- Create index "idx_clicks_short_code_clicked_at" to table: "clicks"
+CREATE INDEX "idx_clicks_short_code_clicked_at" ON "clicks" ("short_code", "clicked_at");
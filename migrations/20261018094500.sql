@@ -0,0 +1,31 @@
+-- Create "click_rollups" table
+CREATE TABLE "click_rollups" (
+  "short_code" character varying(32) NOT NULL,
+  "granularity" character varying(8) NOT NULL,
+  "bucket_start" timestamptz NOT NULL,
+  "clicks" bigint NOT NULL DEFAULT 0,
+  "unique_visitors" bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY ("short_code", "granularity", "bucket_start")
+);
+-- Create "click_dimension_rollups" table
+CREATE TABLE "click_dimension_rollups" (
+  "short_code" character varying(32) NOT NULL,
+  "dimension" character varying(16) NOT NULL,
+  "bucket_start" timestamptz NOT NULL,
+  "value" character varying(512) NOT NULL,
+  "clicks" bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY ("short_code", "dimension", "bucket_start", "value")
+);
+-- Create "click_daily_visitors" table
+CREATE TABLE "click_daily_visitors" (
+  "short_code" character varying(32) NOT NULL,
+  "day" timestamptz NOT NULL,
+  "ip_hash" character varying(64) NOT NULL,
+  PRIMARY KEY ("short_code", "day", "ip_hash")
+);
+-- Create "rollup_checkpoints" table
+CREATE TABLE "rollup_checkpoints" (
+  "name" character varying(64) NOT NULL,
+  "processed_until" timestamptz NOT NULL,
+  PRIMARY KEY ("name")
+);
@@ -0,0 +1,19 @@
+-- Modify "urls" table
+ALTER TABLE "urls" ADD COLUMN "owner_id" character varying(64) NOT NULL DEFAULT '';
+-- Create index "idx_urls_owner_id_created_at" to table: "urls"
+CREATE INDEX "idx_urls_owner_id_created_at" ON "urls" ("owner_id", "created_at");
+-- Create "api_keys" table
+CREATE TABLE "api_keys" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "owner_id" character varying(64) NOT NULL,
+  "name" character varying(100) NOT NULL,
+  "prefix" character varying(16) NOT NULL,
+  "key_hash" character varying(64) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "revoked_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_api_keys_key_hash" to table: "api_keys"
+CREATE UNIQUE INDEX "idx_api_keys_key_hash" ON "api_keys" ("key_hash");
+-- Create index "idx_api_keys_owner_id" to table: "api_keys"
+CREATE INDEX "idx_api_keys_owner_id" ON "api_keys" ("owner_id");
@@ -0,0 +1,2 @@
+-- Create "short_code_ids" sequence
+CREATE SEQUENCE IF NOT EXISTS "short_code_ids";
@@ -0,0 +1,6 @@
+-- Create "pooled_short_codes" table
+CREATE TABLE "pooled_short_codes" (
+  "short_code" character varying(32) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("short_code")
+);
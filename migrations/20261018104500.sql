@@ -0,0 +1,29 @@
+-- Modify "urls" table
+ALTER TABLE "urls" ADD COLUMN "domain" character varying(253) NOT NULL DEFAULT '';
+-- Drop index "idx_urls_short_code" from table: "urls"
+DROP INDEX "idx_urls_short_code";
+-- Create index "idx_urls_short_code" to table: "urls"
+CREATE INDEX "idx_urls_short_code" ON "urls" ("short_code");
+-- Create index "idx_urls_domain_short_code" to table: "urls"
+CREATE UNIQUE INDEX "idx_urls_domain_short_code" ON "urls" ("domain", "short_code");
+-- Modify "clicks" table
+ALTER TABLE "clicks" ADD COLUMN "domain" character varying(253) NOT NULL DEFAULT '';
+-- Drop index "idx_clicks_short_code_clicked_at" from table: "clicks"
+DROP INDEX "idx_clicks_short_code_clicked_at";
+-- Create index "idx_clicks_domain_short_code_clicked_at" to table: "clicks"
+CREATE INDEX "idx_clicks_domain_short_code_clicked_at" ON "clicks" ("domain", "short_code", "clicked_at");
+-- Modify "click_rollups" table
+ALTER TABLE "click_rollups" DROP CONSTRAINT "click_rollups_pkey", ADD COLUMN "domain" character varying(253) NOT NULL DEFAULT '', ADD PRIMARY KEY ("domain", "short_code", "granularity", "bucket_start");
+-- Modify "click_dimension_rollups" table
+ALTER TABLE "click_dimension_rollups" DROP CONSTRAINT "click_dimension_rollups_pkey", ADD COLUMN "domain" character varying(253) NOT NULL DEFAULT '', ADD PRIMARY KEY ("domain", "short_code", "dimension", "bucket_start", "value");
+-- Modify "click_daily_visitors" table
+ALTER TABLE "click_daily_visitors" DROP CONSTRAINT "click_daily_visitors_pkey", ADD COLUMN "domain" character varying(253) NOT NULL DEFAULT '', ADD PRIMARY KEY ("domain", "short_code", "day", "ip_hash");
+-- Create "domains" table
+CREATE TABLE "domains" (
+  "host" character varying(253) NOT NULL,
+  "owner_id" character varying(64) NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("host")
+);
+-- Create index "idx_domains_owner_id" to table: "domains"
+CREATE INDEX "idx_domains_owner_id" ON "domains" ("owner_id");
@@ -0,0 +1,2 @@
+-- Modify "urls" table
+ALTER TABLE "urls" ADD COLUMN "redirect_type" smallint NOT NULL DEFAULT 302;
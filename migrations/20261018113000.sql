@@ -0,0 +1,4 @@
+-- Modify "urls" table
+ALTER TABLE "urls" ADD COLUMN "last_check_status" smallint NOT NULL DEFAULT 0, ADD COLUMN "last_checked_at" timestamptz NULL, ADD COLUMN "next_check_at" timestamptz NULL;
+-- Create index "idx_urls_next_check_at" to table: "urls"
+CREATE INDEX "idx_urls_next_check_at" ON "urls" ("next_check_at");
@@ -0,0 +1,6 @@
+-- Modify "urls" table
+ALTER TABLE "urls" ADD COLUMN "canonical_url" text NOT NULL DEFAULT '';
+-- Backfill "canonical_url": existing links keep being deduplicated on their exact destination
+UPDATE "urls" SET "canonical_url" = "original_url";
+-- Create index "idx_urls_owner_id_domain_canonical_url" to table: "urls"
+CREATE INDEX "idx_urls_owner_id_domain_canonical_url" ON "urls" ("owner_id", "domain", "canonical_url");
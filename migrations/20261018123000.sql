@@ -0,0 +1,8 @@
+-- Modify "urls" table
+ALTER TABLE "urls" ADD COLUMN "canonical_url_hash" bytea NULL;
+-- Backfill "canonical_url_hash": the oldest permanent link to each destination stays shared
+UPDATE "urls" SET "canonical_url_hash" = sha256(convert_to("canonical_url", 'UTF8')) WHERE "id" IN (SELECT DISTINCT ON ("owner_id", "domain", "redirect_type", "canonical_url") "id" FROM "urls" WHERE "expires_at" IS NULL ORDER BY "owner_id", "domain", "redirect_type", "canonical_url", "created_at");
+-- Drop index "idx_urls_owner_id_domain_canonical_url" from table: "urls"
+DROP INDEX "idx_urls_owner_id_domain_canonical_url";
+-- Create index "idx_urls_shared_canonical_url" to table: "urls"
+CREATE UNIQUE INDEX "idx_urls_shared_canonical_url" ON "urls" ("owner_id", "domain", "redirect_type", "canonical_url_hash");
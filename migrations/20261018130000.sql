@@ -0,0 +1,12 @@
+-- Create "idempotency_keys" table
+CREATE TABLE "idempotency_keys" (
+  "key" character varying(64) NOT NULL,
+  "fingerprint" character varying(64) NOT NULL,
+  "status" bigint NOT NULL DEFAULT 0,
+  "content_type" character varying(255) NOT NULL DEFAULT '',
+  "body" bytea NULL,
+  "expires_at" timestamptz NOT NULL,
+  PRIMARY KEY ("key")
+);
+-- Create index "idx_idempotency_keys_expires_at" to table: "idempotency_keys"
+CREATE INDEX "idx_idempotency_keys_expires_at" ON "idempotency_keys" ("expires_at");
@@ -0,0 +1,139 @@
+// Package bloom implements a Bloom filter: a compact set that can answer
+// "definitely not present" or "maybe present", never a false negative.
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"sync/atomic"
+)
+
+var magic = [4]byte{'B', 'L', 'M', '1'}
+
+var ErrInvalidFormat = errors.New("bloom: invalid serialized filter")
+
+// Filter is a fixed-size Bloom filter that is safe for concurrent use.
+type Filter struct {
+	m     uint64
+	k     uint64
+	words []uint64
+}
+
+// New sizes a filter to hold expectedItems with the given false positive
+// rate.
+func New(expectedItems uint64, falsePositiveRate float64) *Filter {
+	m, k := EstimateParameters(expectedItems, falsePositiveRate)
+	return NewWithParameters(m, k)
+}
+
+// NewWithParameters creates a filter of m bits using k hash functions.
+func NewWithParameters(m, k uint64) *Filter {
+	m = max(m, 64)
+	k = max(k, 1)
+	return &Filter{
+		m:     m,
+		k:     k,
+		words: make([]uint64, (m+63)/64),
+	}
+}
+
+// EstimateParameters returns the number of bits m and hash functions k that
+// give the requested false positive rate for n items.
+func EstimateParameters(n uint64, p float64) (m, k uint64) {
+	n = max(n, 1)
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+
+	bits := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	hashes := math.Round(bits / float64(n) * math.Ln2)
+	return max(uint64(bits), 64), max(uint64(hashes), 1)
+}
+
+func (f *Filter) M() uint64 { return f.m }
+func (f *Filter) K() uint64 { return f.k }
+
+func (f *Filter) Add(key string) {
+	for _, loc := range Locations(key, f.m, f.k) {
+		atomic.OrUint64(&f.words[loc/64], 1<<(loc%64))
+	}
+}
+
+// Test reports whether key may have been added. False means it definitely
+// has not.
+func (f *Filter) Test(key string) bool {
+	for _, loc := range Locations(key, f.m, f.k) {
+		if atomic.LoadUint64(&f.words[loc/64])&(1<<(loc%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Locations returns the k bit positions, below m, that represent key. It is
+// deterministic across processes, so filters can be persisted or shared.
+func Locations(key string, m, k uint64) []uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h1 := h.Sum64()
+	h.Write([]byte{0})
+	h2 := h.Sum64() | 1
+
+	locs := make([]uint64, k)
+	for i := uint64(0); i < k; i++ {
+		locs[i] = (h1 + i*h2) % m
+	}
+	return locs
+}
+
+// WriteTo serializes the filter.
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 0, 20)
+	header = append(header, magic[:]...)
+	header = binary.BigEndian.AppendUint64(header, f.m)
+	header = binary.BigEndian.AppendUint64(header, f.k)
+
+	n, err := w.Write(header)
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+
+	buf := make([]byte, 8*len(f.words))
+	for i := range f.words {
+		binary.BigEndian.PutUint64(buf[8*i:], atomic.LoadUint64(&f.words[i]))
+	}
+	n, err = w.Write(buf)
+	return written + int64(n), err
+}
+
+// ReadFrom deserializes a filter written by WriteTo.
+func ReadFrom(r io.Reader) (*Filter, error) {
+	header := make([]byte, 20)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	if [4]byte(header[:4]) != magic {
+		return nil, ErrInvalidFormat
+	}
+
+	m := binary.BigEndian.Uint64(header[4:12])
+	k := binary.BigEndian.Uint64(header[12:20])
+	if m < 64 || k < 1 || m > math.MaxInt32*64 {
+		return nil, ErrInvalidFormat
+	}
+
+	f := NewWithParameters(m, k)
+	buf := make([]byte, 8*len(f.words))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	for i := range f.words {
+		f.words[i] = binary.BigEndian.Uint64(buf[8*i:])
+	}
+	return f, nil
+}
@@ -0,0 +1,65 @@
+package bloom
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter_NoFalseNegatives(t *testing.T) {
+	f := New(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		f.Add(fmt.Sprintf("code-%d", i))
+	}
+
+	for i := 0; i < 10000; i++ {
+		assert.True(t, f.Test(fmt.Sprintf("code-%d", i)))
+	}
+}
+
+func TestFilter_FalsePositiveRate(t *testing.T) {
+	f := New(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		f.Add(fmt.Sprintf("code-%d", i))
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.Test(fmt.Sprintf("other-%d", i)) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 200, "expected roughly 1%% false positives")
+}
+
+func TestEstimateParameters(t *testing.T) {
+	m, k := EstimateParameters(1000000, 0.01)
+	assert.InDelta(t, 9585059, m, 1)
+	assert.Equal(t, uint64(7), k)
+}
+
+func TestFilter_RoundTrip(t *testing.T) {
+	f := New(1000, 0.01)
+	f.Add("abc123")
+	f.Add("promo")
+
+	var buf bytes.Buffer
+	_, err := f.WriteTo(&buf)
+	require.NoError(t, err)
+
+	loaded, err := ReadFrom(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, f.M(), loaded.M())
+	assert.Equal(t, f.K(), loaded.K())
+	assert.True(t, loaded.Test("abc123"))
+	assert.True(t, loaded.Test("promo"))
+	assert.False(t, loaded.Test("nope42"))
+}
+
+func TestReadFrom_Invalid(t *testing.T) {
+	_, err := ReadFrom(bytes.NewReader([]byte("not a filter at all")))
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+}
@@ -0,0 +1,206 @@
+package canonical
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidURL is returned for a URL that does not parse or has no host.
+var ErrInvalidURL = errors.New("canonical: invalid URL")
+
+// Option configures how far a Canonicalizer goes beyond the normalizations
+// that never change which resource a URL names.
+type Option func(*Canonicalizer)
+
+// WithSortedQuery orders query parameters by name, keeping the order of
+// repeated names. Most servers ignore the order, but some do not.
+func WithSortedQuery() Option {
+	return func(c *Canonicalizer) {
+		c.sortQuery = true
+	}
+}
+
+// WithStrippedParams drops the named query parameters, ignoring case; a name
+// ending in '*' drops every parameter starting with it, as in "utm_*".
+func WithStrippedParams(names []string) Option {
+	return func(c *Canonicalizer) {
+		for _, name := range names {
+			name = strings.ToLower(strings.TrimSpace(name))
+			switch {
+			case name == "" || name == "*":
+			case strings.HasSuffix(name, "*"):
+				c.stripPrefixes = append(c.stripPrefixes, strings.TrimSuffix(name, "*"))
+			default:
+				c.stripNames = append(c.stripNames, name)
+			}
+		}
+	}
+}
+
+// Canonicalizer rewrites URLs into a canonical form, so that spellings of
+// the same destination can be recognized as one. It lowercases the scheme
+// and host, drops default ports, adds the root path when there is none,
+// decodes percent-encoded unreserved characters and uppercases the
+// remaining escapes.
+type Canonicalizer struct {
+	sortQuery     bool
+	stripNames    []string
+	stripPrefixes []string
+}
+
+func NewCanonicalizer(opts ...Option) *Canonicalizer {
+	c := &Canonicalizer{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Canonicalizer) Canonicalize(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return "", ErrInvalidURL
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.ToLower(parsed.Scheme))
+	b.WriteString("://")
+	if parsed.User != nil {
+		b.WriteString(parsed.User.String())
+		b.WriteByte('@')
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	b.WriteString(host)
+	if port := parsed.Port(); port != "" && port != defaultPorts[strings.ToLower(parsed.Scheme)] {
+		b.WriteByte(':')
+		b.WriteString(port)
+	}
+
+	path := normalizeEscapes(parsed.EscapedPath())
+	if path == "" {
+		path = "/"
+	}
+	b.WriteString(path)
+
+	if query := c.canonicalQuery(parsed.RawQuery); query != "" {
+		b.WriteByte('?')
+		b.WriteString(query)
+	}
+	if parsed.Fragment != "" {
+		b.WriteByte('#')
+		b.WriteString(normalizeEscapes(parsed.EscapedFragment()))
+	}
+
+	return b.String(), nil
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// canonicalQuery normalizes each parameter's escapes without decoding it,
+// as '+' and '%20' are not equivalent to every server.
+func (c *Canonicalizer) canonicalQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	type param struct {
+		name, raw string
+	}
+	var params []param
+	for _, raw := range strings.Split(rawQuery, "&") {
+		if raw == "" {
+			continue
+		}
+		raw = normalizeEscapes(raw)
+		name, _, _ := strings.Cut(raw, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		if c.stripped(name) {
+			continue
+		}
+		params = append(params, param{name: name, raw: raw})
+	}
+
+	if c.sortQuery {
+		sort.SliceStable(params, func(i, j int) bool {
+			return params[i].name < params[j].name
+		})
+	}
+
+	parts := make([]string, len(params))
+	for i, p := range params {
+		parts[i] = p.raw
+	}
+	return strings.Join(parts, "&")
+}
+
+func (c *Canonicalizer) stripped(name string) bool {
+	name = strings.ToLower(name)
+	for _, stripped := range c.stripNames {
+		if name == stripped {
+			return true
+		}
+	}
+	for _, prefix := range c.stripPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeEscapes decodes escaped unreserved characters, which mean the
+// same either way, and uppercases the hex digits of every other escape.
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		decoded := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(decoded) {
+			b.WriteByte(decoded)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
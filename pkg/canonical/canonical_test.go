@@ -0,0 +1,63 @@
+package canonical
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalize(t *testing.T) {
+	c := NewCanonicalizer()
+
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"HTTPS://Example.com/", "https://example.com/"},
+		{"https://example.com", "https://example.com/"},
+		{"https://example.com:443/a", "https://example.com/a"},
+		{"http://example.com:80/a", "http://example.com/a"},
+		{"https://example.com:8443/a", "https://example.com:8443/a"},
+		{"https://example.com./a", "https://example.com/a"},
+		{"https://example.com/%7euser/%41b", "https://example.com/~user/Ab"},
+		{"https://example.com/a%2fb?q=%e2%82%ac", "https://example.com/a%2Fb?q=%E2%82%AC"},
+		{"https://example.com/Path?b=2&a=1#Frag", "https://example.com/Path?b=2&a=1#Frag"},
+		{"https://example.com/?", "https://example.com/"},
+		{"https://user:pw@Example.com/", "https://user:pw@example.com/"},
+	}
+	for _, tt := range tests {
+		got, err := c.Canonicalize(tt.raw)
+		require.NoError(t, err, tt.raw)
+		assert.Equal(t, tt.want, got, tt.raw)
+	}
+}
+
+func TestCanonicalize_SortedQuery(t *testing.T) {
+	c := NewCanonicalizer(WithSortedQuery())
+
+	got, err := c.Canonicalize("https://example.com/?b=2&a=1&b=1&%61=0")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/?a=1&a=0&b=2&b=1", got)
+}
+
+func TestCanonicalize_StrippedParams(t *testing.T) {
+	c := NewCanonicalizer(WithStrippedParams([]string{"utm_*", " FBCLID ", ""}))
+
+	got, err := c.Canonicalize("https://example.com/p?utm_source=x&id=7&fbclid=abc&UTM_Medium=y")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/p?id=7", got)
+
+	got, err = c.Canonicalize("https://example.com/p?utm_source=x")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/p", got)
+}
+
+func TestCanonicalize_Invalid(t *testing.T) {
+	c := NewCanonicalizer()
+
+	for _, raw := range []string{"not a url", "https://exa mple.com", "%zz"} {
+		_, err := c.Canonicalize(raw)
+		assert.ErrorIs(t, err, ErrInvalidURL, raw)
+	}
+}
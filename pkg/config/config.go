@@ -3,17 +3,121 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/netip"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/mikiasyonas/url-shortener/pkg/shortcode"
+)
+
+const (
+	RateLimitBackendMemory = "memory"
+	RateLimitBackendRedis  = "redis"
+
+	BloomFilterBackendMemory = "memory"
+	BloomFilterBackendRedis  = "redis"
+
+	ShortCodeStrategyRandom  = "random"
+	ShortCodeStrategyCounter = "counter"
+
+	ShortCodeCounterPostgres = "postgres"
+	ShortCodeCounterRedis    = "redis"
+
+	KeyPoolBackendPostgres = "postgres"
+	KeyPoolBackendRedis    = "redis"
+
+	IdempotencyBackendPostgres = "postgres"
+	IdempotencyBackendRedis    = "redis"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	App      AppConfig
-	Redis    RedisConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	App         AppConfig
+	Redis       RedisConfig
+	LocalCache  LocalCacheConfig
+	BloomFilter BloomFilterConfig
+	KeyPool     KeyPoolConfig
+	Analytics   AnalyticsConfig
+	Screening   ScreeningConfig
+	LinkCheck   LinkCheckConfig
+	Idempotency IdempotencyConfig
+}
+
+// IdempotencyConfig controls where responses to requests carrying an
+// Idempotency-Key header are kept for replay, and for how long. An empty
+// Backend ignores the header.
+type IdempotencyConfig struct {
+	Backend string
+	TTL     time.Duration
+}
+
+// LinkCheckConfig controls the background checker that finds links whose
+// destinations have gone dead. It is disabled unless Enabled is set.
+type LinkCheckConfig struct {
+	Enabled      bool
+	Interval     time.Duration
+	BatchSize    int
+	RecheckAfter time.Duration
+	Concurrency  int
+	HostDelay    time.Duration
+	Timeout      time.Duration
+}
+
+// ScreeningConfig points destination screening at its lists. Destinations
+// on the shortener's own hosts and on private networks are always
+// rejected; an empty path disables that list.
+type ScreeningConfig struct {
+	BlocklistFile  string
+	AllowlistFile  string
+	HashPrefixFile string
+	// ResolveHosts also rejects names that resolve to private addresses.
+	ResolveHosts bool
+}
+
+// KeyPoolConfig controls the pool of pre-generated short codes that new
+// links draw from. An empty Backend disables it.
+type KeyPoolConfig struct {
+	Backend        string
+	LowWater       int
+	Target         int
+	BatchSize      int
+	RefillInterval time.Duration
+}
+
+// BloomFilterConfig controls the filter of issued short codes that turns
+// away redirects for codes that were never issued. An empty Backend
+// disables it.
+type BloomFilterConfig struct {
+	Backend           string
+	ExpectedCodes     int
+	FalsePositiveRate float64
+	// File is where the memory backend snapshots the filter on shutdown.
+	File string
+	// SingleInstance acknowledges that only one instance runs, which the
+	// memory backend requires: it never sees codes issued by other instances
+	// and would turn their redirects away.
+	SingleInstance bool
+}
+
+// LocalCacheConfig sizes the in-process cache kept in front of Redis. A
+// MaxEntries of zero disables it.
+type LocalCacheConfig struct {
+	MaxEntries int
+	MaxBytes   int
+	TTL        time.Duration
+}
+
+type AnalyticsConfig struct {
+	BufferSize    int
+	BatchSize     int
+	FlushInterval time.Duration
+	IPHashSalt    string
+
+	RollupInterval    time.Duration
+	RollupSettleDelay time.Duration
 }
 
 type RedisConfig struct {
@@ -22,6 +126,12 @@ type RedisConfig struct {
 	DB       int
 	PoolSize int
 	TTL      time.Duration
+
+	// NegativeTTL is how long an unknown short code is cached as missing.
+	// Zero disables negative caching.
+	NegativeTTL time.Duration
+
+	ClickFlushInterval time.Duration
 }
 
 type ServerConfig struct {
@@ -32,6 +142,10 @@ type ServerConfig struct {
 	IdleTimeout        time.Duration
 	ShutdownTimeout    time.Duration
 	CORSAllowedOrigins []string
+	TrustedProxies     []string
+	// ClientIPHeader is the header the trusted proxies record the client
+	// address in; no other forwarding header is believed.
+	ClientIPHeader string
 }
 
 type DatabaseConfig struct {
@@ -51,6 +165,40 @@ type AppConfig struct {
 	ShortCodeLength    int
 	MaxURLLength       int
 	RateLimitPerSecond int
+	RateLimitBurst     int
+	RateLimitBackend   string
+	AliasMinLength     int
+	AliasMaxLength     int
+	ReservedAliases    []string
+
+	// ShortCodeStrategy selects random codes or codes derived from a counter;
+	// ShortCodeCounter is where the counter lives and ShortCodeSecret keys
+	// the permutation that hides it.
+	ShortCodeStrategy string
+	ShortCodeCounter  string
+	ShortCodeSecret   string
+
+	// ShortCodeAlphabet is base62, base58, base36 or a custom set of
+	// characters.
+	ShortCodeAlphabet       string
+	ShortCodeBlocklist      []string
+	ShortCodeCheckCharacter bool
+
+	// DomainRefreshInterval is how often each instance reloads the branded
+	// domains it answers redirects for.
+	DomainRefreshInterval time.Duration
+
+	// RedirectCacheMaxAge is how long browsers may cache permanent
+	// redirects. Cached redirects are not counted as clicks, so the default
+	// of zero keeps every redirect out of caches.
+	RedirectCacheMaxAge time.Duration
+
+	// CanonicalSortQuery and CanonicalStripParams widen which spellings of
+	// a destination are deduplicated into one link: query parameters are
+	// compared in any order, and the listed ones (a trailing '*' matches a
+	// prefix) are ignored.
+	CanonicalSortQuery   bool
+	CanonicalStripParams []string
 }
 
 func Load() *Config {
@@ -63,6 +211,8 @@ func Load() *Config {
 			IdleTimeout:        getEnvAsDuration("SERVER_IDLE_TIMEOUT", 60*time.Second),
 			ShutdownTimeout:    getEnvAsDuration("SERVER_SHUTDOWN_TIMEOUT", 10*time.Second),
 			CORSAllowedOrigins: getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"*"}, ","),
+			TrustedProxies:     getEnvAsSlice("SERVER_TRUSTED_PROXIES", nil, ","),
+			ClientIPHeader:     getEnv("SERVER_CLIENT_IP_HEADER", "X-Forwarded-For"),
 		},
 		Database: DatabaseConfig{
 			Host:            getEnv("DB_HOST", "localhost"),
@@ -80,6 +230,25 @@ func Load() *Config {
 			ShortCodeLength:    getEnvAsInt("APP_SHORT_CODE_LENGTH", 6),
 			MaxURLLength:       getEnvAsInt("APP_MAX_URL_LENGTH", 2048),
 			RateLimitPerSecond: getEnvAsInt("APP_RATE_LIMIT_PER_SECOND", 100),
+			RateLimitBurst:     getEnvAsInt("APP_RATE_LIMIT_BURST", 100),
+			RateLimitBackend:   getEnv("APP_RATE_LIMIT_BACKEND", RateLimitBackendMemory),
+			AliasMinLength:     getEnvAsInt("APP_ALIAS_MIN_LENGTH", 3),
+			AliasMaxLength:     getEnvAsInt("APP_ALIAS_MAX_LENGTH", 32),
+			ReservedAliases:    getEnvAsSlice("APP_RESERVED_ALIASES", []string{"api", "health", "metrics", "ready", "live"}, ","),
+
+			ShortCodeStrategy: getEnv("APP_SHORT_CODE_STRATEGY", ShortCodeStrategyRandom),
+			ShortCodeCounter:  getEnv("APP_SHORT_CODE_COUNTER", ShortCodeCounterPostgres),
+			ShortCodeSecret:   getEnv("APP_SHORT_CODE_SECRET", ""),
+
+			ShortCodeAlphabet:       getEnv("APP_SHORT_CODE_ALPHABET", "base62"),
+			ShortCodeBlocklist:      getEnvAsSlice("APP_SHORT_CODE_BLOCKLIST", nil, ","),
+			ShortCodeCheckCharacter: getEnvAsBool("APP_SHORT_CODE_CHECK_CHARACTER", false),
+
+			DomainRefreshInterval: getEnvAsDuration("APP_DOMAIN_REFRESH_INTERVAL", time.Minute),
+			RedirectCacheMaxAge:   getEnvAsDuration("APP_REDIRECT_CACHE_MAX_AGE", 0),
+
+			CanonicalSortQuery:   getEnvAsBool("APP_CANONICAL_SORT_QUERY", false),
+			CanonicalStripParams: getEnvAsSlice("APP_CANONICAL_STRIP_PARAMS", nil, ","),
 		},
 		Redis: RedisConfig{
 			URL:      getEnv("REDIS_URL", "localhost:6379"),
@@ -87,6 +256,57 @@ func Load() *Config {
 			DB:       getEnvAsInt("REDIS_DB", 0),
 			PoolSize: getEnvAsInt("REDIS_POOL_SIZE", 100),
 			TTL:      getEnvAsDuration("REDIS_TTL", 24*time.Hour),
+
+			NegativeTTL: getEnvAsDuration("REDIS_NEGATIVE_TTL", 30*time.Second),
+
+			ClickFlushInterval: getEnvAsDuration("REDIS_CLICK_FLUSH_INTERVAL", 10*time.Second),
+		},
+		LocalCache: LocalCacheConfig{
+			MaxEntries: getEnvAsInt("LOCAL_CACHE_MAX_ENTRIES", 10000),
+			MaxBytes:   getEnvAsInt("LOCAL_CACHE_MAX_BYTES", 32<<20),
+			TTL:        getEnvAsDuration("LOCAL_CACHE_TTL", 5*time.Second),
+		},
+		BloomFilter: BloomFilterConfig{
+			Backend:           getEnv("BLOOM_FILTER_BACKEND", ""),
+			ExpectedCodes:     getEnvAsInt("BLOOM_FILTER_EXPECTED_CODES", 10000000),
+			FalsePositiveRate: getEnvAsFloat("BLOOM_FILTER_FALSE_POSITIVE_RATE", 0.01),
+			File:              getEnv("BLOOM_FILTER_FILE", ""),
+			SingleInstance:    getEnvAsBool("BLOOM_FILTER_SINGLE_INSTANCE", false),
+		},
+		KeyPool: KeyPoolConfig{
+			Backend:        getEnv("KEY_POOL_BACKEND", ""),
+			LowWater:       getEnvAsInt("KEY_POOL_LOW_WATER", 10000),
+			Target:         getEnvAsInt("KEY_POOL_TARGET", 50000),
+			BatchSize:      getEnvAsInt("KEY_POOL_BATCH_SIZE", 100),
+			RefillInterval: getEnvAsDuration("KEY_POOL_REFILL_INTERVAL", 10*time.Second),
+		},
+		Analytics: AnalyticsConfig{
+			BufferSize:    getEnvAsInt("ANALYTICS_BUFFER_SIZE", 10000),
+			BatchSize:     getEnvAsInt("ANALYTICS_BATCH_SIZE", 500),
+			FlushInterval: getEnvAsDuration("ANALYTICS_FLUSH_INTERVAL", 2*time.Second),
+			IPHashSalt:    getEnv("ANALYTICS_IP_HASH_SALT", ""),
+
+			RollupInterval:    getEnvAsDuration("ANALYTICS_ROLLUP_INTERVAL", time.Minute),
+			RollupSettleDelay: getEnvAsDuration("ANALYTICS_ROLLUP_SETTLE_DELAY", 2*time.Minute),
+		},
+		Screening: ScreeningConfig{
+			BlocklistFile:  getEnv("SCREENING_BLOCKLIST_FILE", ""),
+			AllowlistFile:  getEnv("SCREENING_ALLOWLIST_FILE", ""),
+			HashPrefixFile: getEnv("SCREENING_HASH_PREFIX_FILE", ""),
+			ResolveHosts:   getEnvAsBool("SCREENING_RESOLVE_HOSTS", false),
+		},
+		LinkCheck: LinkCheckConfig{
+			Enabled:      getEnvAsBool("LINK_CHECK_ENABLED", false),
+			Interval:     getEnvAsDuration("LINK_CHECK_INTERVAL", time.Minute),
+			BatchSize:    getEnvAsInt("LINK_CHECK_BATCH_SIZE", 100),
+			RecheckAfter: getEnvAsDuration("LINK_CHECK_RECHECK_AFTER", 24*time.Hour),
+			Concurrency:  getEnvAsInt("LINK_CHECK_CONCURRENCY", 8),
+			HostDelay:    getEnvAsDuration("LINK_CHECK_HOST_DELAY", time.Second),
+			Timeout:      getEnvAsDuration("LINK_CHECK_TIMEOUT", 10*time.Second),
+		},
+		Idempotency: IdempotencyConfig{
+			Backend: getEnv("IDEMPOTENCY_BACKEND", IdempotencyBackendPostgres),
+			TTL:     getEnvAsDuration("IDEMPOTENCY_TTL", 24*time.Hour),
 		},
 	}
 }
@@ -95,6 +315,16 @@ func (c *Config) Validate() error {
 	if c.Server.Port == "" {
 		return fmt.Errorf("SERVER_PORT is required")
 	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if !isIPOrCIDR(strings.TrimSpace(proxy)) {
+			return fmt.Errorf("SERVER_TRUSTED_PROXIES contains an invalid address or CIDR: %q", proxy)
+		}
+	}
+	switch strings.ToLower(c.Server.ClientIPHeader) {
+	case "x-forwarded-for", "forwarded", "x-real-ip":
+	default:
+		return fmt.Errorf("SERVER_CLIENT_IP_HEADER must be X-Forwarded-For, Forwarded or X-Real-IP")
+	}
 	if c.Database.Host == "" {
 		return fmt.Errorf("DB_HOST is required")
 	}
@@ -107,9 +337,121 @@ func (c *Config) Validate() error {
 	if c.App.ShortCodeLength < 4 || c.App.ShortCodeLength > 10 {
 		return fmt.Errorf("APP_SHORT_CODE_LENGTH must be between 4 and 10")
 	}
+	if c.App.MaxURLLength <= 0 {
+		return fmt.Errorf("APP_MAX_URL_LENGTH must be positive")
+	}
+	if _, err := shortcode.ParseAlphabet(c.App.ShortCodeAlphabet); err != nil {
+		return fmt.Errorf("APP_SHORT_CODE_ALPHABET: %w", err)
+	}
+	switch c.App.ShortCodeStrategy {
+	case ShortCodeStrategyRandom:
+	case ShortCodeStrategyCounter:
+		if c.App.ShortCodeCounter != ShortCodeCounterPostgres && c.App.ShortCodeCounter != ShortCodeCounterRedis {
+			return fmt.Errorf("APP_SHORT_CODE_COUNTER must be %q or %q", ShortCodeCounterPostgres, ShortCodeCounterRedis)
+		}
+		if len(c.App.ShortCodeSecret) < 16 {
+			return fmt.Errorf("APP_SHORT_CODE_SECRET must be at least 16 characters with the counter strategy")
+		}
+	default:
+		return fmt.Errorf("APP_SHORT_CODE_STRATEGY must be %q or %q", ShortCodeStrategyRandom, ShortCodeStrategyCounter)
+	}
+	if c.App.AliasMinLength < 1 || c.App.AliasMaxLength > 32 || c.App.AliasMinLength > c.App.AliasMaxLength {
+		return fmt.Errorf("APP_ALIAS_MIN_LENGTH and APP_ALIAS_MAX_LENGTH must satisfy 1 <= min <= max <= 32")
+	}
+	if c.App.DomainRefreshInterval <= 0 {
+		return fmt.Errorf("APP_DOMAIN_REFRESH_INTERVAL must be positive")
+	}
+	if c.App.RedirectCacheMaxAge < 0 {
+		return fmt.Errorf("APP_REDIRECT_CACHE_MAX_AGE must not be negative")
+	}
+	if c.App.RateLimitPerSecond <= 0 || c.App.RateLimitBurst <= 0 {
+		return fmt.Errorf("APP_RATE_LIMIT_PER_SECOND and APP_RATE_LIMIT_BURST must be positive")
+	}
+	if c.App.RateLimitBackend != RateLimitBackendMemory && c.App.RateLimitBackend != RateLimitBackendRedis {
+		return fmt.Errorf("APP_RATE_LIMIT_BACKEND must be %q or %q", RateLimitBackendMemory, RateLimitBackendRedis)
+	}
 	if c.Redis.URL == "" {
 		return fmt.Errorf("REDIS_URL is required")
 	}
+	if c.Redis.NegativeTTL != 0 && c.Redis.NegativeTTL < time.Second {
+		return fmt.Errorf("REDIS_NEGATIVE_TTL must be 0 or at least 1s")
+	}
+	if c.Redis.ClickFlushInterval <= 0 {
+		return fmt.Errorf("REDIS_CLICK_FLUSH_INTERVAL must be positive")
+	}
+	if c.LocalCache.MaxEntries < 0 {
+		return fmt.Errorf("LOCAL_CACHE_MAX_ENTRIES must not be negative")
+	}
+	if c.LocalCache.MaxEntries > 0 && (c.LocalCache.MaxBytes <= 0 || c.LocalCache.TTL <= 0) {
+		return fmt.Errorf("LOCAL_CACHE_MAX_BYTES and LOCAL_CACHE_TTL must be positive when the local cache is enabled")
+	}
+	switch c.BloomFilter.Backend {
+	case "", BloomFilterBackendMemory, BloomFilterBackendRedis:
+	default:
+		return fmt.Errorf("BLOOM_FILTER_BACKEND must be empty, %q or %q", BloomFilterBackendMemory, BloomFilterBackendRedis)
+	}
+	if c.BloomFilter.Backend != "" {
+		if c.BloomFilter.ExpectedCodes <= 0 {
+			return fmt.Errorf("BLOOM_FILTER_EXPECTED_CODES must be positive")
+		}
+		if c.BloomFilter.FalsePositiveRate <= 0 || c.BloomFilter.FalsePositiveRate >= 1 {
+			return fmt.Errorf("BLOOM_FILTER_FALSE_POSITIVE_RATE must be between 0 and 1")
+		}
+	}
+	if c.BloomFilter.Backend == BloomFilterBackendMemory && !c.BloomFilter.SingleInstance {
+		return fmt.Errorf("BLOOM_FILTER_BACKEND %q is per process and requires BLOOM_FILTER_SINGLE_INSTANCE=true", BloomFilterBackendMemory)
+	}
+	switch c.KeyPool.Backend {
+	case "", KeyPoolBackendPostgres, KeyPoolBackendRedis:
+	default:
+		return fmt.Errorf("KEY_POOL_BACKEND must be empty, %q or %q", KeyPoolBackendPostgres, KeyPoolBackendRedis)
+	}
+	if c.KeyPool.Backend != "" {
+		if c.KeyPool.LowWater <= 0 || c.KeyPool.Target <= c.KeyPool.LowWater {
+			return fmt.Errorf("KEY_POOL_LOW_WATER and KEY_POOL_TARGET must satisfy 0 < low water < target")
+		}
+		if c.KeyPool.BatchSize <= 0 || c.KeyPool.RefillInterval <= 0 {
+			return fmt.Errorf("KEY_POOL_BATCH_SIZE and KEY_POOL_REFILL_INTERVAL must be positive")
+		}
+	}
+	if c.Analytics.BufferSize <= 0 || c.Analytics.BatchSize <= 0 {
+		return fmt.Errorf("ANALYTICS_BUFFER_SIZE and ANALYTICS_BATCH_SIZE must be positive")
+	}
+	if c.Analytics.FlushInterval <= 0 {
+		return fmt.Errorf("ANALYTICS_FLUSH_INTERVAL must be positive")
+	}
+	if c.Analytics.RollupInterval <= 0 {
+		return fmt.Errorf("ANALYTICS_ROLLUP_INTERVAL must be positive")
+	}
+	if c.LinkCheck.Enabled {
+		if c.LinkCheck.Interval <= 0 || c.LinkCheck.RecheckAfter <= 0 || c.LinkCheck.Timeout <= 0 {
+			return fmt.Errorf("LINK_CHECK_INTERVAL, LINK_CHECK_RECHECK_AFTER and LINK_CHECK_TIMEOUT must be positive")
+		}
+		if c.LinkCheck.BatchSize <= 0 || c.LinkCheck.Concurrency <= 0 {
+			return fmt.Errorf("LINK_CHECK_BATCH_SIZE and LINK_CHECK_CONCURRENCY must be positive")
+		}
+		if c.LinkCheck.HostDelay < 0 {
+			return fmt.Errorf("LINK_CHECK_HOST_DELAY must not be negative")
+		}
+	}
+	switch c.Idempotency.Backend {
+	case "", IdempotencyBackendPostgres, IdempotencyBackendRedis:
+	default:
+		return fmt.Errorf("IDEMPOTENCY_BACKEND must be empty, %q or %q", IdempotencyBackendPostgres, IdempotencyBackendRedis)
+	}
+	if c.Idempotency.Backend != "" && c.Idempotency.TTL <= 0 {
+		return fmt.Errorf("IDEMPOTENCY_TTL must be positive")
+	}
+	return nil
+}
+
+// ValidateAnalytics checks the settings needed to record click analytics,
+// which only the API server does.
+func (c *Config) ValidateAnalytics() error {
+	// An unsalted SHA-256 of an IPv4 address is reversed by trying them all.
+	if len(c.Analytics.IPHashSalt) < 16 {
+		return fmt.Errorf("ANALYTICS_IP_HASH_SALT must be at least 16 characters")
+	}
 	return nil
 }
 
@@ -121,6 +463,14 @@ func (c *Config) IsDevelopment() bool {
 	return c.Server.Env == "development"
 }
 
+func isIPOrCIDR(s string) bool {
+	if _, err := netip.ParsePrefix(s); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -138,6 +488,16 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+		log.Printf("Invalid value for %s, using default: %g", key, defaultValue)
+	}
+	return defaultValue
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
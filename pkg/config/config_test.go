@@ -47,6 +47,149 @@ func TestValidate_ValidConfig(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestValidate_RateLimitBackend(t *testing.T) {
+	cfg := config.Load()
+	assert.Equal(t, config.RateLimitBackendMemory, cfg.App.RateLimitBackend)
+
+	cfg.App.RateLimitBackend = config.RateLimitBackendRedis
+	assert.NoError(t, cfg.Validate())
+
+	cfg.App.RateLimitBackend = "memcached"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_MaxURLLength(t *testing.T) {
+	cfg := config.Load()
+	assert.Equal(t, 2048, cfg.App.MaxURLLength)
+
+	cfg.App.MaxURLLength = 0
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidateAnalytics_IPHashSalt(t *testing.T) {
+	cfg := config.Load()
+
+	cfg.Analytics.IPHashSalt = ""
+	assert.Error(t, cfg.ValidateAnalytics())
+	assert.NoError(t, cfg.Validate(), "only the analytics settings need a salt")
+
+	cfg.Analytics.IPHashSalt = "too-short"
+	assert.Error(t, cfg.ValidateAnalytics())
+
+	cfg.Analytics.IPHashSalt = "test-salt-0123456789"
+	assert.NoError(t, cfg.ValidateAnalytics())
+}
+
+func TestValidate_ClientIPHeader(t *testing.T) {
+	cfg := config.Load()
+	assert.Equal(t, "X-Forwarded-For", cfg.Server.ClientIPHeader)
+
+	cfg.Server.ClientIPHeader = "forwarded"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Server.ClientIPHeader = "True-Client-IP"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_TrustedProxies(t *testing.T) {
+	cfg := config.Load()
+	assert.Empty(t, cfg.Server.TrustedProxies)
+
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "172.16.0.0/12", "127.0.0.1", "::1"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/33"}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_BloomFilter(t *testing.T) {
+	cfg := config.Load()
+	assert.Empty(t, cfg.BloomFilter.Backend)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.BloomFilter.Backend = config.BloomFilterBackendRedis
+	assert.NoError(t, cfg.Validate())
+
+	cfg.BloomFilter.FalsePositiveRate = 1.5
+	assert.Error(t, cfg.Validate())
+
+	cfg.BloomFilter.FalsePositiveRate = 0.01
+	cfg.BloomFilter.Backend = "file"
+	assert.Error(t, cfg.Validate())
+
+	cfg.BloomFilter.Backend = config.BloomFilterBackendMemory
+	assert.Error(t, cfg.Validate(), "the memory backend is refused unless single instance")
+
+	cfg.BloomFilter.SingleInstance = true
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_KeyPool(t *testing.T) {
+	cfg := config.Load()
+	assert.Empty(t, cfg.KeyPool.Backend)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.KeyPool.Backend = config.KeyPoolBackendPostgres
+	assert.NoError(t, cfg.Validate())
+
+	cfg.KeyPool.Target = cfg.KeyPool.LowWater
+	assert.Error(t, cfg.Validate())
+
+	cfg.KeyPool.Target = 2 * cfg.KeyPool.LowWater
+	cfg.KeyPool.Backend = "memory"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_Idempotency(t *testing.T) {
+	cfg := config.Load()
+	assert.Equal(t, config.IdempotencyBackendPostgres, cfg.Idempotency.Backend)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Idempotency.TTL = 0
+	assert.Error(t, cfg.Validate())
+
+	cfg.Idempotency.Backend = ""
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Idempotency.TTL = time.Hour
+	cfg.Idempotency.Backend = "memory"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_ShortCodeAlphabet(t *testing.T) {
+	cfg := config.Load()
+	assert.Equal(t, "base62", cfg.App.ShortCodeAlphabet)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.App.ShortCodeAlphabet = "base58"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.App.ShortCodeAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.App.ShortCodeAlphabet = "abc"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_ShortCodeStrategy(t *testing.T) {
+	cfg := config.Load()
+	assert.Equal(t, config.ShortCodeStrategyRandom, cfg.App.ShortCodeStrategy)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.App.ShortCodeStrategy = config.ShortCodeStrategyCounter
+	assert.Error(t, cfg.Validate(), "the counter strategy needs a secret")
+
+	cfg.App.ShortCodeSecret = "0123456789abcdef"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.App.ShortCodeCounter = "etcd"
+	assert.Error(t, cfg.Validate())
+
+	cfg.App.ShortCodeCounter = config.ShortCodeCounterRedis
+	cfg.App.ShortCodeStrategy = "sequential"
+	assert.Error(t, cfg.Validate())
+}
+
 func TestIsProduction(t *testing.T) {
 	os.Setenv("ENVIRONMENT", "production")
 	cfg := config.Load()
@@ -62,11 +62,114 @@ func Connect(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 	log.Printf("Database connected successfully to %s:%s/%s", cfg.Host, cfg.Port, cfg.Name)
 	return db, nil
 }
+
+// linkDomainUpgrades bring tables created before links had a domain up to
+// date, mirroring the migration that added it. AutoMigrate would add the
+// columns but not rebuild the keys and unique index they become part of.
+var linkDomainUpgrades = []struct {
+	table      string
+	statements []string
+}{
+	{"urls", []string{
+		`ALTER TABLE "urls" ADD COLUMN "domain" character varying(253) NOT NULL DEFAULT ''`,
+		`DROP INDEX IF EXISTS "idx_urls_short_code"`,
+		`CREATE UNIQUE INDEX "idx_urls_domain_short_code" ON "urls" ("domain", "short_code")`,
+	}},
+	{"clicks", []string{
+		`ALTER TABLE "clicks" ADD COLUMN "domain" character varying(253) NOT NULL DEFAULT ''`,
+		`DROP INDEX IF EXISTS "idx_clicks_short_code_clicked_at"`,
+	}},
+	{"click_rollups", []string{
+		`ALTER TABLE "click_rollups" DROP CONSTRAINT "click_rollups_pkey", ADD COLUMN "domain" character varying(253) NOT NULL DEFAULT '', ADD PRIMARY KEY ("domain", "short_code", "granularity", "bucket_start")`,
+	}},
+	{"click_dimension_rollups", []string{
+		`ALTER TABLE "click_dimension_rollups" DROP CONSTRAINT "click_dimension_rollups_pkey", ADD COLUMN "domain" character varying(253) NOT NULL DEFAULT '', ADD PRIMARY KEY ("domain", "short_code", "dimension", "bucket_start", "value")`,
+	}},
+	{"click_daily_visitors", []string{
+		`ALTER TABLE "click_daily_visitors" DROP CONSTRAINT "click_daily_visitors_pkey", ADD COLUMN "domain" character varying(253) NOT NULL DEFAULT '', ADD PRIMARY KEY ("domain", "short_code", "day", "ip_hash")`,
+	}},
+}
+
+func upgradeLinkDomains(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, upgrade := range linkDomainUpgrades {
+			if !tx.Migrator().HasTable(upgrade.table) || tx.Migrator().HasColumn(upgrade.table, "domain") {
+				continue
+			}
+			for _, stmt := range upgrade.statements {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// addCanonicalURLs mirrors the migration that added canonical URLs, which
+// AutoMigrate would leave empty on existing links: those links keep being
+// deduplicated on their exact destination.
+func addCanonicalURLs(db *gorm.DB) error {
+	if !db.Migrator().HasTable("urls") || db.Migrator().HasColumn("urls", "canonical_url") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`ALTER TABLE "urls" ADD COLUMN "canonical_url" text NOT NULL DEFAULT ''`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE "urls" SET "canonical_url" = "original_url"`).Error
+	})
+}
+
+// shareCanonicalURLs mirrors the migration that replaced the canonical URL
+// index with hashes of the shared links' canonical URLs. It runs once
+// AutoMigrate has added the hash column, and keeps the oldest permanent link
+// to each destination shared.
+func shareCanonicalURLs(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`UPDATE "urls" SET "canonical_url_hash" = sha256(convert_to("canonical_url", 'UTF8')) WHERE "id" IN (` +
+			`SELECT DISTINCT ON ("owner_id", "domain", "redirect_type", "canonical_url") "id" FROM "urls" WHERE "expires_at" IS NULL ` +
+			`ORDER BY "owner_id", "domain", "redirect_type", "canonical_url", "created_at")`).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`DROP INDEX IF EXISTS "idx_urls_owner_id_domain_canonical_url"`).Error
+	})
+}
+
 func AutoMigrate(db *gorm.DB) error {
-	err := db.AutoMigrate(&domain.URL{})
+	if err := upgradeLinkDomains(db); err != nil {
+		return fmt.Errorf("failed to add domains to links: %w", err)
+	}
+	if err := addCanonicalURLs(db); err != nil {
+		return fmt.Errorf("failed to add canonical URLs to links: %w", err)
+	}
+	shareLinks := db.Migrator().HasTable("urls") && !db.Migrator().HasColumn("urls", "canonical_url_hash")
+	err := db.AutoMigrate(
+		&domain.URL{},
+		&domain.APIKey{},
+		&domain.Click{},
+		&domain.ClickRollup{},
+		&domain.ClickDimensionRollup{},
+		&domain.ClickDailyVisitor{},
+		&domain.RollupCheckpoint{},
+		&domain.PooledShortCode{},
+		&domain.Domain{},
+		&domain.IdempotencyKey{},
+	)
 	if err != nil {
 		return fmt.Errorf("failed to auto-migrate: %w", err)
 	}
+	if shareLinks {
+		if err := shareCanonicalURLs(db); err != nil {
+			return fmt.Errorf("failed to share existing links: %w", err)
+		}
+	}
+	// Sequences are not modelled by gorm; this mirrors the migration that
+	// creates the one used for counter-based short codes.
+	if err := db.Exec(`CREATE SEQUENCE IF NOT EXISTS "short_code_ids"`).Error; err != nil {
+		return fmt.Errorf("failed to create short code sequence: %w", err)
+	}
 	log.Println("Database migration completed")
 	return nil
 }
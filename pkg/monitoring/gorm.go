@@ -0,0 +1,73 @@
+package monitoring
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const queryStartKey = "monitoring:query_start"
+
+// GormPlugin records the count, latency and failures of every query issued
+// through a gorm.DB. Register it with db.Use(monitoring.NewGormPlugin(m)).
+type GormPlugin struct {
+	metrics *Metrics
+}
+
+func NewGormPlugin(metrics *Metrics) *GormPlugin {
+	return &GormPlugin{metrics: metrics}
+}
+
+func (p *GormPlugin) Name() string {
+	return "monitoring"
+}
+
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	type register func(name string, fn func(*gorm.DB)) error
+	hooks := []struct {
+		operation     string
+		before, after register
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+
+	for _, hook := range hooks {
+		if err := hook.before("monitoring:before_"+hook.operation, p.before); err != nil {
+			return err
+		}
+		if err := hook.after("monitoring:after_"+hook.operation, p.after(hook.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *GormPlugin) before(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func (p *GormPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		p.metrics.RecordDBQuery(operation, time.Since(start))
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			p.metrics.RecordDBError(operation)
+		}
+	}
+}
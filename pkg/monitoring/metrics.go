@@ -1,50 +1,100 @@
 package monitoring
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
+const namespace = "url_shortener"
+
+// defaultBuckets are latency histogram upper bounds in seconds.
+var defaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	method string
+	route  string
+	status int
+}
+
+type routeStatusKey struct {
+	route  string
+	status int
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(defaultBuckets))}
+}
+
+func (h *histogram) observe(seconds float64) {
+	for i, bound := range defaultBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
 type Metrics struct {
 	mu sync.RWMutex
 
-	requestsTotal    map[string]int64
-	requestDurations []time.Duration
-	statusCodes      map[int]int64
+	requestsTotal    map[requestKey]int64
+	requestDurations map[routeStatusKey]*histogram
 	activeRequests   int64
 
 	urlsShortened  int64
 	urlsRedirected int64
-	cacheHits      int64
-	cacheMisses    int64
+	cacheHits      map[string]int64
+	cacheMisses    map[string]int64
+	cacheNegative  map[string]int64
+	keyPoolDepth   int64
 
-	dbQueriesTotal int64
-	dbQueryErrors  int64
+	dbQueriesTotal   map[string]int64
+	dbQueryErrors    map[string]int64
+	dbQueryDurations map[string]*histogram
 
 	startTime time.Time
 }
 
 func NewMetrics() *Metrics {
 	return &Metrics{
-		requestsTotal:    make(map[string]int64),
-		statusCodes:      make(map[int]int64),
-		requestDurations: make([]time.Duration, 0, 1000),
+		requestsTotal:    make(map[requestKey]int64),
+		requestDurations: make(map[routeStatusKey]*histogram),
+		cacheHits:        make(map[string]int64),
+		cacheMisses:      make(map[string]int64),
+		cacheNegative:    make(map[string]int64),
+		dbQueriesTotal:   make(map[string]int64),
+		dbQueryErrors:    make(map[string]int64),
+		dbQueryDurations: make(map[string]*histogram),
 		startTime:        time.Now(),
 	}
 }
 
-func (m *Metrics) RecordRequest(method string, statusCode int, duration time.Duration) {
+func (m *Metrics) RecordRequest(method, route string, statusCode int, duration time.Duration) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.requestsTotal[method]++
-	m.statusCodes[statusCode]++
+	m.requestsTotal[requestKey{method: method, route: route, status: statusCode}]++
 
-	if len(m.requestDurations) >= 1000 {
-		m.requestDurations = m.requestDurations[1:]
+	key := routeStatusKey{route: route, status: statusCode}
+	h, ok := m.requestDurations[key]
+	if !ok {
+		h = newHistogram()
+		m.requestDurations[key] = h
 	}
-	m.requestDurations = append(m.requestDurations, duration)
+	h.observe(duration.Seconds())
 }
 
 func (m *Metrics) RecordURLShortened() {
@@ -59,28 +109,54 @@ func (m *Metrics) RecordURLRedirected() {
 	m.urlsRedirected++
 }
 
-func (m *Metrics) RecordCacheHit() {
+// RecordCacheHit counts a lookup answered by the named cache tier.
+func (m *Metrics) RecordCacheHit(cache string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cacheHits[cache]++
+}
+
+// RecordCacheMiss counts a lookup the named cache tier could not answer.
+func (m *Metrics) RecordCacheMiss(cache string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.cacheHits++
+	m.cacheMisses[cache]++
 }
 
-func (m *Metrics) RecordCacheMiss() {
+// RecordCacheNegativeHit counts a lookup the named cache tier answered with
+// a cached "not found", sparing the database.
+func (m *Metrics) RecordCacheNegativeHit(cache string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.cacheMisses++
+	m.cacheNegative[cache]++
 }
 
-func (m *Metrics) RecordDBQuery() {
+// RecordKeyPoolDepth sets the number of pre-generated short codes left in
+// the shared key pool.
+func (m *Metrics) RecordKeyPoolDepth(depth int64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.dbQueriesTotal++
+	m.keyPoolDepth = depth
 }
 
-func (m *Metrics) RecordDBError() {
+func (m *Metrics) RecordDBQuery(operation string, duration time.Duration) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.dbQueryErrors++
+
+	m.dbQueriesTotal[operation]++
+
+	h, ok := m.dbQueryDurations[operation]
+	if !ok {
+		h = newHistogram()
+		m.dbQueryDurations[operation] = h
+	}
+	h.observe(duration.Seconds())
+}
+
+func (m *Metrics) RecordDBError(operation string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dbQueryErrors[operation]++
 }
 
 func (m *Metrics) IncrementActiveRequests() {
@@ -95,39 +171,155 @@ func (m *Metrics) DecrementActiveRequests() {
 	m.activeRequests--
 }
 
-func (m *Metrics) GetMetrics() map[string]interface{} {
+// WritePrometheus renders all metrics in the Prometheus text exposition
+// format (version 0.0.4).
+func (m *Metrics) WritePrometheus(w io.Writer) error {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	var totalDuration time.Duration
-	for _, d := range m.requestDurations {
-		totalDuration += d
+	bw := bufio.NewWriter(w)
+	p := &promWriter{w: bw}
+
+	p.header("http_requests_total", "counter", "Total HTTP requests by method, route and status.")
+	keys := make([]requestKey, 0, len(m.requestsTotal))
+	for k := range m.requestsTotal {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, k := range keys {
+		p.sample("http_requests_total", labels("method", k.method, "route", k.route, "status", strconv.Itoa(k.status)), float64(m.requestsTotal[k]))
+	}
+
+	p.header("http_request_duration_seconds", "histogram", "HTTP request latency by route and status.")
+	durationKeys := make([]routeStatusKey, 0, len(m.requestDurations))
+	for k := range m.requestDurations {
+		durationKeys = append(durationKeys, k)
+	}
+	sort.Slice(durationKeys, func(i, j int) bool {
+		if durationKeys[i].route != durationKeys[j].route {
+			return durationKeys[i].route < durationKeys[j].route
+		}
+		return durationKeys[i].status < durationKeys[j].status
+	})
+	for _, k := range durationKeys {
+		p.histogram("http_request_duration_seconds", []string{"route", k.route, "status", strconv.Itoa(k.status)}, m.requestDurations[k])
+	}
+
+	p.header("http_requests_in_flight", "gauge", "HTTP requests currently being served.")
+	p.sample("http_requests_in_flight", "", float64(m.activeRequests))
+
+	p.header("urls_shortened_total", "counter", "Short links created.")
+	p.sample("urls_shortened_total", "", float64(m.urlsShortened))
+
+	p.header("urls_redirected_total", "counter", "Redirects served.")
+	p.sample("urls_redirected_total", "", float64(m.urlsRedirected))
+
+	p.labelledCounter("cache_hits_total", "Cache lookups answered by the cache tier.", "cache", m.cacheHits)
+	p.labelledCounter("cache_misses_total", "Cache lookups the cache tier could not answer.", "cache", m.cacheMisses)
+	p.labelledCounter("cache_negative_hits_total", "Cache lookups answered with a cached not-found by the cache tier.", "cache", m.cacheNegative)
+
+	p.header("key_pool_depth", "gauge", "Pre-generated short codes left in the shared key pool.")
+	p.sample("key_pool_depth", "", float64(m.keyPoolDepth))
+
+	p.labelledCounter("db_queries_total", "Database queries by operation.", "operation", m.dbQueriesTotal)
+	p.labelledCounter("db_query_errors_total", "Failed database queries by operation.", "operation", m.dbQueryErrors)
+
+	p.header("db_query_duration_seconds", "histogram", "Database query latency by operation.")
+	for _, op := range sortedKeys(m.dbQueryDurations) {
+		p.histogram("db_query_duration_seconds", []string{"operation", op}, m.dbQueryDurations[op])
+	}
+
+	p.header("start_time_seconds", "gauge", "Unix time the process started.")
+	p.sample("start_time_seconds", "", float64(m.startTime.Unix()))
+
+	p.header("uptime_seconds", "gauge", "Seconds since the process started.")
+	p.sample("uptime_seconds", "", time.Since(m.startTime).Seconds())
+
+	if p.err != nil {
+		return p.err
+	}
+	return bw.Flush()
+}
+
+type promWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (p *promWriter) printf(format string, args ...interface{}) {
+	if p.err != nil {
+		return
 	}
+	_, p.err = fmt.Fprintf(p.w, format, args...)
+}
+
+func (p *promWriter) header(name, kind, help string) {
+	p.printf("# HELP %s_%s %s\n", namespace, name, help)
+	p.printf("# TYPE %s_%s %s\n", namespace, name, kind)
+}
 
-	var avgDuration time.Duration
-	if len(m.requestDurations) > 0 {
-		avgDuration = totalDuration / time.Duration(len(m.requestDurations))
+func (p *promWriter) sample(name, labels string, value float64) {
+	p.printf("%s_%s%s %s\n", namespace, name, labels, formatFloat(value))
+}
+
+func (p *promWriter) labelledCounter(name, help, label string, values map[string]int64) {
+	p.header(name, "counter", help)
+	for _, k := range sortedKeys(values) {
+		p.sample(name, labels(label, k), float64(values[k]))
 	}
+}
 
-	var errorRate float64
-	totalRequests := int64(0)
-	for _, count := range m.requestsTotal {
-		totalRequests += count
+func (p *promWriter) histogram(name string, pairs []string, h *histogram) {
+	for i, bound := range defaultBuckets {
+		p.sample(name+"_bucket", labels(append(pairs, "le", formatFloat(bound))...), float64(h.counts[i]))
 	}
-	if totalRequests > 0 {
-		errorRate = float64(m.statusCodes[500]+m.statusCodes[502]+m.statusCodes[503]) / float64(totalRequests) * 100
+	p.sample(name+"_bucket", labels(append(pairs, "le", "+Inf")...), float64(h.count))
+	p.sample(name+"_sum", labels(pairs...), h.sum)
+	p.sample(name+"_count", labels(pairs...), float64(h.count))
+}
+
+// labels formats alternating name/value pairs as a Prometheus label set.
+func labels(pairs ...string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(pairs[i])
+		sb.WriteString(`="`)
+		sb.WriteString(escapeLabelValue(pairs[i+1]))
+		sb.WriteByte('"')
 	}
+	sb.WriteByte('}')
+	return sb.String()
+}
 
-	return map[string]interface{}{
-		"uptime_seconds":          time.Since(m.startTime).Seconds(),
-		"active_requests":         m.activeRequests,
-		"requests_total":          m.requestsTotal,
-		"status_codes":            m.statusCodes,
-		"request_duration_avg_ms": avgDuration.Milliseconds(),
-		"requests_per_second":     float64(totalRequests) / time.Since(m.startTime).Seconds(),
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
 
-		"system": map[string]interface{}{
-			"error_rate": fmt.Sprintf("%.2f%%", errorRate),
-		},
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	return keys
 }
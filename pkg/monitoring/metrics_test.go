@@ -0,0 +1,53 @@
+package monitoring
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_WritePrometheus(t *testing.T) {
+	m := NewMetrics()
+	m.RecordRequest("GET", "redirect", 302, 3*time.Millisecond)
+	m.RecordRequest("GET", "redirect", 302, 200*time.Millisecond)
+	m.RecordRequest("POST", "shorten", 201, 20*time.Millisecond)
+	m.RecordCacheHit("redis")
+	m.RecordCacheMiss("redis")
+	m.RecordCacheMiss("redis")
+	m.RecordCacheNegativeHit("redis")
+	m.RecordKeyPoolDepth(750)
+	m.RecordDBQuery("query", 2*time.Millisecond)
+	m.RecordDBError("query")
+
+	var sb strings.Builder
+	require.NoError(t, m.WritePrometheus(&sb))
+	out := sb.String()
+
+	assert.Contains(t, out, "# TYPE url_shortener_http_requests_total counter\n")
+	assert.Contains(t, out, `url_shortener_http_requests_total{method="GET",route="redirect",status="302"} 2`+"\n")
+	assert.Contains(t, out, `url_shortener_http_requests_total{method="POST",route="shorten",status="201"} 1`+"\n")
+
+	assert.Contains(t, out, "# TYPE url_shortener_http_request_duration_seconds histogram\n")
+	assert.Contains(t, out, `url_shortener_http_request_duration_seconds_bucket{route="redirect",status="302",le="0.001"} 0`+"\n")
+	assert.Contains(t, out, `url_shortener_http_request_duration_seconds_bucket{route="redirect",status="302",le="0.005"} 1`+"\n")
+	assert.Contains(t, out, `url_shortener_http_request_duration_seconds_bucket{route="redirect",status="302",le="0.25"} 2`+"\n")
+	assert.Contains(t, out, `url_shortener_http_request_duration_seconds_bucket{route="redirect",status="302",le="+Inf"} 2`+"\n")
+	assert.Contains(t, out, `url_shortener_http_request_duration_seconds_count{route="redirect",status="302"} 2`+"\n")
+
+	assert.Contains(t, out, `url_shortener_cache_hits_total{cache="redis"} 1`+"\n")
+	assert.Contains(t, out, `url_shortener_cache_misses_total{cache="redis"} 2`+"\n")
+	assert.Contains(t, out, `url_shortener_cache_negative_hits_total{cache="redis"} 1`+"\n")
+	assert.Contains(t, out, "# TYPE url_shortener_key_pool_depth gauge\n")
+	assert.Contains(t, out, "url_shortener_key_pool_depth 750\n")
+	assert.Contains(t, out, `url_shortener_db_queries_total{operation="query"} 1`+"\n")
+	assert.Contains(t, out, `url_shortener_db_query_errors_total{operation="query"} 1`+"\n")
+	assert.Contains(t, out, "# TYPE url_shortener_http_requests_in_flight gauge\n")
+}
+
+func TestLabels_EscapesValues(t *testing.T) {
+	assert.Equal(t, `{route="a\"b\\c\nd"}`, labels("route", "a\"b\\c\nd"))
+	assert.Equal(t, "", labels())
+}
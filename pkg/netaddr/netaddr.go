@@ -0,0 +1,20 @@
+// Package netaddr classifies network addresses.
+package netaddr
+
+import "net"
+
+// sharedAddressSpace is the carrier-grade NAT range, which net.IP does not
+// count as private.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPrivate reports whether ip is a loopback, private, link-local or
+// unspecified address, which links must not lead into.
+func IsPrivate(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip)
+}
@@ -0,0 +1,20 @@
+package netaddr
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPrivate(t *testing.T) {
+	for _, addr := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"100.64.0.1", "0.0.0.0", "::1", "fc00::1", "fe80::1", "::",
+	} {
+		assert.True(t, IsPrivate(net.ParseIP(addr)), addr)
+	}
+	for _, addr := range []string{"93.184.216.34", "100.128.0.1", "8.8.8.8", "2606:4700::1111"} {
+		assert.False(t, IsPrivate(net.ParseIP(addr)), addr)
+	}
+}
@@ -0,0 +1,76 @@
+package shortcode
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidAlias  = errors.New("shortcode: alias does not match the alias grammar")
+	ErrReservedAlias = errors.New("shortcode: alias is a reserved word")
+)
+
+const (
+	aliasChars = base62Chars + "-_"
+
+	defaultAliasMinLength = 3
+	defaultAliasMaxLength = 32
+)
+
+// AliasValidator checks caller-chosen short codes against the alias grammar:
+// a bounded length, base62 characters plus '-' and '_', an alphanumeric
+// first and last character, and no reserved words.
+type AliasValidator struct {
+	minLength int
+	maxLength int
+	reserved  map[string]struct{}
+}
+
+func NewAliasValidator(minLength, maxLength int, reserved []string) *AliasValidator {
+	if minLength <= 0 {
+		minLength = defaultAliasMinLength
+	}
+	if maxLength <= 0 {
+		maxLength = defaultAliasMaxLength
+	}
+
+	reservedSet := make(map[string]struct{}, len(reserved))
+	for _, word := range reserved {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" {
+			reservedSet[word] = struct{}{}
+		}
+	}
+
+	return &AliasValidator{
+		minLength: minLength,
+		maxLength: maxLength,
+		reserved:  reservedSet,
+	}
+}
+
+func (v *AliasValidator) Validate(alias string) error {
+	if len(alias) < v.minLength || len(alias) > v.maxLength {
+		return ErrInvalidAlias
+	}
+
+	for _, char := range alias {
+		if !strings.ContainsRune(aliasChars, char) {
+			return ErrInvalidAlias
+		}
+	}
+
+	if !isAlphanumeric(alias[0]) || !isAlphanumeric(alias[len(alias)-1]) {
+		return ErrInvalidAlias
+	}
+
+	if _, ok := v.reserved[strings.ToLower(alias)]; ok {
+		return ErrReservedAlias
+	}
+
+	return nil
+}
+
+func isAlphanumeric(c byte) bool {
+	return strings.IndexByte(base62Chars, c) >= 0
+}
@@ -0,0 +1,51 @@
+package shortcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Alphabet is the ordered set of characters generated codes are made of.
+type Alphabet string
+
+const (
+	AlphabetBase62 Alphabet = base62Chars
+	// AlphabetBase58 leaves out 0, O, I and l, which are easily misread.
+	AlphabetBase58 Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	// AlphabetBase36 is lowercase only, for codes that are read aloud or
+	// typed on phones.
+	AlphabetBase36 Alphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+	minAlphabetSize = 10
+)
+
+// ParseAlphabet resolves "base62", "base58" or "base36", or otherwise treats
+// name as a custom alphabet. Custom alphabets need at least ten distinct
+// characters, all allowed in aliases so that codes stay valid URL paths.
+func ParseAlphabet(name string) (Alphabet, error) {
+	switch name {
+	case "", "base62":
+		return AlphabetBase62, nil
+	case "base58":
+		return AlphabetBase58, nil
+	case "base36":
+		return AlphabetBase36, nil
+	}
+
+	if len(name) < minAlphabetSize {
+		return "", fmt.Errorf("custom alphabet must have at least %d characters", minAlphabetSize)
+	}
+	for i := 0; i < len(name); i++ {
+		if !strings.ContainsRune(aliasChars, rune(name[i])) {
+			return "", fmt.Errorf("custom alphabet may only contain letters, digits, '-' and '_'")
+		}
+		if strings.IndexByte(name[:i], name[i]) >= 0 {
+			return "", fmt.Errorf("custom alphabet repeats %q", name[i])
+		}
+	}
+	return Alphabet(name), nil
+}
+
+func (a Alphabet) size() int { return len(a) }
+
+func (a Alphabet) index(c byte) int { return strings.IndexByte(string(a), c) }
@@ -0,0 +1,239 @@
+package shortcode
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"sync"
+)
+
+const (
+	// maxCounterSpace bounds the codes of one length a CounterGenerator
+	// issues, which keeps every ID and permutation within a uint64.
+	maxCounterSpace = 1 << 62
+
+	feistelRounds = 6
+
+	defaultIDBlockSize = 100
+)
+
+var ErrIDSpaceExhausted = errors.New("shortcode: no codes left for this id")
+
+// IDAllocator hands out unique, increasing IDs, n at a time. It is
+// satisfied by the allocators in internal/adapters/sequence.
+type IDAllocator interface {
+	Allocate(ctx context.Context, n int) ([]uint64, error)
+}
+
+// CounterGenerator derives short codes from unique IDs instead of random
+// draws, so codes never collide with each other. Each ID is mapped through a
+// keyed permutation of the codes of one length, which keeps consecutive IDs
+// from producing guessable neighbours; codes start at minLength characters
+// and only grow once every code of the current length has been issued.
+type CounterGenerator struct {
+	ids       IDAllocator
+	key       []byte
+	rules     rules
+	minLength int
+	maxLength int
+	blockSize int
+
+	mu      sync.Mutex
+	pending []uint64
+}
+
+// NewCounterGenerator creates a generator whose codes are at least
+// minLength characters long, not counting the check character.
+func NewCounterGenerator(ids IDAllocator, key []byte, minLength int, opts ...Option) *CounterGenerator {
+	if minLength <= 0 {
+		minLength = defaultLength
+	}
+
+	g := &CounterGenerator{
+		ids:       ids,
+		key:       key,
+		rules:     newRules(opts),
+		blockSize: defaultIDBlockSize,
+	}
+	g.maxLength = 1
+	for g.bandSize(g.maxLength+1) < maxCounterSpace {
+		g.maxLength++
+	}
+	g.minLength = min(minLength, g.maxLength)
+	return g
+}
+
+// Generate issues the code of the next ID, skipping IDs whose code the
+// blocklist rejects.
+func (g *CounterGenerator) Generate(ctx context.Context) (string, error) {
+	for attempt := 0; attempt < maxBlockedAttempts; attempt++ {
+		id, err := g.nextID(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		code, err := g.Encode(id)
+		if err != nil {
+			return "", err
+		}
+		if !g.rules.blocked(code) {
+			return code, nil
+		}
+	}
+
+	return "", ErrAllCodesBlocked
+}
+
+// Validate accepts any code of a length the generator can issue.
+func (g *CounterGenerator) Validate(code string) bool {
+	payload, ok := g.rules.payload(code)
+	return ok && len(payload) >= g.minLength && len(payload) <= g.maxLength
+}
+
+func (g *CounterGenerator) Resembles(code string) bool {
+	return g.rules.resembles(code, g.minLength, g.maxLength)
+}
+
+// Encode returns the code for id. With an alphabet of N characters, IDs
+// below N^minLength get codes of minLength characters, the next
+// N^(minLength+1) IDs one character more, and so on.
+func (g *CounterGenerator) Encode(id uint64) (string, error) {
+	length := g.minLength
+	for {
+		size := g.bandSize(length)
+		if id < size {
+			break
+		}
+		id -= size
+		length++
+		if length > g.maxLength {
+			return "", ErrIDSpaceExhausted
+		}
+	}
+
+	index := g.permute(id, length)
+
+	radix := uint64(g.rules.alphabet.size())
+	payload := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		payload[i] = g.rules.alphabet[index%radix]
+		index /= radix
+	}
+	return g.rules.finish(string(payload)), nil
+}
+
+// Decode returns the ID that Encode mapped to code.
+func (g *CounterGenerator) Decode(code string) (uint64, bool) {
+	if !g.Validate(code) {
+		return 0, false
+	}
+	payload, _ := g.rules.payload(code)
+
+	radix := uint64(g.rules.alphabet.size())
+	var index uint64
+	for i := 0; i < len(payload); i++ {
+		index = index*radix + uint64(g.rules.alphabet.index(payload[i]))
+	}
+
+	id := g.unpermute(index, len(payload))
+	for length := g.minLength; length < len(payload); length++ {
+		id += g.bandSize(length)
+	}
+	return id, true
+}
+
+func (g *CounterGenerator) nextID(ctx context.Context) (uint64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.pending) == 0 {
+		ids, err := g.ids.Allocate(ctx, g.blockSize)
+		if err != nil {
+			return 0, err
+		}
+		if len(ids) == 0 {
+			return 0, errors.New("shortcode: id allocator returned no ids")
+		}
+		g.pending = ids
+	}
+
+	id := g.pending[0]
+	g.pending = g.pending[1:]
+	return id, nil
+}
+
+// permute applies a Feistel network over the smallest even number of bits
+// that covers the codes of one length, cycle-walking until the result is a
+// valid index. The network has at most four times as many values as there
+// are codes, so the walk is short.
+func (g *CounterGenerator) permute(x uint64, length int) uint64 {
+	size := g.bandSize(length)
+	half := feistelHalfBits(size)
+	for {
+		x = g.feistel(x, length, half)
+		if x < size {
+			return x
+		}
+	}
+}
+
+func (g *CounterGenerator) unpermute(x uint64, length int) uint64 {
+	size := g.bandSize(length)
+	half := feistelHalfBits(size)
+	for {
+		x = g.unfeistel(x, length, half)
+		if x < size {
+			return x
+		}
+	}
+}
+
+func (g *CounterGenerator) feistel(x uint64, length int, half uint) uint64 {
+	mask := uint64(1)<<half - 1
+	left, right := x>>half, x&mask
+	for round := 0; round < feistelRounds; round++ {
+		left, right = right, left^(g.round(round, length, right)&mask)
+	}
+	return left<<half | right
+}
+
+func (g *CounterGenerator) unfeistel(x uint64, length int, half uint) uint64 {
+	mask := uint64(1)<<half - 1
+	left, right := x>>half, x&mask
+	for round := feistelRounds - 1; round >= 0; round-- {
+		left, right = right^(g.round(round, length, left)&mask), left
+	}
+	return left<<half | right
+}
+
+func (g *CounterGenerator) round(round, length int, value uint64) uint64 {
+	mac := hmac.New(sha256.New, g.key)
+	var buf [10]byte
+	buf[0] = byte(round)
+	buf[1] = byte(length)
+	binary.BigEndian.PutUint64(buf[2:], value)
+	mac.Write(buf[:])
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+func feistelHalfBits(size uint64) uint {
+	n := uint(bits.Len64(size - 1))
+	return (n + 1) / 2
+}
+
+// bandSize returns the number of codes of the given length, capped at
+// maxCounterSpace.
+func (g *CounterGenerator) bandSize(length int) uint64 {
+	radix := uint64(g.rules.alphabet.size())
+	size := uint64(1)
+	for i := 0; i < length; i++ {
+		if size > maxCounterSpace/radix {
+			return maxCounterSpace
+		}
+		size *= radix
+	}
+	return size
+}
@@ -0,0 +1,109 @@
+package shortcode
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sequentialIDs struct {
+	next  uint64
+	calls int
+}
+
+func (s *sequentialIDs) Allocate(ctx context.Context, n int) ([]uint64, error) {
+	s.calls++
+	ids := make([]uint64, n)
+	for i := range ids {
+		s.next++
+		ids[i] = s.next
+	}
+	return ids, nil
+}
+
+type failingIDs struct{}
+
+func (failingIDs) Allocate(ctx context.Context, n int) ([]uint64, error) {
+	return nil, errors.New("sequence unavailable")
+}
+
+func TestCounterGenerator_UniqueAndReversible(t *testing.T) {
+	g := NewCounterGenerator(&sequentialIDs{}, []byte("test-secret"), 4)
+
+	seen := make(map[string]uint64)
+	for id := uint64(0); id < 20000; id++ {
+		code, err := g.Encode(id)
+		require.NoError(t, err)
+		assert.Len(t, code, 4)
+		assert.True(t, g.Validate(code))
+
+		if prev, dup := seen[code]; dup {
+			t.Fatalf("ids %d and %d both map to %q", prev, id, code)
+		}
+		seen[code] = id
+
+		decoded, ok := g.Decode(code)
+		require.True(t, ok)
+		assert.Equal(t, id, decoded)
+	}
+}
+
+func TestCounterGenerator_GrowsOnlyWhenLengthIsExhausted(t *testing.T) {
+	g := NewCounterGenerator(&sequentialIDs{}, []byte("test-secret"), 4)
+
+	last, err := g.Encode(g.bandSize(4) - 1)
+	require.NoError(t, err)
+	assert.Len(t, last, 4)
+
+	first, err := g.Encode(g.bandSize(4))
+	require.NoError(t, err)
+	assert.Len(t, first, 5)
+
+	decoded, ok := g.Decode(first)
+	require.True(t, ok)
+	assert.Equal(t, g.bandSize(4), decoded)
+}
+
+func TestCounterGenerator_KeyChangesCodes(t *testing.T) {
+	a := NewCounterGenerator(&sequentialIDs{}, []byte("key-a"), 6)
+	b := NewCounterGenerator(&sequentialIDs{}, []byte("key-b"), 6)
+
+	codeA, err := a.Encode(42)
+	require.NoError(t, err)
+	codeB, err := b.Encode(42)
+	require.NoError(t, err)
+	assert.NotEqual(t, codeA, codeB)
+
+	next, err := a.Encode(43)
+	require.NoError(t, err)
+	assert.NotEqual(t, codeA[:3], next[:3], "consecutive ids should not share a visible prefix")
+}
+
+func TestCounterGenerator_AllocatesInBlocks(t *testing.T) {
+	ids := &sequentialIDs{}
+	g := NewCounterGenerator(ids, []byte("test-secret"), 6)
+
+	for i := 0; i < defaultIDBlockSize+1; i++ {
+		_, err := g.Generate(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, ids.calls)
+}
+
+func TestCounterGenerator_AllocationError(t *testing.T) {
+	g := NewCounterGenerator(failingIDs{}, []byte("test-secret"), 6)
+
+	_, err := g.Generate(context.Background())
+	assert.Error(t, err)
+}
+
+func TestCounterGenerator_Exhausted(t *testing.T) {
+	g := NewCounterGenerator(&sequentialIDs{}, []byte("test-secret"), 10)
+	assert.Equal(t, 10, g.maxLength)
+
+	_, err := g.Encode(g.bandSize(10))
+	assert.ErrorIs(t, err, ErrIDSpaceExhausted)
+}
@@ -1,7 +1,9 @@
 package shortcode
 
 import (
+	"context"
 	"crypto/rand"
+	"errors"
 	"math/big"
 	"strings"
 )
@@ -9,44 +11,57 @@ import (
 const (
 	base62Chars   = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
 	defaultLength = 6
+
+	// maxBlockedAttempts bounds the draws made to find a code the blocklist
+	// allows; only an unreasonable blocklist gets close.
+	maxBlockedAttempts = 100
 )
 
+var ErrAllCodesBlocked = errors.New("shortcode: every generated code was blocked")
+
 type Generator struct {
 	length int
+	rules  rules
 }
 
-func NewGenerator(length int) *Generator {
+// NewGenerator creates a generator of random codes of length characters,
+// plus the check character when one is enabled.
+func NewGenerator(length int, opts ...Option) *Generator {
 	if length <= 0 {
 		length = defaultLength
 	}
-	return &Generator{length: length}
+	return &Generator{length: length, rules: newRules(opts)}
 }
 
-func (g *Generator) Generate() string {
-	var sb strings.Builder
-	sb.Grow(g.length)
+func (g *Generator) Generate(ctx context.Context) (string, error) {
+	size := big.NewInt(int64(g.rules.alphabet.size()))
+
+	for attempt := 0; attempt < maxBlockedAttempts; attempt++ {
+		var sb strings.Builder
+		sb.Grow(g.length + g.rules.extraLength())
+
+		for i := 0; i < g.length; i++ {
+			idx, err := rand.Int(rand.Reader, size)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteByte(g.rules.alphabet[idx.Int64()])
+		}
 
-	for i := 0; i < g.length; i++ {
-		idx, err := rand.Int(rand.Reader, big.NewInt(62))
-		if err != nil {
-			idx = big.NewInt(int64(i * 17 % 62))
+		code := g.rules.finish(sb.String())
+		if !g.rules.blocked(code) {
+			return code, nil
 		}
-		sb.WriteByte(base62Chars[idx.Int64()])
 	}
 
-	return sb.String()
+	return "", ErrAllCodesBlocked
 }
 
 func (g *Generator) Validate(code string) bool {
-	if len(code) != g.length {
-		return false
-	}
-
-	for _, char := range code {
-		if !strings.ContainsRune(base62Chars, char) {
-			return false
-		}
-	}
+	payload, ok := g.rules.payload(code)
+	return ok && len(payload) == g.length
+}
 
-	return true
+func (g *Generator) Resembles(code string) bool {
+	return g.rules.resembles(code, g.length, g.length)
 }
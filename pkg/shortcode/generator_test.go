@@ -0,0 +1,115 @@
+package shortcode
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_Alphabet(t *testing.T) {
+	g := NewGenerator(8, WithAlphabet(AlphabetBase58))
+
+	for i := 0; i < 200; i++ {
+		code, err := g.Generate(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, code, 8)
+		assert.False(t, strings.ContainsAny(code, "0OIl"), "code %q has an ambiguous character", code)
+		assert.True(t, g.Validate(code))
+	}
+
+	assert.False(t, g.Validate("abcdef0O"))
+	assert.False(t, NewGenerator(6, WithAlphabet(AlphabetBase36)).Validate("ABC123"))
+}
+
+func TestGenerator_CheckCharacter(t *testing.T) {
+	g := NewGenerator(6, WithCheckCharacter())
+
+	code, err := g.Generate(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, code, 7)
+	assert.True(t, g.Validate(code))
+
+	for i := 0; i < len(code); i++ {
+		for _, c := range []byte(base62Chars) {
+			if c == code[i] {
+				continue
+			}
+			typo := code[:i] + string(c) + code[i+1:]
+			assert.False(t, g.Validate(typo), "typo %q of %q accepted", typo, code)
+		}
+	}
+
+	code = g.rules.finish("abc123")
+	require.True(t, g.Validate(code))
+	assert.False(t, g.Validate("bac123"+code[6:]), "transposition of %q accepted", code)
+}
+
+func TestGenerator_Resembles(t *testing.T) {
+	g := NewGenerator(6, WithCheckCharacter())
+
+	assert.True(t, g.Resembles("abc1234"), "a mistyped code still resembles one")
+	assert.False(t, g.Resembles("abc123"))
+	assert.False(t, g.Resembles("abc-123"))
+
+	c := NewCounterGenerator(&sequentialIDs{}, []byte("test-secret"), 4)
+	assert.True(t, c.Resembles("abcd"))
+	assert.True(t, c.Resembles("abcdefg"))
+	assert.False(t, c.Resembles("abc"))
+}
+
+func TestGenerator_Blocklist(t *testing.T) {
+	g := NewGenerator(2, WithAlphabet("abcdefghij"), WithBlocklist([]string{"A", "b", "c", "d", "e"}))
+
+	for i := 0; i < 200; i++ {
+		code, err := g.Generate(context.Background())
+		require.NoError(t, err)
+		assert.False(t, strings.ContainsAny(code, "abcde"), "blocked code %q issued", code)
+	}
+	assert.True(t, g.Validate("fa"), "codes issued before a word was blocked stay valid")
+	assert.True(t, g.Validate("fg"))
+
+	all := NewGenerator(2, WithAlphabet("abcdefghij"), WithBlocklist(strings.Split("a,b,c,d,e,f,g,h,i,j", ",")))
+	_, err := all.Generate(context.Background())
+	assert.ErrorIs(t, err, ErrAllCodesBlocked)
+}
+
+func TestParseAlphabet(t *testing.T) {
+	for name, want := range map[string]Alphabet{
+		"":       AlphabetBase62,
+		"base62": AlphabetBase62,
+		"base58": AlphabetBase58,
+		"base36": AlphabetBase36,
+	} {
+		got, err := ParseAlphabet(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	custom, err := ParseAlphabet("23456789abcdefghjkmnpqrstuvwxyz")
+	require.NoError(t, err)
+	assert.Equal(t, Alphabet("23456789abcdefghjkmnpqrstuvwxyz"), custom)
+
+	for _, invalid := range []string{"abc", "abcdefghia", "abcdefghi/"} {
+		_, err := ParseAlphabet(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestCounterGenerator_AlphabetAndCheckCharacter(t *testing.T) {
+	g := NewCounterGenerator(&sequentialIDs{}, []byte("test-secret"), 4, WithAlphabet(AlphabetBase36), WithCheckCharacter())
+	assert.Equal(t, 11, g.maxLength)
+
+	for id := uint64(0); id < 2000; id++ {
+		code, err := g.Encode(id)
+		require.NoError(t, err)
+		assert.Len(t, code, 5)
+		assert.Equal(t, strings.ToLower(code), code)
+
+		decoded, ok := g.Decode(code)
+		require.True(t, ok)
+		assert.Equal(t, id, decoded)
+	}
+}
@@ -0,0 +1,132 @@
+package shortcode
+
+import "strings"
+
+// Option configures the codes a generator issues and accepts.
+type Option func(*rules)
+
+// WithAlphabet makes codes out of the given alphabet instead of base62.
+func WithAlphabet(alphabet Alphabet) Option {
+	return func(r *rules) {
+		r.alphabet = alphabet
+	}
+}
+
+// WithBlocklist skips codes containing any of the given words, ignoring
+// case, so that no offensive code is issued. It only affects new codes:
+// Validate still accepts codes issued before a word was added.
+func WithBlocklist(words []string) Option {
+	return func(r *rules) {
+		for _, word := range words {
+			word = strings.ToLower(strings.TrimSpace(word))
+			if word != "" {
+				r.blocklist = append(r.blocklist, word)
+			}
+		}
+	}
+}
+
+// WithCheckCharacter appends a check character to every code, making codes
+// one character longer, so that a mistyped character or two swapped
+// neighbours are rejected instead of leading to another link.
+func WithCheckCharacter() Option {
+	return func(r *rules) {
+		r.checkCharacter = true
+	}
+}
+
+// rules are the constraints shared by every generator.
+type rules struct {
+	alphabet       Alphabet
+	blocklist      []string
+	checkCharacter bool
+}
+
+func newRules(opts []Option) rules {
+	r := rules{alphabet: AlphabetBase62}
+	for _, opt := range opts {
+		opt(&r)
+	}
+	return r
+}
+
+// finish appends the check character, if any, to payload.
+func (r rules) finish(payload string) string {
+	if !r.checkCharacter {
+		return payload
+	}
+	return payload + string(r.alphabet[r.checkIndex(payload)])
+}
+
+// payload verifies code and returns it without its check character.
+func (r rules) payload(code string) (string, bool) {
+	for i := 0; i < len(code); i++ {
+		if r.alphabet.index(code[i]) < 0 {
+			return "", false
+		}
+	}
+	if !r.checkCharacter {
+		return code, true
+	}
+
+	if len(code) < 2 {
+		return "", false
+	}
+	payload := code[:len(code)-1]
+	if r.alphabet.index(code[len(code)-1]) != r.checkIndex(payload) {
+		return "", false
+	}
+	return payload, true
+}
+
+// resembles reports whether code is made of the alphabet and, without its
+// check character, between minLength and maxLength characters long.
+func (r rules) resembles(code string, minLength, maxLength int) bool {
+	length := len(code) - r.extraLength()
+	if length < minLength || length > maxLength {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if r.alphabet.index(code[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// extraLength is the number of characters added to the payload.
+func (r rules) extraLength() int {
+	if r.checkCharacter {
+		return 1
+	}
+	return 0
+}
+
+func (r rules) blocked(code string) bool {
+	if len(r.blocklist) == 0 {
+		return false
+	}
+
+	code = strings.ToLower(code)
+	for _, word := range r.blocklist {
+		if strings.Contains(code, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIndex computes the Luhn mod N check character of payload, which
+// catches every single-character error and most transpositions of adjacent
+// characters.
+func (r rules) checkIndex(payload string) int {
+	n := r.alphabet.size()
+	factor := 2
+	sum := 0
+	for i := len(payload) - 1; i >= 0; i-- {
+		addend := factor * r.alphabet.index(payload[i])
+		sum += addend/n + addend%n
+		factor = 3 - factor
+	}
+	return (n - sum%n) % n
+}